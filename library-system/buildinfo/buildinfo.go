@@ -0,0 +1,54 @@
+// Package buildinfo 保存构建时注入的版本信息
+//
+//	go build -ldflags "-X library-system/buildinfo.Version=v1.2.0 -X library-system/buildinfo.Commit=$(git rev-parse HEAD) -X library-system/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// 通过 -ldflags -X 注入
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info 构建信息
+type Info struct {
+	Version   string `json:"version" example:"v1.2.0"`
+	Commit    string `json:"commit" example:"c8fd255"`
+	BuildTime string `json:"build_time,omitempty" example:"2024-01-15T10:30:00Z"`
+	GoVersion string `json:"go_version" example:"go1.25.1"`
+}
+
+// Get 未注入提交信息时使用go build记录的VCS信息
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+
+	return info
+}
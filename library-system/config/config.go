@@ -0,0 +1,525 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"library-system/logging"
+	"library-system/services"
+	"library-system/tracing"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultSessionSecret 仅供本地开发使用，release模式下拒绝启动
+const DefaultSessionSecret = "SBSBSBSBSBSSBSBS"
+
+// 运行模式，与gin的模式一致
+const (
+	ModeDebug   = "debug"
+	ModeRelease = "release"
+	ModeTest    = "test"
+)
+
+// Config 应用配置
+// yaml标签对应配置文件中的键，env标签对应覆盖该项的环境变量
+type Config struct {
+	// debug、release或test，release模式下会拒绝不安全的配置
+	Mode          string              `yaml:"mode" env:"GIN_MODE"`
+	Log           LogConfig           `yaml:"log"`
+	Server        ServerConfig        `yaml:"server"`
+	Database      DatabaseConfig      `yaml:"database"`
+	Session       SessionConfig       `yaml:"session"`
+	CORS          CORSConfig          `yaml:"cors"`
+	Mail          MailConfig          `yaml:"mail"`
+	PasswordReset PasswordResetConfig `yaml:"password_reset"`
+	Password      PasswordConfig      `yaml:"password"`
+	Loan          LoanConfig          `yaml:"loan"`
+	TwoFactor     TwoFactorConfig     `yaml:"two_factor"`
+	OIDC          OIDCConfig          `yaml:"oidc"`
+	LDAP          LDAPConfig          `yaml:"ldap"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Webhooks      WebhooksConfig      `yaml:"webhooks"`
+	Stream        StreamConfig        `yaml:"stream"`
+	Scheduler     SchedulerConfig     `yaml:"scheduler"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+}
+
+type LogConfig struct {
+	// debug、info、warn或error
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// json或text
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+type ServerConfig struct {
+	Addr string `yaml:"addr" env:"SERVER_PORT"`
+	// 只信任这些反向代理转发的客户端IP，登录限流依赖真实IP
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// 收到退出信号后等待进行中的请求和后台任务完成的最长时间
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+
+	// 同时配置证书和私钥时使用HTTPS
+	TLSCertFile string `yaml:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tls_key_file" env:"TLS_KEY_FILE"`
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host" env:"MYSQL_HOST"`
+	Port            int           `yaml:"port" env:"MYSQL_PORT"`
+	User            string        `yaml:"user" env:"MYSQL_USER"`
+	Password        string        `yaml:"password" env:"MYSQL_PASSWORD"`
+	Name            string        `yaml:"name" env:"MYSQL_DBNAME"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	// 每个请求中数据库操作的截止时间
+	Deadlines DeadlineConfig `yaml:"deadlines"`
+}
+
+// DeadlineConfig 按路由组配置请求截止时间，为0的路由组使用Default，Default也为0时不限制
+type DeadlineConfig struct {
+	Default time.Duration `yaml:"default" env:"DB_DEADLINE"`
+	Auth    time.Duration `yaml:"auth" env:"DB_DEADLINE_AUTH"`
+	Me      time.Duration `yaml:"me" env:"DB_DEADLINE_ME"`
+	Books   time.Duration `yaml:"books" env:"DB_DEADLINE_BOOKS"`
+	Borrow  time.Duration `yaml:"borrow" env:"DB_DEADLINE_BORROW"`
+	Admin   time.Duration `yaml:"admin" env:"DB_DEADLINE_ADMIN"`
+}
+
+type SessionConfig struct {
+	Secret string        `yaml:"secret" env:"SESSION_SECRET"`
+	MaxAge time.Duration `yaml:"max_age" env:"SESSION_MAX_AGE"`
+	// SameSite=strict时从身份提供方跳转回来的单点登录回调不会携带Cookie
+	CookieSecure   bool   `yaml:"cookie_secure" env:"SESSION_COOKIE_SECURE"`
+	CookieSameSite string `yaml:"cookie_same_site" env:"SESSION_COOKIE_SAMESITE"`
+}
+
+type CORSConfig struct {
+	// 允许携带Cookie跨域访问的前端地址，为空时不允许跨域
+	AllowedOrigins []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+}
+
+type MailConfig struct {
+	// 未配置SMTP时邮件写入Dir目录
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUser     string `yaml:"smtp_user" env:"SMTP_USER"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	From         string `yaml:"from" env:"MAIL_FROM"`
+	Dir          string `yaml:"dir" env:"MAIL_DIR"`
+}
+
+type PasswordResetConfig struct {
+	URL      string        `yaml:"url" env:"PASSWORD_RESET_URL"`
+	TokenTTL time.Duration `yaml:"token_ttl" env:"PASSWORD_RESET_TTL"`
+}
+
+type PasswordConfig struct {
+	MinLength     int  `yaml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	RequireUpper  bool `yaml:"require_upper" env:"PASSWORD_REQUIRE_UPPER"`
+	RequireLower  bool `yaml:"require_lower" env:"PASSWORD_REQUIRE_LOWER"`
+	RequireDigit  bool `yaml:"require_digit" env:"PASSWORD_REQUIRE_DIGIT"`
+	RequireSymbol bool `yaml:"require_symbol" env:"PASSWORD_REQUIRE_SYMBOL"`
+	RejectCommon  bool `yaml:"reject_common" env:"PASSWORD_REJECT_COMMON"`
+}
+
+type LoanConfig struct {
+	MaxActiveBorrows int `yaml:"max_active_borrows" env:"LOAN_MAX_ACTIVE_BORROWS"`
+	LoanDays         int `yaml:"loan_days" env:"LOAN_DAYS"`
+}
+
+type TwoFactorConfig struct {
+	Issuer           string `yaml:"issuer" env:"TOTP_ISSUER"`
+	RequireForAdmins bool   `yaml:"require_for_admins" env:"REQUIRE_ADMIN_2FA"`
+}
+
+type OIDCConfig struct {
+	// 为空时不启用单点登录
+	IssuerURL    string   `yaml:"issuer_url" env:"OIDC_ISSUER_URL"`
+	ClientID     string   `yaml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string   `yaml:"client_secret" env:"OIDC_CLIENT_SECRET"`
+	RedirectURL  string   `yaml:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	GroupsClaim  string   `yaml:"groups_claim" env:"OIDC_GROUPS_CLAIM"`
+	AdminGroups  []string `yaml:"admin_groups" env:"OIDC_ADMIN_GROUPS"`
+	PostLoginURL string   `yaml:"post_login_url" env:"OIDC_POST_LOGIN_URL"`
+}
+
+type LDAPConfig struct {
+	// 为空时不启用目录认证
+	URL                string `yaml:"url" env:"LDAP_URL"`
+	StartTLS           bool   `yaml:"start_tls" env:"LDAP_START_TLS"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" env:"LDAP_INSECURE_SKIP_VERIFY"`
+	BindDN             string `yaml:"bind_dn" env:"LDAP_BIND_DN"`
+	BindPassword       string `yaml:"bind_password" env:"LDAP_BIND_PASSWORD"`
+	BaseDN             string `yaml:"base_dn" env:"LDAP_BASE_DN"`
+	UserFilter         string `yaml:"user_filter" env:"LDAP_USER_FILTER"`
+	SyncFilter         string `yaml:"sync_filter" env:"LDAP_SYNC_FILTER"`
+	UsernameAttribute  string `yaml:"username_attribute" env:"LDAP_USERNAME_ATTR"`
+	EmailAttribute     string `yaml:"email_attribute" env:"LDAP_EMAIL_ATTR"`
+	GroupAttribute     string `yaml:"group_attribute" env:"LDAP_GROUP_ATTR"`
+	// 组DN中包含逗号，环境变量中用分号分隔
+	AdminGroups []string `yaml:"admin_groups" env:"LDAP_ADMIN_GROUPS" sep:";"`
+}
+
+type NotificationsConfig struct {
+	// 应还日期前多久发送即将到期提醒，扫描时间见 scheduler.jobs.overdue_scan
+	DueSoon time.Duration `yaml:"due_soon" env:"NOTIFICATION_DUE_SOON"`
+}
+
+type WebhooksConfig struct {
+	// 单次投递请求的超时时间
+	Timeout time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT"`
+	// 最多尝试的次数，重试间隔从retry_backoff开始翻倍，最长6小时
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env:"WEBHOOK_RETRY_BACKOFF"`
+}
+
+type StreamConfig struct {
+	// 事件流没有事件时发送注释行的间隔
+	Heartbeat time.Duration `yaml:"heartbeat" env:"STREAM_HEARTBEAT"`
+	// 保留的最近事件数，客户端断线重连时最多补发这么多
+	History int `yaml:"history" env:"STREAM_HISTORY"`
+}
+
+type SchedulerConfig struct {
+	// 任务锁的有效期，运行中的任务会定期续期；实例异常退出后，其他实例最多等待这么久
+	LockTTL time.Duration `yaml:"lock_ttl" env:"SCHEDULER_LOCK_TTL"`
+	Jobs    JobsConfig    `yaml:"jobs"`
+}
+
+// JobsConfig 各任务的调度规则，cron表达式（分 时 日 月 周）或 @every 1h，为空时只能手动触发
+type JobsConfig struct {
+	OverdueScan  string `yaml:"overdue_scan" env:"JOB_OVERDUE_SCAN"`
+	SessionPurge string `yaml:"session_purge" env:"JOB_SESSION_PURGE"`
+	// 只在配置了ldap.url时注册
+	LDAPSync string `yaml:"ldap_sync" env:"JOB_LDAP_SYNC"`
+	// 投递间隔，事件最多延迟这么久送达
+	WebhookDispatch string `yaml:"webhook_dispatch" env:"JOB_WEBHOOK_DISPATCH"`
+}
+
+type TracingConfig struct {
+	// none、otlp或stdout
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+	// OTLP/HTTP收集器地址，例如 http://localhost:4318
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	ServiceName string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// Default 默认配置，适合本地开发
+func Default() *Config {
+	passwordPolicy := services.DefaultPasswordPolicy()
+	loanPolicy := services.DefaultLoanPolicy()
+	notificationPolicy := services.DefaultNotificationPolicy()
+	webhookPolicy := services.DefaultWebhookPolicy()
+
+	return &Config{
+		Mode: ModeDebug,
+		Log: LogConfig{
+			Level:  "info",
+			Format: logging.FormatJSON,
+		},
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            3306,
+			User:            "root",
+			Name:            "library-system",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			Deadlines: DeadlineConfig{
+				Default: 5 * time.Second,
+				// 认证可能需要访问LDAP，管理员查询全部借阅记录
+				Auth:  10 * time.Second,
+				Admin: 15 * time.Second,
+			},
+		},
+		Session: SessionConfig{
+			Secret:         DefaultSessionSecret,
+			MaxAge:         24 * time.Hour,
+			CookieSameSite: "lax",
+		},
+		Mail: MailConfig{
+			SMTPPort: 587,
+			From:     "library@localhost",
+			Dir:      "mail-outbox",
+		},
+		PasswordReset: PasswordResetConfig{
+			URL:      "http://localhost:8080/reset-password",
+			TokenTTL: 30 * time.Minute,
+		},
+		Password: PasswordConfig{
+			MinLength:     passwordPolicy.MinLength,
+			RequireUpper:  passwordPolicy.RequireUpper,
+			RequireLower:  passwordPolicy.RequireLower,
+			RequireDigit:  passwordPolicy.RequireDigit,
+			RequireSymbol: passwordPolicy.RequireSymbol,
+			RejectCommon:  passwordPolicy.RejectCommon,
+		},
+		Loan: LoanConfig{
+			MaxActiveBorrows: loanPolicy.MaxActiveBorrows,
+			LoanDays:         loanPolicy.LoanDays,
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer: "LibrarySystem",
+		},
+		OIDC: OIDCConfig{
+			RedirectURL: "http://localhost:8080/api/v1/auth/oidc/callback",
+			GroupsClaim: "groups",
+		},
+		LDAP: LDAPConfig{
+			UsernameAttribute: "uid",
+			EmailAttribute:    "mail",
+			GroupAttribute:    "memberOf",
+		},
+		Notifications: NotificationsConfig{
+			DueSoon: notificationPolicy.DueSoonWindow,
+		},
+		Webhooks: WebhooksConfig{
+			Timeout:      webhookPolicy.Timeout,
+			MaxAttempts:  webhookPolicy.MaxAttempts,
+			RetryBackoff: webhookPolicy.RetryBackoff,
+		},
+		Stream: StreamConfig{
+			Heartbeat: 15 * time.Second,
+			History:   1000,
+		},
+		Scheduler: SchedulerConfig{
+			LockTTL: 5 * time.Minute,
+			Jobs: JobsConfig{
+				OverdueScan:     "0 * * * *",
+				SessionPurge:    "30 3 * * *",
+				LDAPSync:        "15 * * * *",
+				WebhookDispatch: "* * * * *",
+			},
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.ExporterNone,
+			ServiceName: "library-system",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Validate 检查配置是否完整、安全，返回所有问题
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !slices.Contains([]string{ModeDebug, ModeRelease, ModeTest}, c.Mode) {
+		add("mode must be one of debug, release, test, got %q", c.Mode)
+	}
+
+	// 日志
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Log.Format != logging.FormatJSON && c.Log.Format != logging.FormatText {
+		add("log.format must be json or text, got %q", c.Log.Format)
+	}
+
+	// HTTP服务
+	if c.Server.ReadTimeout < 0 || c.Server.ReadHeaderTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		add("server timeouts must not be negative")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		add("server.shutdown_timeout must be positive")
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		add("server.tls_cert_file and server.tls_key_file must be set together")
+	}
+
+	// 数据库
+	if c.Database.Host == "" || c.Database.Name == "" {
+		add("database.host and database.name are required")
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		add("database.port %d is out of range", c.Database.Port)
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetime < 0 {
+		add("database pool settings must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		add("database.max_idle_conns (%d) exceeds database.max_open_conns (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+	d := c.Database.Deadlines
+	if min(d.Default, d.Auth, d.Me, d.Books, d.Borrow, d.Admin) < 0 {
+		add("database.deadlines must not be negative")
+	}
+
+	// Session和Cookie
+	if c.Session.Secret == "" {
+		add("session.secret is required")
+	}
+	if c.Session.MaxAge <= 0 {
+		add("session.max_age must be positive")
+	}
+	sameSite, err := parseSameSite(c.Session.CookieSameSite)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	// 浏览器会拒绝没有Secure属性的SameSite=None Cookie
+	if sameSite == http.SameSiteNoneMode && !c.Session.CookieSecure {
+		add("session.cookie_same_site=none requires session.cookie_secure=true")
+	}
+
+	// CORS允许携带Cookie，不能使用通配符
+	if slices.Contains(c.CORS.AllowedOrigins, "*") {
+		add("cors.allowed_origins must list explicit origins, \"*\" is not allowed")
+	}
+
+	// 密码和借阅规则
+	if c.Password.MinLength < 1 || c.Password.MinLength > 72 {
+		add("password.min_length must be between 1 and 72")
+	}
+	if c.Loan.MaxActiveBorrows <= 0 || c.Loan.LoanDays <= 0 {
+		add("loan.max_active_borrows and loan.loan_days must be positive")
+	}
+	if c.PasswordReset.TokenTTL <= 0 {
+		add("password_reset.token_ttl must be positive")
+	}
+	if c.Notifications.DueSoon <= 0 {
+		add("notifications.due_soon must be positive")
+	}
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.MaxAttempts <= 0 || c.Webhooks.RetryBackoff <= 0 {
+		add("webhooks.timeout, webhooks.max_attempts and webhooks.retry_backoff must be positive")
+	}
+	if c.Stream.Heartbeat <= 0 || c.Stream.History <= 0 {
+		add("stream.heartbeat and stream.history must be positive")
+	}
+
+	// 单点登录和目录服务
+	if c.OIDC.IssuerURL != "" && (c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "") {
+		add("oidc.client_id and oidc.redirect_url are required when oidc.issuer_url is set")
+	}
+	if c.LDAP.URL != "" && c.LDAP.BaseDN == "" {
+		add("ldap.base_dn is required when ldap.url is set")
+	}
+
+	// 定时任务
+	if c.Scheduler.LockTTL < time.Minute {
+		add("scheduler.lock_ttl must be at least 1m")
+	}
+	jobs := c.Scheduler.Jobs
+	for _, job := range []struct{ name, spec string }{
+		{"overdue_scan", jobs.OverdueScan},
+		{"session_purge", jobs.SessionPurge},
+		{"ldap_sync", jobs.LDAPSync},
+		{"webhook_dispatch", jobs.WebhookDispatch},
+	} {
+		if _, err := jobs.Schedule(job.spec); err != nil {
+			add("scheduler.jobs.%s: %w", job.name, err)
+		}
+	}
+
+	// 链路追踪
+	if !slices.Contains([]string{tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout}, c.Tracing.Exporter) {
+		add("tracing.exporter must be none, otlp or stdout, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be between 0 and 1")
+	}
+	if c.Tracing.Exporter != tracing.ExporterNone && c.Tracing.ServiceName == "" {
+		add("tracing.service_name is required when tracing is enabled")
+	}
+
+	// release模式下拒绝开发用的不安全配置
+	if c.Mode == ModeRelease {
+		if c.Session.Secret == DefaultSessionSecret {
+			add("session.secret must be changed from the development default in release mode")
+		} else if len(c.Session.Secret) < 32 {
+			add("session.secret must be at least 32 bytes in release mode")
+		}
+		if c.Database.Password == "" {
+			add("database.password must be set in release mode")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DSN MySQL连接串
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", c.User, c.Password, c.Host, c.Port, c.Name)
+}
+
+// SameSite Cookie的SameSite属性，配置已通过Validate校验
+func (c SessionConfig) SameSite() http.SameSite {
+	sameSite, _ := parseSameSite(c.CookieSameSite)
+	return sameSite
+}
+
+// PasswordPolicy
+func (c PasswordConfig) PasswordPolicy() services.PasswordPolicy {
+	return services.PasswordPolicy{
+		MinLength:     c.MinLength,
+		RequireUpper:  c.RequireUpper,
+		RequireLower:  c.RequireLower,
+		RequireDigit:  c.RequireDigit,
+		RequireSymbol: c.RequireSymbol,
+		RejectCommon:  c.RejectCommon,
+	}
+}
+
+// LoanPolicy
+func (c LoanConfig) LoanPolicy() services.LoanPolicy {
+	return services.LoanPolicy{
+		MaxActiveBorrows: c.MaxActiveBorrows,
+		LoanDays:         c.LoanDays,
+	}
+}
+
+// NotificationPolicy
+func (c NotificationsConfig) NotificationPolicy() services.NotificationPolicy {
+	return services.NotificationPolicy{
+		DueSoonWindow: c.DueSoon,
+	}
+}
+
+// WebhookPolicy
+func (c WebhooksConfig) WebhookPolicy() services.WebhookPolicy {
+	return services.WebhookPolicy{
+		Timeout:      c.Timeout,
+		MaxAttempts:  c.MaxAttempts,
+		RetryBackoff: c.RetryBackoff,
+	}
+}
+
+// Schedule 解析调度规则，为空时返回nil，表示只能手动触发
+func (c JobsConfig) Schedule(spec string) (services.Schedule, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	return services.ParseSchedule(spec)
+}
+
+// parseSameSite
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("session.cookie_same_site must be lax, strict or none, got %q", value)
+	}
+}
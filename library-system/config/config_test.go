@@ -0,0 +1,238 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试用到的环境变量，每个用例先清空，避免受运行环境影响
+var testEnvKeys = []string{
+	"CONFIG_FILE", "GIN_MODE", "SERVER_PORT", "TRUSTED_PROXIES", "MYSQL_HOST", "MYSQL_PORT", "MYSQL_PASSWORD",
+	"MYSQL_DBNAME", "SESSION_SECRET", "SESSION_MAX_AGE", "SESSION_COOKIE_SECURE", "LDAP_ADMIN_GROUPS", "DB_DEADLINE",
+}
+
+// clearEnv 将测试用到的环境变量设为空，空值视为未设置
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range testEnvKeys {
+		t.Setenv(key, "")
+	}
+}
+
+// writeFile 在临时目录中写入配置文件，返回路径
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	const file = `
+server:
+  addr: ":9000"
+database:
+  host: db.internal
+  port: 3307
+  name: library
+session:
+  max_age: 12h
+`
+
+	tests := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		args     []string
+		wantAddr string
+		wantHost string
+		wantPort int
+		wantName string
+	}{
+		{name: "默认值", wantAddr: ":8080", wantHost: "localhost", wantPort: 3306, wantName: "library-system"},
+		{name: "配置文件覆盖默认值", file: file, wantAddr: ":9000", wantHost: "db.internal", wantPort: 3307, wantName: "library"},
+		{
+			name:     "环境变量覆盖配置文件",
+			file:     file,
+			env:      map[string]string{"MYSQL_HOST": "db.env", "MYSQL_PORT": "3308"},
+			wantAddr: ":9000", wantHost: "db.env", wantPort: 3308, wantName: "library",
+		},
+		{
+			name:     "命令行参数覆盖环境变量",
+			file:     file,
+			env:      map[string]string{"MYSQL_HOST": "db.env", "MYSQL_PORT": "3308"},
+			args:     []string{"-db-host", "db.flag", "-addr", ":7000"},
+			wantAddr: ":7000", wantHost: "db.flag", wantPort: 3308, wantName: "library",
+		},
+		{
+			name:     "空的环境变量视为未设置",
+			file:     file,
+			env:      map[string]string{"MYSQL_HOST": ""},
+			wantAddr: ":9000", wantHost: "db.internal", wantPort: 3307, wantName: "library",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file)}, args...)
+			}
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.Server.Addr != tt.wantAddr || cfg.Database.Host != tt.wantHost || cfg.Database.Port != tt.wantPort || cfg.Database.Name != tt.wantName {
+				t.Errorf("addr = %q, host = %q, port = %d, name = %q, want %q, %q, %d, %q",
+					cfg.Server.Addr, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name, tt.wantAddr, tt.wantHost, tt.wantPort, tt.wantName)
+			}
+			// 未出现在配置文件中的项保留默认值
+			if cfg.Server.ReadTimeout != Default().Server.ReadTimeout {
+				t.Errorf("ReadTimeout = %s, want default", cfg.Server.ReadTimeout)
+			}
+		})
+	}
+
+	t.Run("CONFIG_FILE环境变量指定配置文件", func(t *testing.T) {
+		clearEnv(t)
+		t.Setenv("CONFIG_FILE", writeFile(t, file))
+		cfg, err := Load(nil)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if cfg.Database.Host != "db.internal" || cfg.Session.MaxAge != 12*time.Hour {
+			t.Errorf("host = %q, max_age = %s, want values from the file", cfg.Database.Host, cfg.Session.MaxAge)
+		}
+	})
+}
+
+func TestLoad_EnvTypes(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SESSION_MAX_AGE", "90m")
+	t.Setenv("SESSION_COOKIE_SECURE", "true")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1, 10.0.0.2,")
+	t.Setenv("LDAP_ADMIN_GROUPS", "cn=admins,dc=example,dc=com;cn=staff,dc=example,dc=com")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Session.MaxAge != 90*time.Minute || !cfg.Session.CookieSecure {
+		t.Errorf("max_age = %s, cookie_secure = %v", cfg.Session.MaxAge, cfg.Session.CookieSecure)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2"}; !reflect.DeepEqual(cfg.Server.TrustedProxies, want) {
+		t.Errorf("TrustedProxies = %q, want %q", cfg.Server.TrustedProxies, want)
+	}
+	// DN中包含逗号，使用sep标签指定的分隔符
+	if want := []string{"cn=admins,dc=example,dc=com", "cn=staff,dc=example,dc=com"}; !reflect.DeepEqual(cfg.LDAP.AdminGroups, want) {
+		t.Errorf("AdminGroups = %q, want %q", cfg.LDAP.AdminGroups, want)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{name: "端口不是整数", env: map[string]string{"MYSQL_PORT": "abc"}, wantErr: "MYSQL_PORT"},
+		{name: "时长缺少单位", env: map[string]string{"SESSION_MAX_AGE": "5"}, wantErr: "SESSION_MAX_AGE"},
+		{name: "无效的布尔值", env: map[string]string{"SESSION_COOKIE_SECURE": "maybe"}, wantErr: "SESSION_COOKIE_SECURE"},
+		{name: "配置文件中的无效时长", file: "session:\n  max_age: soon\n", wantErr: "failed to parse config file"},
+		{name: "配置文件中的未知键", file: "server:\n  adress: \":9000\"\n", wantErr: "adress"},
+		{name: "配置文件不存在", args: []string{"-config", "/nonexistent/config.yaml"}, wantErr: "failed to read config file"},
+		{name: "未知的命令行参数", args: []string{"-port", "80"}, wantErr: "port"},
+		{name: "校验失败", args: []string{"-mode", "production"}, wantErr: "invalid configuration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file)}, args...)
+			}
+
+			_, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Release(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr []string
+	}{
+		{
+			name: "开发模式允许默认配置",
+			modify: func(cfg *Config) {
+				cfg.Mode = ModeDebug
+			},
+		},
+		{
+			name:    "默认Session密钥和空的数据库密码",
+			modify:  func(cfg *Config) {},
+			wantErr: []string{"session.secret must be changed", "database.password must be set"},
+		},
+		{
+			name: "Session密钥太短",
+			modify: func(cfg *Config) {
+				cfg.Session.Secret = "too-short"
+				cfg.Database.Password = "secret"
+			},
+			wantErr: []string{"at least 32 bytes"},
+		},
+		{
+			name: "安全的配置",
+			modify: func(cfg *Config) {
+				cfg.Session.Secret = strings.Repeat("s", 32)
+				cfg.Database.Password = "secret"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Mode = ModeRelease
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %q", tt.wantErr)
+			}
+			// 返回全部问题，而不是第一个
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -43,25 +43,25 @@ const docTemplate = `{
                     "200": {
                         "description": "更新成功",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SuccessResponse"
+                            "$ref": "#/definitions/handlers.Response-any"
                         }
                     },
                     "400": {
                         "description": "请求参数错误或格式不正确",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "404": {
                         "description": "未找到该图书",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
@@ -93,25 +93,25 @@ const docTemplate = `{
                     "200": {
                         "description": "添加成功",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SuccessResponse"
+                            "$ref": "#/definitions/handlers.Response-any"
                         }
                     },
                     "400": {
                         "description": "请求参数错误或格式不正确",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "409": {
                         "description": "图书已存在",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
@@ -143,25 +143,25 @@ const docTemplate = `{
                     "200": {
                         "description": "删除成功",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SuccessResponse"
+                            "$ref": "#/definitions/handlers.Response-any"
                         }
                     },
                     "400": {
                         "description": "请求参数错误或格式不正确",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "404": {
                         "description": "未找到该图书",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
@@ -184,24 +184,21 @@ const docTemplate = `{
                     "200": {
                         "description": "借阅记录数组",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.BorrowRecord"
-                            }
+                            "$ref": "#/definitions/handlers.Response-array_models_BorrowRecord"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
         },
-        "/auth/login": {
+        "/admin/checkins": {
             "post": {
-                "description": "用户使用用户名和密码登录系统，登录成功后设置Session",
+                "description": "馆员归还图书（如还书箱中的图书），不要求是借阅者本人。借阅记录由record_id指定，或由card_number（借书证号）和book_id指定，二者只能选一种；同一本书借了多册时归还应还日期最早的一册。借阅记录中记录经办的馆员（管理员）",
                 "consumes": [
                     "application/json"
                 ],
@@ -209,83 +206,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "用户登录",
+                "summary": "服务台还书",
                 "parameters": [
                     {
-                        "description": "登录信息",
+                        "description": "还书信息",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginRequest"
+                            "$ref": "#/definitions/handlers.CheckInRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "登录成功",
+                        "description": "还书成功",
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginResponse"
+                            "$ref": "#/definitions/handlers.Response-models_BorrowRecord"
                         }
                     },
                     "400": {
                         "description": "请求参数错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "用户名或密码错误",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
-                    "500": {
-                        "description": "服务器内部错误",
+                    "404": {
+                        "description": "借阅记录、读者或图书不存在",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
-                    }
-                }
-            }
-        },
-        "/auth/logout": {
-            "post": {
-                "description": "用户注销登录，清除session。需要用户已登录。",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "auth"
-                ],
-                "summary": "用户注销",
-                "responses": {
-                    "204": {
-                        "description": "注销成功"
                     },
-                    "401": {
-                        "description": "未登录用户无法注销",
+                    "409": {
+                        "description": "图书已归还",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
         },
-        "/auth/register": {
+        "/admin/checkouts": {
             "post": {
-                "description": "新用户注册账号",
+                "description": "馆员为指定读者借出图书，读者由user_id或card_number（借书证号）指定，二者只能填一个。借阅记录中记录经办的馆员（管理员）",
                 "consumes": [
                     "application/json"
                 ],
@@ -293,45 +264,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "用户注册",
+                "summary": "服务台借书",
                 "parameters": [
                     {
-                        "description": "注册信息",
+                        "description": "借书信息",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.RegisterRequest"
+                            "$ref": "#/definitions/handlers.CheckOutRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "注册成功",
+                        "description": "借书成功",
                         "schema": {
-                            "$ref": "#/definitions/handlers.RegisterResponse"
+                            "$ref": "#/definitions/handlers.Response-models_BorrowRecord"
                         }
                     },
                     "400": {
-                        "description": "请求参数错误或用户名已存在",
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "读者账号已停用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "读者或图书不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "库存不足或借阅次数已达上限",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
         },
-        "/books": {
+        "/admin/jobs": {
             "get": {
-                "description": "获取系统中的所有图书列表",
+                "description": "管理员查看全部定时任务的调度规则、下次运行时间和最近一次运行",
                 "consumes": [
                     "application/json"
                 ],
@@ -339,31 +328,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "获取所有图书",
+                "summary": "获取定时任务列表",
                 "responses": {
                     "200": {
-                        "description": "图书列表\" // 修改1：{array} 改为 {object}，因为返回的是单个模型实例的列表",
+                        "description": "任务数组",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Book"
-                            }
+                            "$ref": "#/definitions/handlers.Response-array_services_JobInfo"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
         },
-        "/books/search": {
-            "get": {
-                "description": "通过关键词搜索图书，支持标题、作者等字段的模糊匹配",
+        "/admin/jobs/{name}/run": {
+            "post": {
+                "description": "管理员立即运行定时任务，任务在后台运行，返回已创建的运行记录。任务正在本实例或其他实例运行时返回409",
                 "consumes": [
                     "application/json"
                 ],
@@ -371,46 +357,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "根据关键词搜索图书",
+                "summary": "手动运行任务",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "搜索关键词",
-                        "name": "keyword",
-                        "in": "query",
+                        "example": "session_purge",
+                        "description": "任务名",
+                        "name": "name",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "搜索结果\" // 此处使用 {array} 正确，因为返回的是图书列表",
+                    "202": {
+                        "description": "任务已开始运行",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Book"
-                            }
+                            "$ref": "#/definitions/handlers.Response-models_JobRun"
                         }
                     },
-                    "400": {
-                        "description": "搜索关键词不能为空",
+                    "404": {
+                        "description": "任务不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "任务正在运行",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
         },
-        "/books/search/author": {
+        "/admin/jobs/{name}/runs": {
             "get": {
-                "description": "通过精确的作者名称搜索图书，返回该作者的所有图书",
+                "description": "管理员查看定时任务的运行记录，最新的在前",
                 "consumes": [
                     "application/json"
                 ],
@@ -418,46 +408,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "根据作者搜索图书",
+                "summary": "获取任务运行历史",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "作者名称",
-                        "name": "author",
-                        "in": "query",
+                        "example": "session_purge",
+                        "description": "任务名",
+                        "name": "name",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "返回条数，默认20，最多100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "搜索到的图书列表",
+                        "description": "运行记录数组",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Book"
-                            }
+                            "$ref": "#/definitions/handlers.Response-array_models_JobRun"
                         }
                     },
                     "400": {
-                        "description": "作者不能为空",
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "任务不存在",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
         },
-        "/books/search/title": {
+        "/admin/lockouts": {
             "get": {
-                "description": "通过书名中的关键词进行模糊搜索，返回匹配的图书列表",
+                "description": "管理员查看因登录失败次数过多而被临时锁定的账号（user:用户名）和IP（ip:地址）",
                 "consumes": [
                     "application/json"
                 ],
@@ -465,46 +465,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "books"
-                ],
-                "summary": "根据书名关键词搜索图书",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "书名关键词",
-                        "name": "titlekeyword",
-                        "in": "query",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "获取登录锁定列表",
                 "responses": {
                     "200": {
-                        "description": "搜索到的图书列表",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Book"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "书名关键词不能为空",
+                        "description": "锁定记录数组",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/handlers.Response-array_models_LoginThrottle"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/books/title": {
-            "get": {
-                "description": "通过图书标题获取特定图书的详细信息",
+            },
+            "delete": {
+                "description": "管理员清除账号或IP的登录失败记录并解除锁定",
                 "consumes": [
                     "application/json"
                 ],
@@ -512,49 +492,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "根据标题获取图书信息",
+                "summary": "解除登录锁定",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "图书标题",
-                        "name": "title",
-                        "in": "query",
-                        "required": true
+                        "description": "解除锁定请求",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ClearLockoutRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "图书信息",
+                        "description": "解除成功",
                         "schema": {
-                            "$ref": "#/definitions/models.Book"
+                            "$ref": "#/definitions/handlers.Response-any"
                         }
                     },
                     "400": {
-                        "description": "标题不能为空",
+                        "description": "请求参数错误或格式不正确",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "404": {
-                        "description": "图书不存在",
+                        "description": "锁定记录不存在",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
-                        "description": "服务器内部错误\" // 修改2：补充500错误",
+                        "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
         },
-        "/books/{id}": {
-            "get": {
-                "description": "通过图书ID获取特定图书的详细信息",
+        "/admin/users/{id}/card-number": {
+            "put": {
+                "description": "管理员为读者登记或更换借书证号，card_number为空时注销。馆员可凭借书证号在服务台为读者借书",
                 "consumes": [
                     "application/json"
                 ],
@@ -562,113 +544,64 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "根据ID获取图书信息",
+                "summary": "登记借书证号",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "图书ID",
+                        "description": "用户ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "图书信息\" // 修改1：{array} 改为 {object}，返回单个图书对象",
-                        "schema": {
-                            "$ref": "#/definitions/models.Book"
-                        }
-                    },
-                    "400": {
-                        "description": "无效的图书ID",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "图书不存在",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
                     },
-                    "500": {
-                        "description": "服务器内部错误\" // 修改3：补充500错误",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/borrow": {
-            "post": {
-                "description": "用户借阅指定图书（需要登录）",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "borrow"
-                ],
-                "summary": "借阅图书",
-                "parameters": [
                     {
-                        "description": "借书信息",
+                        "description": "借书证号",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.BorrowBookRequest"
+                            "$ref": "#/definitions/handlers.SetCardNumberRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "借书成功",
+                        "description": "登记成功",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SuccessResponse"
+                            "$ref": "#/definitions/handlers.Response-models_User"
                         }
                     },
                     "400": {
-                        "description": "请求参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "用户未认证",
+                        "description": "请求参数错误或格式不正确",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "404": {
-                        "description": "图书不存在",
+                        "description": "用户不存在",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "409": {
-                        "description": "库存不足或借阅次数已达上限",
+                        "description": "借书证号已被使用",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
         },
-        "/borrow/records": {
+        "/admin/webhooks": {
             "get": {
-                "description": "获取当前用户的所有借阅记录（需要登录）",
+                "description": "管理员查看全部登记的Webhook，不返回签名密钥",
                 "consumes": [
                     "application/json"
                 ],
@@ -676,37 +609,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "borrow"
+                    "admin"
                 ],
-                "summary": "获取用户借阅记录",
+                "summary": "获取Webhook列表",
                 "responses": {
                     "200": {
-                        "description": "借阅记录数组",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.BorrowRecord"
-                            }
-                        }
-                    },
-                    "401": {
-                        "description": "用户未认证",
+                        "description": "Webhook数组",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/handlers.Response-array_models_Webhook"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/borrow/return": {
+            },
             "post": {
-                "description": "用户归还已借阅的图书（需要登录）",
+                "description": "管理员登记事件接收地址。事件以POST请求投递，请求头X-Webhook-Signature为 t=\u003c时间戳\u003e,v1=\u003cHMAC-SHA256(secret, \"\u003c时间戳\u003e.\u003c请求体\u003e\")\u003e，\n签名密钥只在本接口返回一次。接收方返回非2xx时按指数退避重试",
                 "consumes": [
                     "application/json"
                 ],
@@ -714,66 +636,1929 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "borrow"
+                    "admin"
                 ],
-                "summary": "归还图书",
+                "summary": "登记Webhook",
                 "parameters": [
                     {
-                        "description": "还书信息",
+                        "description": "Webhook信息",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.ReturnBookRequest"
+                            "$ref": "#/definitions/handlers.WebhookRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "还书成功",
+                    "201": {
+                        "description": "登记成功，包含签名密钥",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SuccessResponse"
+                            "$ref": "#/definitions/handlers.Response-services_CreatedWebhook"
                         }
                     },
                     "400": {
-                        "description": "请求参数错误",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "用户未认证",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "权限不足",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "借阅记录或图书不存在",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "图书已归还",
+                        "description": "请求参数错误或格式不正确",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     },
                     "500": {
                         "description": "服务器内部错误",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/middleware.Problem"
                         }
                     }
                 }
             }
-        }
+        },
+        "/admin/webhooks/{id}": {
+            "put": {
+                "description": "管理员修改接收地址、订阅的事件或启用状态，签名密钥不变。停用后尚未投递的事件不再重试",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "修改Webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "修改成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-models_Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或格式不正确",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "管理员删除Webhook及其投递记录",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "删除Webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "删除成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "管理员查看Webhook的投递记录，包括尝试次数、响应状态码和错误，最新的在前",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "获取Webhook投递记录",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "返回条数，默认20，最多100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "投递记录数组",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-array_models_WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/csrf": {
+            "get": {
+                "description": "返回当前Session的CSRF令牌，不存在Session时会创建。所有POST/PUT/DELETE请求都需要在X-CSRF-Token请求头中携带该令牌；注销后需重新获取",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "获取CSRF令牌",
+                "responses": {
+                    "200": {
+                        "description": "获取成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-handlers_CSRFTokenResult"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "用户使用用户名和密码登录系统，登录成功后更换Session和CSRF令牌（响应中返回新令牌）。已启用两步验证的用户返回two_factor_required，需继续调用 /auth/login/2fa",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "用户登录",
+                "parameters": [
+                    {
+                        "description": "登录信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "登录成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-handlers_LoginResult"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户名或密码错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "账号已停用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "目录用户的用户名已被其他账号占用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "登录失败次数过多，账号或IP被临时锁定，Retry-After头给出等待秒数",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login/2fa": {
+            "post": {
+                "description": "用户名密码验证通过后，在5分钟内提交TOTP验证码或恢复码完成登录",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "两步验证登录",
+                "parameters": [
+                    {
+                        "description": "验证码或恢复码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "登录成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-handlers_LoginResult"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或验证码错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "登录已过期",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "账号已停用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "失败次数过多，Retry-After头给出等待秒数",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "description": "用户注销登录，清除session。需要用户已登录。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "用户注销",
+                "responses": {
+                    "204": {
+                        "description": "注销成功"
+                    },
+                    "401": {
+                        "description": "未登录用户无法注销",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/callback": {
+            "get": {
+                "description": "身份提供方回调地址。校验state后用授权码换取ID Token，首次登录时自动创建本地用户，然后建立与密码登录相同的Session",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "单点登录回调",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "授权码",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "state",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "登录成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-handlers_LoginResult"
+                        }
+                    },
+                    "302": {
+                        "description": "配置了登录后地址时跳转"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "单点登录失败或登录请求已过期",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "账号已停用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/login": {
+            "get": {
+                "description": "跳转到OpenID Connect身份提供方进行登录（授权码模式 + PKCE）",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "单点登录",
+                "responses": {
+                    "302": {
+                        "description": "跳转到身份提供方"
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password-reset": {
+            "post": {
+                "description": "向注册邮箱发送一次性重置链接。无论邮箱是否存在都返回相同的响应",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "申请重置密码",
+                "parameters": [
+                    {
+                        "description": "注册邮箱",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "请求已受理",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-any"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password-reset/confirm": {
+            "post": {
+                "description": "使用邮件中的一次性令牌设置新密码，成功后该用户的所有登录状态失效",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "确认重置密码",
+                "parameters": [
+                    {
+                        "description": "令牌和新密码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PasswordResetConfirmRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "重置成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-any"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或令牌无效",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "新用户注册账号。用户名以字母开头，3-32位；密码需满足服务端配置的强度策略，未通过的规则在errors中返回",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "用户注册",
+                "parameters": [
+                    {
+                        "description": "注册信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "注册成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-any"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误、用户名或密码不符合要求",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "用户名已存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/books": {
+            "get": {
+                "description": "获取系统中的所有图书列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "获取所有图书",
+                "responses": {
+                    "200": {
+                        "description": "图书列表",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-array_models_Book"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/search": {
+            "get": {
+                "description": "通过关键词搜索图书，支持标题、作者等字段的模糊匹配",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "根据关键词搜索图书",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "搜索关键词",
+                        "name": "keyword",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "搜索结果",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-array_models_Book"
+                        }
+                    },
+                    "400": {
+                        "description": "搜索关键词不能为空",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/search/author": {
+            "get": {
+                "description": "通过精确的作者名称搜索图书，返回该作者的所有图书",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "根据作者搜索图书",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "作者名称",
+                        "name": "author",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "搜索到的图书列表",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-array_models_Book"
+                        }
+                    },
+                    "400": {
+                        "description": "作者不能为空",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/search/title": {
+            "get": {
+                "description": "通过书名中的关键词进行模糊搜索，返回匹配的图书列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "根据书名关键词搜索图书",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "书名关键词",
+                        "name": "titlekeyword",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "搜索到的图书列表",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-array_models_Book"
+                        }
+                    },
+                    "400": {
+                        "description": "书名关键词不能为空",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/title": {
+            "get": {
+                "description": "通过图书标题获取特定图书的详细信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "根据标题获取图书信息",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "图书标题",
+                        "name": "title",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "图书信息",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-models_Book"
+                        }
+                    },
+                    "400": {
+                        "description": "标题不能为空",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "图书不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}": {
+            "get": {
+                "description": "通过图书ID获取特定图书的详细信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "根据ID获取图书信息",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "图书ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "图书信息",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-models_Book"
+                        }
+                    },
+                    "400": {
+                        "description": "无效的图书ID",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "图书不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/borrow": {
+            "post": {
+                "description": "用户借阅指定图书（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "borrow"
+                ],
+                "summary": "借阅图书",
+                "parameters": [
+                    {
+                        "description": "借书信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BorrowBookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "借书成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-any"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "图书不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "库存不足或借阅次数已达上限",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/borrow/records": {
+            "get": {
+                "description": "获取当前用户的所有借阅记录（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "borrow"
+                ],
+                "summary": "获取用户借阅记录",
+                "responses": {
+                    "200": {
+                        "description": "借阅记录数组",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-array_models_BorrowRecord"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/borrow/return": {
+            "post": {
+                "description": "用户归还已借阅的图书（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "borrow"
+                ],
+                "summary": "归还图书",
+                "parameters": [
+                    {
+                        "description": "还书信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReturnBookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "还书成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-any"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "借阅记录或图书不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "图书已归还",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/availability": {
+            "get": {
+                "description": "Server-Sent Events 流。每次借还书后推送 event: availability，data为图书ID和当前库存（需要登录）。\n新连接先推送一次当前库存；断线重连时浏览器会带上Last-Event-ID请求头，服务端补发错过的事件，\n错过的事件已不在缓存中时重新推送当前库存。没有事件时定期发送注释行保持连接",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "订阅图书库存变化",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "图书ID，可以重复，最多50个",
+                        "name": "book_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "最后收到的事件ID",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "同Last-Event-ID，用于无法设置请求头的客户端",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "事件流",
+                        "schema": {
+                            "$ref": "#/definitions/services.Availability"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "图书不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/circulation": {
+            "get": {
+                "description": "Server-Sent Events 流，推送全部借书（loan.created）、还书（loan.returned）事件和库存变化（availability），data为借阅记录或库存（管理员）。\n断线重连时根据Last-Event-ID补发缓存中错过的事件",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "订阅全部借还事件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "最后收到的事件ID",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "同Last-Event-ID，用于无法设置请求头的客户端",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "事件流",
+                        "schema": {
+                            "$ref": "#/definitions/models.BorrowRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me": {
+            "get": {
+                "description": "获取当前登录用户的个人资料（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "获取个人资料",
+                "responses": {
+                    "200": {
+                        "description": "个人资料",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-models_User"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "更新当前登录用户的邮箱、手机号和语言偏好，传空字符串表示清除（需要登录）。语言偏好为zh-CN或en-US，设置后优先于Accept-Language",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "更新个人资料",
+                "parameters": [
+                    {
+                        "description": "个人资料",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新后的个人资料",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-models_User"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "邮箱已被使用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/disable": {
+            "post": {
+                "description": "验证密码和验证码（或恢复码）后关闭两步验证（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "关闭两步验证",
+                "parameters": [
+                    {
+                        "description": "密码和验证码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DisableTwoFactorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "关闭成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-any"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或验证码错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "密码错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "两步验证未启用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "失败次数过多，Retry-After头给出等待秒数",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/enable": {
+            "post": {
+                "description": "提交验证器应用生成的验证码确认密钥，返回只显示一次的恢复码（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "启用两步验证",
+                "parameters": [
+                    {
+                        "description": "验证码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "启用成功",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-handlers_RecoveryCodesResult"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或验证码错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "尚未生成密钥",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "两步验证已启用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/qrcode": {
+            "get": {
+                "description": "返回待确认密钥的二维码PNG图片，供验证器应用扫描（需要登录）",
+                "produces": [
+                    "image/png"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "获取两步验证二维码",
+                "responses": {
+                    "200": {
+                        "description": "二维码图片",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "尚未生成密钥",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "两步验证已启用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/recovery-codes": {
+            "post": {
+                "description": "验证码校验通过后作废所有旧恢复码并生成新的一组（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "重新生成恢复码",
+                "parameters": [
+                    {
+                        "description": "验证码",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "新的恢复码",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-handlers_RecoveryCodesResult"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或验证码错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "两步验证未启用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "失败次数过多，Retry-After头给出等待秒数",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/2fa/setup": {
+            "post": {
+                "description": "生成新的TOTP密钥和otpauth URI，需调用启用接口确认后才生效（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "生成两步验证密钥",
+                "responses": {
+                    "200": {
+                        "description": "密钥和URI",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-services_TOTPSetup"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "两步验证已启用",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/notification-preferences": {
+            "get": {
+                "description": "获取当前登录用户接收通知的渠道，未设置时全部开启（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "获取通知设置",
+                "responses": {
+                    "200": {
+                        "description": "通知设置",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-models_NotificationPreference"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "设置当前登录用户是否接收邮件和站内信通知（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "更新通知设置",
+                "parameters": [
+                    {
+                        "description": "通知设置",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateNotificationPreferenceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新后的通知设置",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-models_NotificationPreference"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/notifications": {
+            "get": {
+                "description": "获取当前登录用户的站内信，最新的在前，同时返回未读数量（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "获取站内信",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "只返回未读通知",
+                        "name": "unread",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "返回条数，默认50，最多100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "站内信",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-services_NotificationInbox"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/notifications/read-all": {
+            "post": {
+                "description": "将当前登录用户的全部通知标记为已读（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "全部标记已读",
+                "responses": {
+                    "204": {
+                        "description": "标记成功"
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/notifications/{id}/read": {
+            "post": {
+                "description": "将当前登录用户的一条通知标记为已读（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "标记通知已读",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "通知ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "标记成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "通知不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/notifications/{id}/unread": {
+            "post": {
+                "description": "将当前登录用户的一条通知标记为未读（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "标记通知未读",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "通知ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "标记成功"
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "通知不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/password": {
+            "post": {
+                "description": "验证当前密码后设置新密码（需要登录）。当前密码错误计入登录失败次数；修改成功后其他设备上的登录全部失效，当前Session更换ID和CSRF令牌",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "修改密码",
+                "parameters": [
+                    {
+                        "description": "密码信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "修改成功，返回当前Session新的CSRF令牌",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-handlers_CSRFTokenResult"
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误或新密码不符合要求",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "当前密码错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "用户不存在",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "当前密码错误次数过多，Retry-After头给出等待秒数",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/summary": {
+            "get": {
+                "description": "一次性返回当前用户的在借图书、逾期数量和借阅上限（需要登录）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "获取账户概览",
+                "responses": {
+                    "200": {
+                        "description": "账户概览",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.Response-services_AccountSummary"
+                        }
+                    },
+                    "401": {
+                        "description": "用户未认证",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Problem"
+                        }
+                    }
+                }
+            }
+        }
     },
     "definitions": {
         "handlers.AddBookRequest": {
@@ -786,220 +2571,1116 @@ const docTemplate = `{
             "properties": {
                 "author": {
                     "type": "string",
-                    "example": "Lemon"
+                    "example": "Lemon"
+                },
+                "stock": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "title": {
+                    "type": "string",
+                    "example": "LemonisTheBestFruit"
+                }
+            }
+        },
+        "handlers.BorrowBookRequest": {
+            "type": "object",
+            "required": [
+                "book_id"
+            ],
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers.CSRFTokenResult": {
+            "type": "object",
+            "properties": {
+                "csrf_token": {
+                    "type": "string",
+                    "example": "J8Yq1c3nZt0rW2vX5bD7fH9kL4mP6sU8aE0gI2oQ1yR"
+                }
+            }
+        },
+        "handlers.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "current_password",
+                "new_password"
+            ],
+            "properties": {
+                "current_password": {
+                    "type": "string",
+                    "example": "password123"
+                },
+                "new_password": {
+                    "type": "string",
+                    "example": "lemon2024tree"
+                }
+            }
+        },
+        "handlers.CheckInRequest": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "card_number": {
+                    "type": "string",
+                    "example": "L2024000123"
+                },
+                "record_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers.CheckOutRequest": {
+            "type": "object",
+            "required": [
+                "book_id"
+            ],
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "card_number": {
+                    "type": "string",
+                    "example": "L2024000123"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers.ClearLockoutRequest": {
+            "type": "object",
+            "required": [
+                "key"
+            ],
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "example": "user:lemon"
+                }
+            }
+        },
+        "handlers.DeleteBookRequest": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers.DisableTwoFactorRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "lemon2024tree"
+                }
+            }
+        },
+        "handlers.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "password123"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "handlers.LoginResult": {
+            "type": "object",
+            "properties": {
+                "csrf_token": {
+                    "description": "登录成功后Session中的新CSRF令牌，登录前获取的令牌失效",
+                    "type": "string",
+                    "example": "J8Yq1c3nZt0rW2vX5bD7fH9kL4mP6sU8aE0gI2oQ1yR"
+                },
+                "two_factor_required": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                }
+            }
+        },
+        "handlers.PasswordResetConfirmRequest": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "token"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "lemon2024tree"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "q1w2e3r4t5y6"
+                }
+            }
+        },
+        "handlers.PasswordResetRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "lemon@example.com"
+                }
+            }
+        },
+        "handlers.RecoveryCodesResult": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "abcde-23456",
+                        "fghjk-78923"
+                    ]
+                }
+            }
+        },
+        "handlers.RegisterRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "lemon2024tree"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "user123"
+                }
+            }
+        },
+        "handlers.Response-any": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-array_models_Book": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Book"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-array_models_BorrowRecord": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BorrowRecord"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-array_models_JobRun": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.JobRun"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-array_models_LoginThrottle": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LoginThrottle"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-array_models_Webhook": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Webhook"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-array_models_WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookDelivery"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-array_services_JobInfo": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.JobInfo"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-handlers_CSRFTokenResult": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/handlers.CSRFTokenResult"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-handlers_LoginResult": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/handlers.LoginResult"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-handlers_RecoveryCodesResult": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/handlers.RecoveryCodesResult"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-models_Book": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Book"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-models_BorrowRecord": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.BorrowRecord"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-models_JobRun": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.JobRun"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-models_NotificationPreference": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.NotificationPreference"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-models_User": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.User"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-models_Webhook": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Webhook"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-services_AccountSummary": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/services.AccountSummary"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-services_CreatedWebhook": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/services.CreatedWebhook"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-services_NotificationInbox": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/services.NotificationInbox"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.Response-services_TOTPSetup": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/services.TOTPSetup"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "操作成功"
+                }
+            }
+        },
+        "handlers.ReturnBookRequest": {
+            "type": "object",
+            "required": [
+                "record_id"
+            ],
+            "properties": {
+                "record_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers.SetCardNumberRequest": {
+            "type": "object",
+            "properties": {
+                "card_number": {
+                    "type": "string",
+                    "example": "L2024000123"
+                }
+            }
+        },
+        "handlers.TwoFactorCodeRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "handlers.UpdateBookRequest": {
+            "type": "object",
+            "required": [
+                "author",
+                "id",
+                "stock",
+                "title"
+            ],
+            "properties": {
+                "author": {
+                    "type": "string",
+                    "example": "Lemon"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "stock": {
+                    "type": "integer",
+                    "example": 15
+                },
+                "title": {
+                    "type": "string",
+                    "example": "LemonisTheBestFruit"
+                }
+            }
+        },
+        "handlers.UpdateNotificationPreferenceRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "in_app"
+            ],
+            "properties": {
+                "email": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "in_app": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "handlers.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "lemon@example.com"
+                },
+                "locale": {
+                    "type": "string",
+                    "example": "en-US"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "13800000000"
+                }
+            }
+        },
+        "handlers.WebhookRequest": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "active": {
+                    "description": "不传时为true",
+                    "type": "boolean",
+                    "example": true
+                },
+                "events": {
+                    "description": "book.created、book.updated、book.deleted、loan.created、loan.returned或loan.overdue",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "loan.created",
+                        "loan.returned"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://portal.example.com/hooks/library"
+                }
+            }
+        },
+        "middleware.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "BOOK_NOT_FOUND"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "未找到该图书"
+                },
+                "error": {
+                    "description": "非release模式下返回原始错误，便于调试",
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/v1/borrow"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "models.Book": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string",
+                    "example": "Lemon"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "stock": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "title": {
+                    "type": "string",
+                    "example": "LemonisTheBestFruit"
+                }
+            }
+        },
+        "models.BorrowRecord": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "borrowed_at": {
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "checked_in_by": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "checked_out_by": {
+                    "description": "在服务台经办借出和归还的馆员，读者自助借还时为空",
+                    "type": "integer",
+                    "example": 2
+                },
+                "due_date": {
+                    "type": "string",
+                    "example": "2024-02-15T10:30:00Z"
                 },
-                "stock": {
+                "id": {
                     "type": "integer",
-                    "example": 10
+                    "example": 1
                 },
-                "title": {
+                "returned_at": {
                     "type": "string",
-                    "example": "LemonisTheBestFruit"
-                }
-            }
-        },
-        "handlers.BorrowBookRequest": {
-            "type": "object",
-            "required": [
-                "book_id"
-            ],
-            "properties": {
-                "book_id": {
+                    "example": "2024-01-18T09:15:00Z"
+                },
+                "user_id": {
                     "type": "integer",
                     "example": 1
                 }
             }
         },
-        "handlers.DeleteBookRequest": {
+        "models.JobRun": {
             "type": "object",
-            "required": [
-                "id"
-            ],
             "properties": {
+                "duration_ms": {
+                    "type": "integer",
+                    "example": 842
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2024-01-15T03:30:01Z"
+                },
                 "id": {
                     "type": "integer",
                     "example": 1
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "library-1:4821"
+                },
+                "job": {
+                    "type": "string",
+                    "example": "session_purge"
+                },
+                "result": {
+                    "description": "运行结果摘要，例如处理的记录数",
+                    "type": "string",
+                    "example": "deleted=12"
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2024-01-15T03:30:00Z"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "succeeded"
+                },
+                "trigger": {
+                    "type": "string",
+                    "example": "schedule"
                 }
             }
         },
-        "handlers.ErrorResponse": {
+        "models.LoginThrottle": {
             "type": "object",
             "properties": {
-                "error": {
-                    "type": "string"
+                "failures": {
+                    "type": "integer",
+                    "example": 6
                 },
-                "message": {
-                    "type": "string"
+                "key": {
+                    "type": "string",
+                    "example": "user:lemon"
+                },
+                "last_failed_at": {
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "locked_until": {
+                    "type": "string",
+                    "example": "2024-01-15T10:32:00Z"
                 }
             }
         },
-        "handlers.LoginRequest": {
+        "models.Notification": {
             "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
             "properties": {
-                "password": {
+                "body": {
                     "type": "string",
-                    "example": "password123"
+                    "example": "您借阅的《三体》将于2024-02-15到期，请按时归还。"
                 },
-                "username": {
+                "created_at": {
                     "type": "string",
-                    "example": "user123"
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "read_at": {
+                    "type": "string",
+                    "example": "2024-01-18T09:15:00Z"
+                },
+                "record_id": {
+                    "description": "关联的借阅记录",
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "type": "string",
+                    "example": "图书即将到期"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "due_soon"
                 }
             }
         },
-        "handlers.LoginResponse": {
+        "models.NotificationPreference": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "登录成功"
+                "email": {
+                    "type": "boolean",
+                    "example": true
                 },
-                "user": {
-                    "$ref": "#/definitions/models.User"
+                "in_app": {
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
-        "handlers.RegisterRequest": {
+        "models.User": {
             "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
             "properties": {
-                "password": {
+                "active": {
+                    "description": "停用的账号无法登录，目录同步时不再存在的用户会被停用",
+                    "type": "boolean",
+                    "example": true
+                },
+                "auth_provider": {
+                    "description": "外部身份提供方的用户标识，本地用户为空",
                     "type": "string",
-                    "example": "password123"
+                    "example": "local"
                 },
-                "username": {
+                "card_number": {
+                    "description": "借书证号，由管理员登记，馆员在服务台可凭证号为读者借书",
                     "type": "string",
-                    "example": "user123"
+                    "example": "L2024000123"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "lemon@example.com"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 123
+                },
+                "locale": {
+                    "description": "界面语言偏好，为空时按请求的Accept-Language协商",
+                    "type": "string",
+                    "example": "en-US"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "lemon"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "13800000000"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "totp_enabled": {
+                    "type": "boolean",
+                    "example": false
                 }
             }
         },
-        "handlers.RegisterResponse": {
+        "models.Webhook": {
             "type": "object",
             "properties": {
-                "message": {
+                "active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "events": {
+                    "description": "订阅的事件类型",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "loan.created",
+                        "loan.returned"
+                    ]
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "url": {
                     "type": "string",
-                    "example": "用户注册成功"
+                    "example": "https://portal.example.com/hooks/library"
                 }
             }
         },
-        "handlers.ReturnBookRequest": {
+        "models.WebhookDelivery": {
             "type": "object",
-            "required": [
-                "record_id"
-            ],
             "properties": {
-                "record_id": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "delivered_at": {
+                    "type": "string",
+                    "example": "2024-01-15T10:31:00Z"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "event_type": {
+                    "type": "string",
+                    "example": "loan.created"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "next_attempt_at": {
+                    "description": "下次尝试的时间，只对pending有意义",
+                    "type": "string",
+                    "example": "2024-01-15T10:31:00Z"
+                },
+                "response_status": {
+                    "description": "最近一次尝试的响应状态码，请求未完成时为0",
+                    "type": "integer",
+                    "example": 200
+                },
+                "status": {
+                    "type": "string",
+                    "example": "succeeded"
+                },
+                "webhook_id": {
                     "type": "integer",
                     "example": 1
                 }
             }
         },
-        "handlers.SuccessResponse": {
+        "services.AccountSummary": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "操作成功"
+                "active_count": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "active_loans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BorrowRecord"
+                    }
+                },
+                "borrow_limit": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "overdue_count": {
+                    "type": "integer",
+                    "example": 1
                 }
             }
         },
-        "handlers.UpdateBookRequest": {
+        "services.Availability": {
             "type": "object",
-            "required": [
-                "author",
-                "id",
-                "stock",
-                "title"
-            ],
             "properties": {
-                "author": {
-                    "type": "string",
-                    "example": "Lemon"
-                },
-                "id": {
+                "book_id": {
                     "type": "integer",
                     "example": 1
                 },
                 "stock": {
                     "type": "integer",
-                    "example": 15
-                },
-                "title": {
-                    "type": "string",
-                    "example": "LemonisTheBestFruit"
+                    "example": 4
                 }
             }
         },
-        "models.Book": {
+        "services.CreatedWebhook": {
             "type": "object",
             "properties": {
-                "author": {
+                "active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "created_at": {
                     "type": "string",
-                    "example": "Lemon"
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "events": {
+                    "description": "订阅的事件类型",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "loan.created",
+                        "loan.returned"
+                    ]
                 },
                 "id": {
                     "type": "integer",
                     "example": 1
                 },
-                "stock": {
-                    "type": "integer",
-                    "example": 10
+                "secret": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
                 },
-                "title": {
+                "updated_at": {
                     "type": "string",
-                    "example": "LemonisTheBestFruit"
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://portal.example.com/hooks/library"
                 }
             }
         },
-        "models.BorrowRecord": {
+        "services.FieldError": {
             "type": "object",
             "properties": {
-                "book_id": {
-                    "type": "integer",
-                    "example": 1
+                "field": {
+                    "type": "string",
+                    "example": "password"
                 },
-                "borrowed_at": {
+                "message": {
                     "type": "string",
-                    "example": "2024-01-15T10:30:00Z"
+                    "example": "密码长度不能少于8位"
                 },
-                "due_date": {
+                "rule": {
                     "type": "string",
-                    "example": "2024-02-15T10:30:00Z"
+                    "example": "min_length"
+                }
+            }
+        },
+        "services.JobInfo": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "清理已过期的登录会话"
                 },
-                "id": {
-                    "type": "integer",
-                    "example": 1
+                "last_run": {
+                    "$ref": "#/definitions/models.JobRun"
                 },
-                "returned_at": {
+                "name": {
                     "type": "string",
-                    "example": "2024-01-18T09:15:00Z"
+                    "example": "session_purge"
                 },
-                "user_id": {
-                    "type": "integer",
-                    "example": 1
+                "next_run_at": {
+                    "type": "string",
+                    "example": "2024-01-16T03:30:00Z"
+                },
+                "schedule": {
+                    "description": "为空时只能手动触发",
+                    "type": "string",
+                    "example": "30 3 * * *"
                 }
             }
         },
-        "models.User": {
+        "services.NotificationInbox": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer",
-                    "example": 123
+                "notifications": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Notification"
+                    }
                 },
-                "name": {
+                "unread_count": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "services.TOTPSetup": {
+            "type": "object",
+            "properties": {
+                "otpauth_uri": {
                     "type": "string",
-                    "example": "lemon"
+                    "example": "otpauth://totp/LibrarySystem:lemon?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP\u0026issuer=LibrarySystem"
                 },
-                "role": {
+                "secret": {
                     "type": "string",
-                    "example": "admin"
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
                 }
             }
         }
@@ -1010,6 +3691,12 @@ const docTemplate = `{
             "type": "apiKey",
             "name": "library-session",
             "in": "cookie"
+        },
+        "CSRFToken": {
+            "description": "POST/PUT/DELETE请求需要携带 GET /auth/csrf 返回的令牌",
+            "type": "apiKey",
+            "name": "X-CSRF-Token",
+            "in": "header"
         }
     }
 }`
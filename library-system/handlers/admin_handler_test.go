@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"library-system/models"
+	"net/http"
+	"testing"
+)
+
+func TestAdminGuard(t *testing.T) {
+	tests := []struct {
+		name             string
+		requireTwoFactor bool
+		// login 返回发送请求的客户端
+		login      func(t *testing.T, app *testApp) *testClient
+		wantStatus int
+	}{
+		{
+			name:       "未登录",
+			login:      func(t *testing.T, app *testApp) *testClient { return app.newClient(t) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "普通用户",
+			login: func(t *testing.T, app *testApp) *testClient {
+				app.createUser(t, "lemon", models.RoleUser)
+				client := app.newClient(t)
+				client.login("lemon")
+				return client
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "管理员",
+			login: func(t *testing.T, app *testApp) *testClient {
+				app.createUser(t, "root", models.RoleAdmin)
+				client := app.newClient(t)
+				client.login("root")
+				return client
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:             "要求两步验证时未验证的管理员",
+			requireTwoFactor: true,
+			login: func(t *testing.T, app *testApp) *testClient {
+				app.createUser(t, "root", models.RoleAdmin)
+				client := app.newClient(t)
+				client.login("root")
+				return client
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:             "要求两步验证时已验证的管理员",
+			requireTwoFactor: true,
+			login: func(t *testing.T, app *testApp) *testClient {
+				code := app.enableTwoFactor(t, app.createUser(t, "root", models.RoleAdmin))
+				client := app.newClient(t)
+				client.login("root")
+				client.verifyTwoFactor(code)
+				return client
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, tt.requireTwoFactor)
+			client := tt.login(t, app)
+
+			if status := client.do(http.MethodGet, "/api/v1/admin/borrow-records", nil, nil); status != tt.wantStatus {
+				t.Errorf("GET status = %d, want %d", status, tt.wantStatus)
+			}
+			if status := client.do(http.MethodPost, "/api/v1/admin/books", AddBookRequest{Title: "Go", Author: "Lemon", Stock: 1}, nil); status != tt.wantStatus {
+				t.Errorf("POST status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "root", models.RoleAdmin)
+	admin := app.newClient(t)
+	admin.login("root")
+	book := app.createBook(t, "Go", 1)
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		req        any
+		wantStatus int
+	}{
+		{name: "添加图书", method: http.MethodPost, path: "/api/v1/admin/books", req: AddBookRequest{Title: "Rust", Author: "Lime", Stock: 2}, wantStatus: http.StatusOK},
+		{name: "书名已存在", method: http.MethodPost, path: "/api/v1/admin/books", req: AddBookRequest{Title: "go", Author: "Lime", Stock: 2}, wantStatus: http.StatusConflict},
+		{name: "删除图书", method: http.MethodDelete, path: "/api/v1/admin/books", req: DeleteBookRequest{ID: book.ID}, wantStatus: http.StatusOK},
+		{name: "删除不存在的图书", method: http.MethodDelete, path: "/api/v1/admin/books", req: DeleteBookRequest{ID: book.ID}, wantStatus: http.StatusNotFound},
+		{name: "解除不存在的锁定", method: http.MethodDelete, path: "/api/v1/admin/lockouts", req: ClearLockoutRequest{Key: "user:nobody"}, wantStatus: http.StatusNotFound},
+	}
+	for _, step := range steps {
+		if status := admin.do(step.method, step.path, step.req, nil); status != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d", step.name, status, step.wantStatus)
+		}
+	}
+}
@@ -1,166 +1,226 @@
-package handlers
-
-import (
-	"errors"
-	"library-system/models"
-	"library-system/services"
-	"net/http"
-
-	"github.com/gin-gonic/gin"
-	"github.com/gorilla/sessions"
-)
-
-type AuthHandler struct {
-	authService  *services.AuthService
-	sessionStore sessions.Store
-}
-
-func NewAuthHandler(authService *services.AuthService, sessionStore sessions.Store) *AuthHandler {
-	return &AuthHandler{authService: authService, sessionStore: sessionStore}
-}
-
-// Login godoc
-// @Summary 用户登录
-// @Description 用户使用用户名和密码登录系统，登录成功后设置Session
-// @Tags auth
-// @Accept json
-// @Produce json
-// @Param request body LoginRequest true "登录信息"
-// @Success 200 {object} LoginResponse "登录成功"
-// @Failure 400 {object} ErrorResponse "请求参数错误"
-// @Failure 401 {object} ErrorResponse "用户名或密码错误"
-// @Failure 500 {object} ErrorResponse "服务器内部错误"
-// @Router /auth/login [post]
-func (h *AuthHandler) Login(c *gin.Context) {
-	var req LoginRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, "请求参数格式错误", err)
-		return
-	}
-
-	// 登录
-	user, err := h.authService.Login(req.Username, req.Password)
-	if err != nil {
-		if errors.Is(err, services.ErrUserNotFound) || errors.Is(err, services.ErrInvalidPassword) {
-			Unauthorized(c, "用户名或密码错误", err)
-			return
-		} else {
-			InternalError(c, "登录失败", err)
-			return
-		}
-	}
-
-	// session处理
-	session, err := h.sessionStore.Get(c.Request, "library-session")
-	if err != nil {
-		InternalError(c, "Session初始化失败", err)
-		return
-	}
-
-	session.Values["authenticated"] = true
-	session.Values["userID"] = user.ID
-	session.Values["username"] = user.Name
-	session.Values["role"] = user.Role
-
-	// 保存Session
-	err = session.Save(c.Request, c.Writer)
-	if err != nil {
-		InternalError(c, "无法保存Session", err)
-		return
-	}
-
-	c.JSON(http.StatusOK, LoginResponse{
-		Message: "登录成功",
-		User:    user,
-	})
-}
-
-// Register godoc
-// @Summary 用户注册
-// @Description 新用户注册账号
-// @Tags auth
-// @Accept json
-// @Produce json
-// @Param request body RegisterRequest true "注册信息"
-// @Success 201 {object} RegisterResponse "注册成功"
-// @Failure 400 {object} ErrorResponse "请求参数错误或用户名已存在"
-// @Failure 500 {object} ErrorResponse "服务器内部错误"
-// @Router /auth/register [post]
-func (h *AuthHandler) Register(c *gin.Context) {
-	var req RegisterRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, "请求参数格式错误", err)
-		return
-	}
-
-	// 注册用户
-	err := h.authService.Register(req.Username, req.Password)
-	if err != nil {
-		if errors.Is(err, services.ErrUserExists) {
-			BadRequest(c, "用户名已存在", err)
-			return
-		} else {
-			InternalError(c, "用户注册失败", err)
-			return
-		}
-	}
-
-	c.JSON(http.StatusCreated, RegisterResponse{Message: "用户注册成功"})
-}
-
-// Logout godoc
-// @Summary 用户注销
-// @Description 用户注销登录，清除session。需要用户已登录。
-// @Tags auth
-// @Accept json
-// @Produce json
-// @Success 204 "注销成功"
-// @Failure 401 {object} ErrorResponse "未登录用户无法注销"
-// @Failure 500 {object} ErrorResponse "服务器内部错误"
-// @Router /auth/logout [post]
-func (h *AuthHandler) Logout(c *gin.Context) {
-	// 获取Session
-	session, err := h.sessionStore.Get(c.Request, "library-session")
-	if err != nil {
-		InternalError(c, "Session错误", err)
-		return
-	}
-
-	// 检查是否已登录
-	if session.IsNew {
-		Unauthorized(c, "未登录用户无法注销", nil)
-		return
-	}
-
-	// 注销用户
-	session.Options.MaxAge = -1
-	err = session.Save(c.Request, c.Writer)
-	if err != nil {
-		InternalError(c, "注销失败", err)
-		return
-	}
-
-	c.Status(http.StatusNoContent)
-}
-
-// 请求和响应结构体定义
-type RegisterRequest struct {
-	Username string `json:"username" binding:"required" example:"user123"`
-	Password string `json:"password" binding:"required" example:"password123"`
-}
-
-type LoginRequest struct {
-	Username string `json:"username" binding:"required" example:"user123"`
-	Password string `json:"password" binding:"required" example:"password123"`
-}
-
-type LoginResponse struct {
-	Message string       `json:"message" example:"登录成功"`
-	User    *models.User `json:"user"`
-}
-
-type RegisterResponse struct {
-	Message string `json:"message" example:"用户注册成功"`
-}
+package handlers
+
+import (
+	"errors"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+type AuthHandler struct {
+	authService  *services.AuthService
+	sessionStore sessions.Store
+}
+
+func NewAuthHandler(authService *services.AuthService, sessionStore sessions.Store) *AuthHandler {
+	return &AuthHandler{authService: authService, sessionStore: sessionStore}
+}
+
+// Login godoc
+// @Summary 用户登录
+// @Description 用户使用用户名和密码登录系统，登录成功后更换Session和CSRF令牌（响应中返回新令牌）。已启用两步验证的用户返回two_factor_required，需继续调用 /auth/login/2fa
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body LoginRequest true "登录信息"
+// @Success 200 {object} Response[LoginResult] "登录成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户名或密码错误"
+// @Failure 403 {object} middleware.Problem "账号已停用"
+// @Failure 409 {object} middleware.Problem "目录用户的用户名已被其他账号占用"
+// @Failure 429 {object} middleware.Problem "登录失败次数过多，账号或IP被临时锁定，Retry-After头给出等待秒数"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req LoginRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 登录
+	user, err := h.authService.Login(c.Request.Context(), req.Username, req.Password, c.ClientIP())
+	if err != nil {
+		// 不区分用户不存在和密码错误，避免暴露用户名是否已注册
+		if errors.Is(err, services.ErrUserNotFound) || errors.Is(err, services.ErrInvalidPassword) {
+			err = withCode(services.ErrInvalidCredentials, err)
+		}
+		c.Error(err)
+		return
+	}
+
+	// session处理
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 已启用两步验证的用户先进入待验证状态
+	if user.TOTPEnabled {
+		session.Values["authenticated"] = false
+		session.Values["pendingUserID"] = user.ID
+		session.Values["pendingAt"] = time.Now().Unix()
+
+		if err := session.Save(c.Request, c.Writer); err != nil {
+			c.Error(err)
+			return
+		}
+
+		Success(c, http.StatusOK, "auth.two_factor_required", LoginResult{TwoFactorRequired: true})
+		return
+	}
+
+	// 保存Session
+	csrfToken, err := establishSession(c, session, user, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "auth.login_success", LoginResult{User: user, CSRFToken: csrfToken})
+}
+
+// establishSession 更换Session ID和CSRF令牌，将已认证的用户信息写入Session并保存，返回新的CSRF令牌
+func establishSession(c *gin.Context, session *sessions.Session, user *models.User, twoFactorVerified bool) (string, error) {
+	if err := middleware.RenewSession(c.Request, session); err != nil {
+		return "", err
+	}
+
+	delete(session.Values, "pendingUserID")
+	delete(session.Values, "pendingAt")
+
+	session.Values["authenticated"] = true
+	session.Values["userID"] = user.ID
+	session.Values["username"] = user.Name
+	session.Values["role"] = user.Role
+	session.Values["twoFactorVerified"] = twoFactorVerified
+	session.Values["locale"] = user.Locale
+
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		return "", err
+	}
+	csrfToken, _ := session.Values["csrfToken"].(string)
+	return csrfToken, nil
+}
+
+// Register godoc
+// @Summary 用户注册
+// @Description 新用户注册账号。用户名以字母开头，3-32位；密码需满足服务端配置的强度策略，未通过的规则在errors中返回
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RegisterRequest true "注册信息"
+// @Success 201 {object} Response[any] "注册成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误、用户名或密码不符合要求"
+// @Failure 409 {object} middleware.Problem "用户名已存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 注册用户
+	err := h.authService.Register(c.Request.Context(), req.Username, req.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Message(c, http.StatusCreated, "auth.registered")
+}
+
+// Logout godoc
+// @Summary 用户注销
+// @Description 用户注销登录，清除session。需要用户已登录。
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 204 "注销成功"
+// @Failure 401 {object} middleware.Problem "未登录用户无法注销"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	// 获取Session
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 检查是否已登录
+	if session.IsNew {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+
+	// 注销用户
+	session.Options.MaxAge = -1
+	err = session.Save(c.Request, c.Writer)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CSRFToken godoc
+// @Summary 获取CSRF令牌
+// @Description 返回当前Session的CSRF令牌，不存在Session时会创建。所有POST/PUT/DELETE请求都需要在X-CSRF-Token请求头中携带该令牌；注销后需重新获取
+// @Tags auth
+// @Produce json
+// @Success 200 {object} Response[CSRFTokenResult] "获取成功"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /auth/csrf [get]
+func (h *AuthHandler) CSRFToken(c *gin.Context) {
+	// 获取Session
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	token, err := middleware.CSRFToken(session)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", CSRFTokenResult{CSRFToken: token})
+}
+
+// 请求和响应结构体定义
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required" example:"user123"`
+	Password string `json:"password" binding:"required" example:"lemon2024tree"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required" example:"user123"`
+	Password string `json:"password" binding:"required" example:"password123"`
+}
+
+type LoginResult struct {
+	User              *models.User `json:"user,omitempty"`
+	TwoFactorRequired bool         `json:"two_factor_required,omitempty" example:"false"`
+	// 登录成功后Session中的新CSRF令牌，登录前获取的令牌失效
+	CSRFToken string `json:"csrf_token,omitempty" example:"J8Yq1c3nZt0rW2vX5bD7fH9kL4mP6sU8aE0gI2oQ1yR"`
+}
+
+type CSRFTokenResult struct {
+	CSRFToken string `json:"csrf_token" example:"J8Yq1c3nZt0rW2vX5bD7fH9kL4mP6sU8aE0gI2oQ1yR"`
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestAuthHandler_Register(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        any
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		{name: "注册成功", req: RegisterRequest{Username: "lime", Password: testPassword}, wantStatus: http.StatusCreated},
+		{name: "用户名已存在", req: RegisterRequest{Username: "lemon", Password: testPassword}, wantStatus: http.StatusConflict, wantCode: "USER_EXISTS"},
+		{name: "密码太弱", req: RegisterRequest{Username: "lime", Password: "short"}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED", wantField: "password"},
+		{name: "缺少字段", req: map[string]string{"username": "lime"}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED", wantField: "password"},
+		{name: "字段类型错误", req: map[string]any{"username": "lime", "password": 2024}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED", wantField: "password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, false)
+			app.createUser(t, "lemon", models.RoleUser)
+			client := app.newClient(t)
+
+			var resp middleware.Problem
+			if status := client.do(http.MethodPost, "/api/v1/auth/register", tt.req, &resp); status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", resp.Code, tt.wantCode)
+			}
+			if tt.wantField != "" && (len(resp.Errors) == 0 || resp.Errors[0].Field != tt.wantField) {
+				t.Errorf("errors = %+v, want field %q", resp.Errors, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_Login(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, app *testApp)
+		password   string
+		wantStatus int
+	}{
+		{
+			name:       "登录成功",
+			setup:      func(t *testing.T, app *testApp) {},
+			password:   testPassword,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "密码错误",
+			setup:      func(t *testing.T, app *testApp) {},
+			password:   "wrong-password1",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "账号已停用",
+			setup: func(t *testing.T, app *testApp) {
+				user, err := app.repos.Users.GetByUsername(context.Background(), "lemon")
+				if err != nil {
+					t.Fatal(err)
+				}
+				user.Active = false
+				if err := app.repos.Users.Update(context.Background(), user); err != nil {
+					t.Fatal(err)
+				}
+			},
+			password:   testPassword,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, false)
+			app.createUser(t, "lemon", models.RoleUser)
+			tt.setup(t, app)
+			client := app.newClient(t)
+
+			var resp Response[LoginResult]
+			status := client.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Username: "lemon", Password: tt.password}, &resp)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+
+			// 只有登录成功后才能访问需要登录的接口
+			wantMe := http.StatusUnauthorized
+			if tt.wantStatus == http.StatusOK {
+				wantMe = http.StatusOK
+				if resp.Data.User == nil || resp.Data.User.Name != "lemon" {
+					t.Errorf("response user = %+v", resp.Data.User)
+				}
+			}
+			if status := client.do(http.MethodGet, "/api/v1/me", nil, nil); status != wantMe {
+				t.Errorf("GET /me status = %d, want %d", status, wantMe)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_LoginLockout(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	client := app.newClient(t)
+
+	for range services.DefaultLoginGuardPolicy().AccountThreshold {
+		if status := client.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Username: "lemon", Password: "wrong-password1"}, nil); status != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", status, http.StatusUnauthorized)
+		}
+	}
+
+	if status := client.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Username: "lemon", Password: testPassword}, nil); status != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+	if seconds, err := strconv.Atoi(client.lastResponse.Header.Get("Retry-After")); err != nil || seconds <= 0 {
+		t.Errorf("Retry-After = %q, want positive seconds", client.lastResponse.Header.Get("Retry-After"))
+	}
+}
+
+func TestAuthHandler_TwoFactorLogin(t *testing.T) {
+	app := newTestApp(t, false)
+	user := app.createUser(t, "lemon", models.RoleUser)
+	code := app.enableTwoFactor(t, user)
+	client := app.newClient(t)
+
+	var resp Response[LoginResult]
+	if status := client.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Username: "lemon", Password: testPassword}, &resp); status != http.StatusOK {
+		t.Fatalf("login status = %d", status)
+	}
+	if !resp.Data.TwoFactorRequired || resp.Data.User != nil {
+		t.Fatalf("response = %+v, want two_factor_required", resp)
+	}
+
+	// 通过第二步之前仍未登录
+	if status := client.do(http.MethodGet, "/api/v1/me", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("GET /me status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := client.do(http.MethodPost, "/api/v1/auth/login/2fa", TwoFactorCodeRequest{Code: "000000"}, nil); status != http.StatusBadRequest {
+		t.Errorf("wrong code status = %d, want %d", status, http.StatusBadRequest)
+	}
+	client.verifyTwoFactor(code)
+	if status := client.do(http.MethodGet, "/api/v1/me", nil, nil); status != http.StatusOK {
+		t.Errorf("GET /me status = %d, want %d", status, http.StatusOK)
+	}
+
+	// 没有待验证登录的客户端不能直接提交验证码
+	other := app.newClient(t)
+	if status := other.do(http.MethodPost, "/api/v1/auth/login/2fa", TwoFactorCodeRequest{Code: code}, nil); status != http.StatusUnauthorized {
+		t.Errorf("2fa without pending login status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthHandler_SessionRenewedOnLogin(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	code := app.enableTwoFactor(t, app.createUser(t, "lime", models.RoleUser))
+
+	tests := []struct {
+		name  string
+		login func(c *testClient)
+	}{
+		{name: "密码登录", login: func(c *testClient) { c.login("lemon") }},
+		{name: "两步验证登录", login: func(c *testClient) {
+			c.login("lime")
+			c.verifyTwoFactor(code)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 攻击者获取的登录前Session，植入受害者的浏览器
+			victim := app.newClient(t)
+			planted := victim.sessionCookie()
+			plantedCSRF := victim.csrf
+
+			tt.login(victim)
+			if cookie := victim.sessionCookie(); cookie == "" || cookie == planted {
+				t.Errorf("session cookie not renewed on login")
+			}
+			if victim.csrf == "" || victim.csrf == plantedCSRF {
+				t.Errorf("csrf token not renewed on login")
+			}
+			if status := victim.do(http.MethodGet, "/api/v1/me", nil, nil); status != http.StatusOK {
+				t.Errorf("victim GET /me status = %d, want %d", status, http.StatusOK)
+			}
+
+			// 攻击者持有的旧Session不能访问受害者的账号
+			attacker := app.newClient(t)
+			attacker.setSessionCookie(planted)
+			attacker.csrf = plantedCSRF
+			if status := attacker.do(http.MethodGet, "/api/v1/me", nil, nil); status != http.StatusUnauthorized {
+				t.Errorf("attacker GET /me status = %d, want %d", status, http.StatusUnauthorized)
+			}
+			if status := attacker.do(http.MethodPut, "/api/v1/me", UpdateProfileRequest{}, nil); status != http.StatusForbidden {
+				t.Errorf("attacker PUT /me status = %d, want %d", status, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestUserHandler_ChangePassword(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	client := app.newClient(t)
+	client.login("lemon")
+	other := app.newClient(t)
+	other.login("lemon")
+	before := client.sessionCookie()
+
+	var resp Response[CSRFTokenResult]
+	if status := client.do(http.MethodPost, "/api/v1/me/password", ChangePasswordRequest{CurrentPassword: testPassword, NewPassword: "orange2025juice"}, &resp); status != http.StatusOK {
+		t.Fatalf("change password status = %d", status)
+	}
+	if client.sessionCookie() == before || resp.Data.CSRFToken == "" || resp.Data.CSRFToken == client.csrf {
+		t.Errorf("session not renewed after password change")
+	}
+
+	// 当前设备使用新令牌继续保持登录，其他设备的登录失效
+	client.csrf = resp.Data.CSRFToken
+	if status := client.do(http.MethodPut, "/api/v1/me", UpdateProfileRequest{}, nil); status != http.StatusOK {
+		t.Errorf("PUT /me status = %d, want %d", status, http.StatusOK)
+	}
+	if status := other.do(http.MethodGet, "/api/v1/me", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("other device GET /me status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthHandler_Logout(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	client := app.newClient(t)
+	client.login("lemon")
+
+	if status := client.do(http.MethodPost, "/api/v1/auth/logout", nil, nil); status != http.StatusNoContent {
+		t.Fatalf("logout status = %d, want %d", status, http.StatusNoContent)
+	}
+	if status := client.do(http.MethodGet, "/api/v1/me", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("GET /me after logout status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	// Session已删除，旧令牌失效
+	if status := client.do(http.MethodPost, "/api/v1/auth/logout", nil, nil); status != http.StatusForbidden {
+		t.Errorf("logout with stale csrf token status = %d, want %d", status, http.StatusForbidden)
+	}
+	client.refreshCSRF()
+	if status := client.do(http.MethodPost, "/api/v1/auth/logout", nil, nil); status != http.StatusNoContent {
+		t.Errorf("logout with fresh session status = %d, want %d", status, http.StatusNoContent)
+	}
+}
+
+func TestCSRFRequired(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	client := app.newClient(t)
+	client.noCSRF = true
+
+	if status := client.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Username: "lemon", Password: testPassword}, nil); status != http.StatusForbidden {
+		t.Errorf("login without csrf token status = %d, want %d", status, http.StatusForbidden)
+	}
+}
@@ -1,177 +1,230 @@
-package handlers
-
-import (
-	"errors"
-	"library-system/models"
-	"library-system/services"
-	"net/http"
-
-	"github.com/gin-gonic/gin"
-)
-
-type BorrowHandler struct {
-	borrowService *services.BorrowService
-}
-
-func NewBorrowHandler(borrowService *services.BorrowService) *BorrowHandler {
-	return &BorrowHandler{borrowService: borrowService}
-}
-
-// BorrowBook godoc
-// @Summary 借阅图书
-// @Description 用户借阅指定图书（需要登录）
-// @Tags borrow
-// @Accept json
-// @Produce json
-// @Param request body BorrowBookRequest true "借书信息"
-// @Success 200 {object} SuccessResponse "借书成功"
-// @Failure 400 {object} ErrorResponse "请求参数错误"
-// @Failure 401 {object} ErrorResponse "用户未认证"
-// @Failure 404 {object} ErrorResponse "图书不存在"
-// @Failure 409 {object} ErrorResponse "库存不足或借阅次数已达上限"
-// @Failure 500 {object} ErrorResponse "服务器内部错误"
-// @Router /borrow [post]
-func (h *BorrowHandler) BorrowBook(c *gin.Context) {
-	var req BorrowBookRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, "请求参数格式错误", err)
-		return
-	}
-
-	// 获取用户信息
-	userObj, exists := c.Get("user")
-	if !exists {
-		Unauthorized(c, "未找到用户信息", nil)
-		return
-	}
-
-	user := userObj.(*models.User)
-
-	// 借书
-	err := h.borrowService.BorrowBook(user.ID, req.BookID)
-	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			BadRequest(c, "请求参数错误", err)
-			return
-		} else if errors.Is(err, services.ErrBookNotFound) {
-			NotFound(c, "未找到该图书", err)
-			return
-		} else if errors.Is(err, services.ErrStockNotEnough) {
-			Conflict(c, "库存不足", err)
-			return
-		} else if errors.Is(err, services.ErrBorrowLimit) {
-			Conflict(c, "借阅次数已达上限", err)
-			return
-		} else {
-			InternalError(c, "借阅失败", err)
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, SuccessResponse{Message: "借书成功"})
-}
-
-// ReturnBook godoc
-// @Summary 归还图书
-// @Description 用户归还已借阅的图书（需要登录）
-// @Tags borrow
-// @Accept json
-// @Produce json
-// @Param request body ReturnBookRequest true "还书信息"
-// @Success 200 {object} SuccessResponse "还书成功"
-// @Failure 400 {object} ErrorResponse "请求参数错误"
-// @Failure 401 {object} ErrorResponse "用户未认证"
-// @Failure 403 {object} ErrorResponse "权限不足"
-// @Failure 404 {object} ErrorResponse "借阅记录或图书不存在"
-// @Failure 409 {object} ErrorResponse "图书已归还"
-// @Failure 500 {object} ErrorResponse "服务器内部错误"
-// @Router /borrow/return [post]
-func (h *BorrowHandler) ReturnBook(c *gin.Context) {
-	var req ReturnBookRequest
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, "请求参数格式错误", err)
-		return
-	}
-
-	// 获取用户信息
-	userObj, exists := c.Get("user")
-	if !exists {
-		Unauthorized(c, "未找到用户信息", nil)
-		return
-	}
-	user := userObj.(*models.User)
-
-	// 还书
-	err := h.borrowService.ReturnBook(req.RecordID, user.ID)
-	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			BadRequest(c, "请求参数错误", err)
-			return
-		} else if errors.Is(err, services.ErrRecordNotFound) {
-			NotFound(c, "未找到该借阅记录", err)
-			return
-		} else if errors.Is(err, services.ErrPermissionDenied) {
-			Forbidden(c, "借阅者与当前用户不匹配", err)
-			return
-		} else if errors.Is(err, services.ErrAlreadyReturned) {
-			Conflict(c, "图书已归还", err)
-			return
-		} else if errors.Is(err, services.ErrBookNotFound) {
-			NotFound(c, "未找到该图书", err)
-			return
-		} else {
-			InternalError(c, "还书失败", err)
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, SuccessResponse{Message: "还书成功"})
-}
-
-// GetUserBorrowRecords godoc
-// @Summary 获取用户借阅记录
-// @Description 获取当前用户的所有借阅记录（需要登录）
-// @Tags borrow
-// @Accept json
-// @Produce json
-// @Success 200 {array} models.BorrowRecord "借阅记录数组"
-// @Failure 401 {object} ErrorResponse "用户未认证"
-// @Failure 500 {object} ErrorResponse "服务器内部错误"
-// @Router /borrow/records [get]
-func (h *BorrowHandler) GetUserBorrowRecords(c *gin.Context) {
-	// 获取用户信息
-	userObj, exists := c.Get("user")
-	if !exists {
-		Unauthorized(c, "未找到用户信息", nil)
-		return
-	}
-	user := userObj.(*models.User)
-
-	// 获取借阅记录
-	records, err := h.borrowService.GetUserBorrowRecords(user.ID)
-	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			BadRequest(c, "请求参数错误", err)
-			return
-		} else if errors.Is(err, services.ErrRecordNotFound) {
-			c.JSON(http.StatusOK, []models.BorrowRecord{})
-			return
-		} else {
-			InternalError(c, "获取借阅记录失败", err)
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, records)
-}
-
-// 请求和响应结构体定义
-type BorrowBookRequest struct {
-	BookID int `json:"book_id" binding:"required" example:"1"`
-}
-
-type ReturnBookRequest struct {
-	RecordID int `json:"record_id" binding:"required" example:"1"`
-}
+package handlers
+
+import (
+	"errors"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BorrowHandler struct {
+	borrowService *services.BorrowService
+}
+
+func NewBorrowHandler(borrowService *services.BorrowService) *BorrowHandler {
+	return &BorrowHandler{borrowService: borrowService}
+}
+
+// BorrowBook godoc
+// @Summary 借阅图书
+// @Description 用户借阅指定图书（需要登录）
+// @Tags borrow
+// @Accept json
+// @Produce json
+// @Param request body BorrowBookRequest true "借书信息"
+// @Success 200 {object} Response[any] "借书成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 404 {object} middleware.Problem "图书不存在"
+// @Failure 409 {object} middleware.Problem "库存不足或借阅次数已达上限"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /borrow [post]
+func (h *BorrowHandler) BorrowBook(c *gin.Context) {
+	var req BorrowBookRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+
+	user := userObj.(*models.User)
+
+	// 借书
+	err := h.borrowService.BorrowBook(c.Request.Context(), user.ID, req.BookID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Message(c, http.StatusOK, "borrow.borrowed")
+}
+
+// ReturnBook godoc
+// @Summary 归还图书
+// @Description 用户归还已借阅的图书（需要登录）
+// @Tags borrow
+// @Accept json
+// @Produce json
+// @Param request body ReturnBookRequest true "还书信息"
+// @Success 200 {object} Response[any] "还书成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 403 {object} middleware.Problem "权限不足"
+// @Failure 404 {object} middleware.Problem "借阅记录或图书不存在"
+// @Failure 409 {object} middleware.Problem "图书已归还"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /borrow/return [post]
+func (h *BorrowHandler) ReturnBook(c *gin.Context) {
+	var req ReturnBookRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	// 还书
+	err := h.borrowService.ReturnBook(c.Request.Context(), req.RecordID, user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Message(c, http.StatusOK, "borrow.returned")
+}
+
+// CheckOut godoc
+// @Summary 服务台借书
+// @Description 馆员为指定读者借出图书，读者由user_id或card_number（借书证号）指定，二者只能填一个。借阅记录中记录经办的馆员（管理员）
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body CheckOutRequest true "借书信息"
+// @Success 201 {object} Response[models.BorrowRecord] "借书成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 403 {object} middleware.Problem "读者账号已停用"
+// @Failure 404 {object} middleware.Problem "读者或图书不存在"
+// @Failure 409 {object} middleware.Problem "库存不足或借阅次数已达上限"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/checkouts [post]
+func (h *BorrowHandler) CheckOut(c *gin.Context) {
+	var req CheckOutRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取经办馆员信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	staff := userObj.(*models.User)
+
+	record, err := h.borrowService.CheckOut(c.Request.Context(), staff.ID, req.UserID, req.CardNumber, req.BookID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusCreated, "borrow.checked_out", record)
+}
+
+// CheckIn godoc
+// @Summary 服务台还书
+// @Description 馆员归还图书（如还书箱中的图书），不要求是借阅者本人。借阅记录由record_id指定，或由card_number（借书证号）和book_id指定，二者只能选一种；同一本书借了多册时归还应还日期最早的一册。借阅记录中记录经办的馆员（管理员）
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body CheckInRequest true "还书信息"
+// @Success 200 {object} Response[models.BorrowRecord] "还书成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 404 {object} middleware.Problem "借阅记录、读者或图书不存在"
+// @Failure 409 {object} middleware.Problem "图书已归还"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/checkins [post]
+func (h *BorrowHandler) CheckIn(c *gin.Context) {
+	var req CheckInRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取经办馆员信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	staff := userObj.(*models.User)
+
+	record, err := h.borrowService.CheckIn(c.Request.Context(), staff.ID, req.RecordID, req.CardNumber, req.BookID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "borrow.checked_in", record)
+}
+
+// GetUserBorrowRecords godoc
+// @Summary 获取用户借阅记录
+// @Description 获取当前用户的所有借阅记录（需要登录）
+// @Tags borrow
+// @Accept json
+// @Produce json
+// @Success 200 {object} Response[[]models.BorrowRecord] "借阅记录数组"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /borrow/records [get]
+func (h *BorrowHandler) GetUserBorrowRecords(c *gin.Context) {
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	// 获取借阅记录
+	records, err := h.borrowService.GetUserBorrowRecords(c.Request.Context(), user.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrRecordNotFound) {
+			Success(c, http.StatusOK, "", []models.BorrowRecord{})
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", records)
+}
+
+// 请求和响应结构体定义
+type BorrowBookRequest struct {
+	BookID int `json:"book_id" binding:"required" example:"1"`
+}
+
+// CheckOutRequest user_id和card_number二选一
+type CheckOutRequest struct {
+	UserID     int    `json:"user_id" example:"1"`
+	CardNumber string `json:"card_number" example:"L2024000123"`
+	BookID     int    `json:"book_id" binding:"required" example:"1"`
+}
+
+type ReturnBookRequest struct {
+	RecordID int `json:"record_id" binding:"required" example:"1"`
+}
+
+// CheckInRequest record_id和card_number+book_id二选一
+type CheckInRequest struct {
+	RecordID   int    `json:"record_id" example:"1"`
+	CardNumber string `json:"card_number" example:"L2024000123"`
+	BookID     int    `json:"book_id" example:"1"`
+}
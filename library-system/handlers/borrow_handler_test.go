@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"library-system/middleware"
+	"library-system/models"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestBorrowHandler(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	app.createUser(t, "lime", models.RoleUser)
+	book := app.createBook(t, "Go", 1)
+
+	lemon := app.newClient(t)
+	lemon.login("lemon")
+	lime := app.newClient(t)
+	lime.login("lime")
+	anonymous := app.newClient(t)
+
+	// 借书后得到lemon的借阅记录
+	if status := lemon.do(http.MethodPost, "/api/v1/borrow", BorrowBookRequest{BookID: book.ID}, nil); status != http.StatusOK {
+		t.Fatalf("borrow status = %d, want %d", status, http.StatusOK)
+	}
+	var records Response[[]models.BorrowRecord]
+	if status := lemon.do(http.MethodGet, "/api/v1/borrow/records", nil, &records); status != http.StatusOK || len(records.Data) != 1 {
+		t.Fatalf("records status = %d, records = %+v", status, records)
+	}
+	recordID := records.Data[0].ID
+
+	// 依次执行，后面的用例依赖前面的状态
+	steps := []struct {
+		name       string
+		client     *testClient
+		path       string
+		req        any
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "未登录不能借书", client: anonymous, path: "/api/v1/borrow", req: BorrowBookRequest{BookID: book.ID}, wantStatus: http.StatusUnauthorized, wantCode: "UNAUTHENTICATED"},
+		{name: "库存不足", client: lime, path: "/api/v1/borrow", req: BorrowBookRequest{BookID: book.ID}, wantStatus: http.StatusConflict, wantCode: "OUT_OF_STOCK"},
+		{name: "图书不存在", client: lime, path: "/api/v1/borrow", req: BorrowBookRequest{BookID: 404}, wantStatus: http.StatusNotFound, wantCode: "BOOK_NOT_FOUND"},
+		{name: "缺少图书ID", client: lime, path: "/api/v1/borrow", req: map[string]int{}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "未登录不能还书", client: anonymous, path: "/api/v1/borrow/return", req: ReturnBookRequest{RecordID: recordID}, wantStatus: http.StatusUnauthorized, wantCode: "UNAUTHENTICATED"},
+		{name: "不能归还他人的借阅", client: lime, path: "/api/v1/borrow/return", req: ReturnBookRequest{RecordID: recordID}, wantStatus: http.StatusForbidden, wantCode: "PERMISSION_DENIED"},
+		{name: "记录不存在", client: lemon, path: "/api/v1/borrow/return", req: ReturnBookRequest{RecordID: 404}, wantStatus: http.StatusNotFound, wantCode: "RECORD_NOT_FOUND"},
+		{name: "归还本人的借阅", client: lemon, path: "/api/v1/borrow/return", req: ReturnBookRequest{RecordID: recordID}, wantStatus: http.StatusOK},
+		{name: "重复归还", client: lemon, path: "/api/v1/borrow/return", req: ReturnBookRequest{RecordID: recordID}, wantStatus: http.StatusConflict, wantCode: "ALREADY_RETURNED"},
+		{name: "归还后他人可以借阅", client: lime, path: "/api/v1/borrow", req: BorrowBookRequest{BookID: book.ID}, wantStatus: http.StatusOK},
+	}
+	for _, step := range steps {
+		// 成功响应中没有code字段
+		var problem middleware.Problem
+		if status := step.client.do(http.MethodPost, step.path, step.req, &problem); status != step.wantStatus || problem.Code != step.wantCode {
+			t.Fatalf("%s: status = %d, code = %q, want %d, %q", step.name, status, problem.Code, step.wantStatus, step.wantCode)
+		}
+	}
+
+	// 每个用户只能看到自己的借阅记录
+	records = Response[[]models.BorrowRecord]{}
+	if status := lime.do(http.MethodGet, "/api/v1/borrow/records", nil, &records); status != http.StatusOK {
+		t.Fatalf("records status = %d", status)
+	}
+	limeUser, err := app.repos.Users.GetByUsername(context.Background(), "lime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records.Data) != 1 || records.Data[0].UserID != limeUser.ID {
+		t.Errorf("lime records = %+v", records)
+	}
+	if status := anonymous.do(http.MethodGet, "/api/v1/borrow/records", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("anonymous records status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestBorrowHandler_Circulation(t *testing.T) {
+	app := newTestApp(t, false)
+	patron := app.createUser(t, "lemon", models.RoleUser)
+	staff := app.createUser(t, "admin", models.RoleAdmin)
+	book := app.createBook(t, "Go", 1)
+
+	lemon := app.newClient(t)
+	lemon.login("lemon")
+	admin := app.newClient(t)
+	admin.login("admin")
+
+	// 登记借书证号后凭证号借书
+	var user Response[models.User]
+	path := "/api/v1/admin/users/" + strconv.Itoa(patron.ID) + "/card-number"
+	if status := admin.do(http.MethodPut, path, SetCardNumberRequest{CardNumber: "L0001"}, &user); status != http.StatusOK || user.Data.CardNumber == nil || *user.Data.CardNumber != "L0001" {
+		t.Fatalf("set card number status = %d, user = %+v", status, user.Data)
+	}
+	var checkedOut Response[models.BorrowRecord]
+	if status := admin.do(http.MethodPost, "/api/v1/admin/checkouts", CheckOutRequest{CardNumber: "L0001", BookID: book.ID}, &checkedOut); status != http.StatusCreated {
+		t.Fatalf("checkout status = %d", status)
+	}
+	if record := checkedOut.Data; record.UserID != patron.ID || record.CheckedOutBy == nil || *record.CheckedOutBy != staff.ID {
+		t.Fatalf("checked out record = %+v", record)
+	}
+
+	// 馆员可以归还读者的借阅
+	var checkedIn Response[models.BorrowRecord]
+	if status := admin.do(http.MethodPost, "/api/v1/admin/checkins", CheckInRequest{RecordID: checkedOut.Data.ID}, &checkedIn); status != http.StatusOK {
+		t.Fatalf("checkin status = %d", status)
+	}
+	if record := checkedIn.Data; record.ReturnedAt == nil || record.CheckedInBy == nil || *record.CheckedInBy != staff.ID {
+		t.Fatalf("checked in record = %+v", record)
+	}
+
+	// 凭借书证号和图书还书
+	var again Response[models.BorrowRecord]
+	if status := admin.do(http.MethodPost, "/api/v1/admin/checkouts", CheckOutRequest{CardNumber: "L0001", BookID: book.ID}, &again); status != http.StatusCreated {
+		t.Fatalf("second checkout status = %d", status)
+	}
+	if status := admin.do(http.MethodPost, "/api/v1/admin/checkins", CheckInRequest{CardNumber: "L0001", BookID: book.ID}, &checkedIn); status != http.StatusOK {
+		t.Fatalf("checkin by card number status = %d", status)
+	}
+	if record := checkedIn.Data; record.ID != again.Data.ID || record.ReturnedAt == nil {
+		t.Fatalf("checked in record = %+v, want record %d", record, again.Data.ID)
+	}
+
+	tests := []struct {
+		name       string
+		client     *testClient
+		method     string
+		path       string
+		req        any
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "读者不能使用服务台借书", client: lemon, method: http.MethodPost, path: "/api/v1/admin/checkouts", req: CheckOutRequest{UserID: patron.ID, BookID: book.ID}, wantStatus: http.StatusForbidden, wantCode: "ADMIN_REQUIRED"},
+		{name: "读者不能使用服务台还书", client: lemon, method: http.MethodPost, path: "/api/v1/admin/checkins", req: CheckInRequest{RecordID: checkedOut.Data.ID}, wantStatus: http.StatusForbidden, wantCode: "ADMIN_REQUIRED"},
+		{name: "缺少读者", client: admin, method: http.MethodPost, path: "/api/v1/admin/checkouts", req: CheckOutRequest{BookID: book.ID}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "证号不存在", client: admin, method: http.MethodPost, path: "/api/v1/admin/checkouts", req: CheckOutRequest{CardNumber: "L404", BookID: book.ID}, wantStatus: http.StatusNotFound, wantCode: "USER_NOT_FOUND"},
+		{name: "重复还书", client: admin, method: http.MethodPost, path: "/api/v1/admin/checkins", req: CheckInRequest{RecordID: checkedOut.Data.ID}, wantStatus: http.StatusConflict, wantCode: "ALREADY_RETURNED"},
+		{name: "缺少借阅记录", client: admin, method: http.MethodPost, path: "/api/v1/admin/checkins", req: CheckInRequest{}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "证号没有未还的借阅", client: admin, method: http.MethodPost, path: "/api/v1/admin/checkins", req: CheckInRequest{CardNumber: "L0001", BookID: book.ID}, wantStatus: http.StatusNotFound, wantCode: "RECORD_NOT_FOUND"},
+		{name: "证号已被使用", client: admin, method: http.MethodPut, path: "/api/v1/admin/users/" + strconv.Itoa(staff.ID) + "/card-number", req: SetCardNumberRequest{CardNumber: "L0001"}, wantStatus: http.StatusConflict, wantCode: "CARD_NUMBER_EXISTS"},
+		{name: "用户不存在", client: admin, method: http.MethodPut, path: "/api/v1/admin/users/404/card-number", req: SetCardNumberRequest{CardNumber: "L0002"}, wantStatus: http.StatusNotFound, wantCode: "USER_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem middleware.Problem
+			if status := tt.client.do(tt.method, tt.path, tt.req, &problem); status != tt.wantStatus || problem.Code != tt.wantCode {
+				t.Errorf("status = %d, code = %q, want %d, %q", status, problem.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -1,47 +1,48 @@
-package handlers
-
-import "github.com/gin-gonic/gin"
-
-type ErrorResponse struct {
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
-}
-
-// 错误响应
-func Error(c *gin.Context, httpStatus int, message string, err error) {
-	response := ErrorResponse{
-		Message: message,
-	}
-
-	// 生产环境隐藏详细错误
-	if gin.Mode() != gin.ReleaseMode && err != nil {
-		response.Error = err.Error()
-	}
-
-	c.JSON(httpStatus, response)
-}
-
-// 常用的错误响应
-func BadRequest(c *gin.Context, message string, err error) {
-	Error(c, 400, message, err)
-}
-
-func Unauthorized(c *gin.Context, message string, err error) {
-	Error(c, 401, message, err)
-}
-
-func Forbidden(c *gin.Context, message string, err error) {
-	Error(c, 403, message, err)
-}
-
-func NotFound(c *gin.Context, message string, err error) {
-	Error(c, 404, message, err)
-}
-
-func Conflict(c *gin.Context, message string, err error) {
-	Error(c, 409, message, err)
-}
-
-func InternalError(c *gin.Context, message string, err error) {
-	Error(c, 500, message, err)
-}
+package handlers
+
+import (
+	"fmt"
+	"library-system/i18n"
+	"library-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response 统一的成功响应，data为业务数据，message为可直接展示的提示
+// 处理函数通过 c.Error 报告错误，由 middleware.ErrorHandler 统一返回RFC 7807问题详情，见 middleware.Problem
+type Response[T any] struct {
+	Message string `json:"message,omitempty" example:"操作成功"`
+	Data    T      `json:"data"`
+}
+
+// Success 成功响应，key为消息目录中提示信息的key，为空时不返回message
+func Success[T any](c *gin.Context, httpStatus int, key string, data T) {
+	c.JSON(httpStatus, Response[T]{Message: i18n.T(i18n.FromContext(c.Request.Context()), key), Data: data})
+}
+
+// Message 没有业务数据的成功响应，data为null
+func Message(c *gin.Context, httpStatus int, key string) {
+	Success[any](c, httpStatus, key, nil)
+}
+
+// withCode 对外使用code的错误码报告err，日志中保留原始错误
+func withCode(code, err error) error {
+	if err == nil {
+		return code
+	}
+	return fmt.Errorf("%w: %w", code, err)
+}
+
+// bindJSON 解析请求体，失败时报告为binding错误，由 middleware.ErrorHandler 返回400
+func bindJSON(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		c.Error(err).SetType(gin.ErrorTypeBind)
+		return false
+	}
+	return true
+}
+
+// invalidParam 路径或查询参数field未通过rule校验
+func invalidParam(field, rule string) error {
+	return &services.ValidationError{Fields: []services.FieldError{services.NewFieldError(field, rule, field)}}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"library-system/i18n"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+	"testing"
+)
+
+func TestErrorResponse(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	client := app.newClient(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		req        any
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "未登录", method: http.MethodGet, path: "/api/v1/me", wantStatus: http.StatusUnauthorized, wantCode: "UNAUTHENTICATED"},
+		{name: "接口不存在", method: http.MethodGet, path: "/api/v1/unknown", wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND"},
+		// 用户不存在和密码错误使用相同的错误码
+		{name: "用户不存在", method: http.MethodPost, path: "/api/v1/auth/login", req: LoginRequest{Username: "lime", Password: testPassword}, wantStatus: http.StatusUnauthorized, wantCode: "INVALID_CREDENTIALS"},
+		{name: "密码错误", method: http.MethodPost, path: "/api/v1/auth/login", req: LoginRequest{Username: "lemon", Password: "wrong-password1"}, wantStatus: http.StatusUnauthorized, wantCode: "INVALID_CREDENTIALS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem middleware.Problem
+			if status := client.do(tt.method, tt.path, tt.req, &problem); status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if got := client.lastResponse.Header.Get("Content-Type"); got != middleware.ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, middleware.ProblemContentType)
+			}
+			if problem.Code != tt.wantCode || problem.Status != tt.wantStatus || problem.Instance != tt.path {
+				t.Errorf("problem = %+v, want code %q", problem, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestLocale(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	client := app.newClient(t)
+	client.acceptLanguage = "en-GB,zh;q=0.5"
+
+	// 检查错误提示、字段校验提示和Content-Language都使用期望的语言
+	check := func(locale string) {
+		t.Helper()
+		var problem middleware.Problem
+		if status := client.do(http.MethodPost, "/api/v1/me/password", ChangePasswordRequest{CurrentPassword: testPassword, NewPassword: "short"}, &problem); status != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
+		}
+		if got := client.lastResponse.Header.Get("Content-Language"); got != locale {
+			t.Errorf("Content-Language = %q, want %q", got, locale)
+		}
+		if want := i18n.T(locale, "error.validation_failed"); problem.Detail != want {
+			t.Errorf("detail = %q, want %q", problem.Detail, want)
+		}
+		if len(problem.Errors) == 0 || problem.Errors[0].Message != i18n.T(locale, "validation.min_length", services.DefaultPasswordPolicy().MinLength) {
+			t.Errorf("errors = %+v, want %s messages", problem.Errors, locale)
+		}
+	}
+
+	// 未设置偏好时按Accept-Language协商
+	client.login("lemon")
+	check(i18n.EnUS)
+
+	// 设置偏好后优先于Accept-Language
+	if status := client.do(http.MethodPut, "/api/v1/me", UpdateProfileRequest{Locale: "zh-CN"}, nil); status != http.StatusOK {
+		t.Fatalf("PUT /me status = %d", status)
+	}
+	check(i18n.ZhCN)
+
+	// 重新登录后偏好仍然生效
+	if status := client.do(http.MethodPost, "/api/v1/auth/logout", nil, nil); status != http.StatusNoContent {
+		t.Fatalf("logout status = %d", status)
+	}
+	client.refreshCSRF()
+	client.login("lemon")
+	check(i18n.ZhCN)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"library-system/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	scheduler *services.Scheduler
+}
+
+func NewJobHandler(scheduler *services.Scheduler) *JobHandler {
+	return &JobHandler{scheduler: scheduler}
+}
+
+// GetJobs godoc
+// @Summary 获取定时任务列表
+// @Description 管理员查看全部定时任务的调度规则、下次运行时间和最近一次运行
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} Response[[]services.JobInfo] "任务数组"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/jobs [get]
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	jobs, err := h.scheduler.Jobs(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", jobs)
+}
+
+// GetJobRuns godoc
+// @Summary 获取任务运行历史
+// @Description 管理员查看定时任务的运行记录，最新的在前
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "任务名" example(session_purge)
+// @Param limit query int false "返回条数，默认20，最多100"
+// @Success 200 {object} Response[[]models.JobRun] "运行记录数组"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 404 {object} middleware.Problem "任务不存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/jobs/{name}/runs [get]
+func (h *JobHandler) GetJobRuns(c *gin.Context) {
+	// 从查询参数获取条数
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.Error(invalidParam("limit", "invalid"))
+			return
+		}
+	}
+
+	runs, err := h.scheduler.Runs(c.Request.Context(), c.Param("name"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", runs)
+}
+
+// TriggerJob godoc
+// @Summary 手动运行任务
+// @Description 管理员立即运行定时任务，任务在后台运行，返回已创建的运行记录。任务正在本实例或其他实例运行时返回409
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "任务名" example(session_purge)
+// @Success 202 {object} Response[models.JobRun] "任务已开始运行"
+// @Failure 404 {object} middleware.Problem "任务不存在"
+// @Failure 409 {object} middleware.Problem "任务正在运行"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/jobs/{name}/run [post]
+func (h *JobHandler) TriggerJob(c *gin.Context) {
+	run, err := h.scheduler.Trigger(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusAccepted, "admin.job_triggered", run)
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// GetNotifications godoc
+// @Summary 获取站内信
+// @Description 获取当前登录用户的站内信，最新的在前，同时返回未读数量（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param unread query bool false "只返回未读通知"
+// @Param limit query int false "返回条数，默认50，最多100"
+// @Success 200 {object} Response[services.NotificationInbox] "站内信"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/notifications [get]
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	// 从查询参数获取筛选条件
+	unread := false
+	if raw := c.Query("unread"); raw != "" {
+		var err error
+		unread, err = strconv.ParseBool(raw)
+		if err != nil {
+			c.Error(invalidParam("unread", "invalid"))
+			return
+		}
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.Error(invalidParam("limit", "invalid"))
+			return
+		}
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	inbox, err := h.notificationService.GetInbox(c.Request.Context(), user.ID, unread, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", inbox)
+}
+
+// MarkRead godoc
+// @Summary 标记通知已读
+// @Description 将当前登录用户的一条通知标记为已读（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param id path int true "通知ID"
+// @Success 204 "标记成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 404 {object} middleware.Problem "通知不存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/notifications/{id}/read [post]
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	h.setRead(c, true)
+}
+
+// MarkUnread godoc
+// @Summary 标记通知未读
+// @Description 将当前登录用户的一条通知标记为未读（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param id path int true "通知ID"
+// @Success 204 "标记成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 404 {object} middleware.Problem "通知不存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/notifications/{id}/unread [post]
+func (h *NotificationHandler) MarkUnread(c *gin.Context) {
+	h.setRead(c, false)
+}
+
+// setRead
+func (h *NotificationHandler) setRead(c *gin.Context, read bool) {
+	// 从路径参数获取ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.Error(invalidParam("id", "invalid"))
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	if err := h.notificationService.SetRead(c.Request.Context(), user.ID, id, read); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MarkAllRead godoc
+// @Summary 全部标记已读
+// @Description 将当前登录用户的全部通知标记为已读（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Success 204 "标记成功"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/notifications/read-all [post]
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	if err := h.notificationService.MarkAllRead(c.Request.Context(), user.ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetPreference godoc
+// @Summary 获取通知设置
+// @Description 获取当前登录用户接收通知的渠道，未设置时全部开启（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Success 200 {object} Response[models.NotificationPreference] "通知设置"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/notification-preferences [get]
+func (h *NotificationHandler) GetPreference(c *gin.Context) {
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	preference, err := h.notificationService.GetPreference(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", preference)
+}
+
+// UpdatePreference godoc
+// @Summary 更新通知设置
+// @Description 设置当前登录用户是否接收邮件和站内信通知（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body UpdateNotificationPreferenceRequest true "通知设置"
+// @Success 200 {object} Response[models.NotificationPreference] "更新后的通知设置"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/notification-preferences [put]
+func (h *NotificationHandler) UpdatePreference(c *gin.Context) {
+	var req UpdateNotificationPreferenceRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	preference, err := h.notificationService.UpdatePreference(c.Request.Context(), user.ID, *req.Email, *req.InApp)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "notification.preferences_updated", preference)
+}
+
+// 请求和响应结构体定义
+// 使用指针区分未传和false
+type UpdateNotificationPreferenceRequest struct {
+	Email *bool `json:"email" binding:"required" example:"true"`
+	InApp *bool `json:"in_app" binding:"required" example:"false"`
+}
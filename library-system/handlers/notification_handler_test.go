@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+	"testing"
+)
+
+func TestNotificationHandler(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	app.createUser(t, "lime", models.RoleUser)
+	book := app.createBook(t, "Go", 1)
+
+	lemon := app.newClient(t)
+	lemon.login("lemon")
+	lime := app.newClient(t)
+	lime.login("lime")
+
+	// 借书后收到站内信
+	if status := lemon.do(http.MethodPost, "/api/v1/borrow", BorrowBookRequest{BookID: book.ID}, nil); status != http.StatusOK {
+		t.Fatalf("borrow status = %d, want %d", status, http.StatusOK)
+	}
+	app.notifications.Wait()
+
+	var inbox Response[services.NotificationInbox]
+	if status := lemon.do(http.MethodGet, "/api/v1/me/notifications?unread=true", nil, &inbox); status != http.StatusOK {
+		t.Fatalf("notifications status = %d", status)
+	}
+	if inbox.Data.UnreadCount != 1 || len(inbox.Data.Notifications) != 1 || inbox.Data.Notifications[0].Type != models.NotificationLoanCreated {
+		t.Fatalf("inbox = %+v", inbox.Data)
+	}
+	readPath := fmt.Sprintf("/api/v1/me/notifications/%d/read", inbox.Data.Notifications[0].ID)
+
+	steps := []struct {
+		name       string
+		client     *testClient
+		method     string
+		path       string
+		req        any
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "不能标记他人的通知", client: lime, method: http.MethodPost, path: readPath, wantStatus: http.StatusNotFound, wantCode: "NOTIFICATION_NOT_FOUND"},
+		{name: "无效的通知ID", client: lemon, method: http.MethodPost, path: "/api/v1/me/notifications/abc/read", wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "无效的条数", client: lemon, method: http.MethodGet, path: "/api/v1/me/notifications?limit=0", wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "标记已读", client: lemon, method: http.MethodPost, path: readPath, wantStatus: http.StatusNoContent},
+		{name: "缺少渠道设置", client: lemon, method: http.MethodPut, path: "/api/v1/me/notification-preferences", req: map[string]bool{"email": false}, wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+	}
+	for _, step := range steps {
+		var problem middleware.Problem
+		var out any = &problem
+		if step.wantStatus == http.StatusNoContent {
+			out = nil
+		}
+		if status := step.client.do(step.method, step.path, step.req, out); status != step.wantStatus || problem.Code != step.wantCode {
+			t.Fatalf("%s: status = %d, code = %q, want %d, %q", step.name, status, problem.Code, step.wantStatus, step.wantCode)
+		}
+	}
+
+	inbox = Response[services.NotificationInbox]{}
+	if status := lemon.do(http.MethodGet, "/api/v1/me/notifications", nil, &inbox); status != http.StatusOK || inbox.Data.UnreadCount != 0 {
+		t.Errorf("after read: status = %d, inbox = %+v", status, inbox.Data)
+	}
+
+	// 关闭站内信后还书不再收到通知
+	email, inApp := true, false
+	var preference Response[models.NotificationPreference]
+	if status := lemon.do(http.MethodPut, "/api/v1/me/notification-preferences", UpdateNotificationPreferenceRequest{Email: &email, InApp: &inApp}, &preference); status != http.StatusOK || preference.Data.InApp {
+		t.Fatalf("update preference status = %d, preference = %+v", status, preference.Data)
+	}
+	var records Response[[]models.BorrowRecord]
+	if status := lemon.do(http.MethodGet, "/api/v1/borrow/records", nil, &records); status != http.StatusOK || len(records.Data) != 1 {
+		t.Fatalf("records status = %d, records = %+v", status, records)
+	}
+	if status := lemon.do(http.MethodPost, "/api/v1/borrow/return", ReturnBookRequest{RecordID: records.Data[0].ID}, nil); status != http.StatusOK {
+		t.Fatalf("return status = %d", status)
+	}
+	app.notifications.Wait()
+
+	inbox = Response[services.NotificationInbox]{}
+	if status := lemon.do(http.MethodGet, "/api/v1/me/notifications", nil, &inbox); status != http.StatusOK || len(inbox.Data.Notifications) != 1 {
+		t.Errorf("after return: status = %d, inbox = %+v", status, inbox.Data)
+	}
+}
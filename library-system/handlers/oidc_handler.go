@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"library-system/services"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// 从跳转到身份提供方到回调的最长时间
+const oidcLoginTTL = 10 * time.Minute
+
+// 回调的state与发起登录时不一致或已过期
+var errOIDCInvalidState = errors.New("invalid or expired oidc state")
+
+type OIDCHandler struct {
+	oidcService  *services.OIDCService
+	sessionStore sessions.Store
+	// 登录完成后跳转的前端地址，为空时直接返回JSON
+	postLoginURL string
+}
+
+func NewOIDCHandler(oidcService *services.OIDCService, sessionStore sessions.Store, postLoginURL string) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService, sessionStore: sessionStore, postLoginURL: postLoginURL}
+}
+
+// Login godoc
+// @Summary 单点登录
+// @Description 跳转到OpenID Connect身份提供方进行登录（授权码模式 + PKCE）
+// @Tags auth
+// @Success 302 "跳转到身份提供方"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /auth/oidc/login [get]
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authReq, err := h.oidcService.AuthCodeURL(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 保存回调时需要校验的参数
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	session.Values["oidcState"] = authReq.State
+	session.Values["oidcNonce"] = authReq.Nonce
+	session.Values["oidcVerifier"] = authReq.Verifier
+	session.Values["oidcAt"] = time.Now().Unix()
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, authReq.URL)
+}
+
+// Callback godoc
+// @Summary 单点登录回调
+// @Description 身份提供方回调地址。校验state后用授权码换取ID Token，首次登录时自动创建本地用户，然后建立与密码登录相同的Session
+// @Tags auth
+// @Produce json
+// @Param code query string true "授权码"
+// @Param state query string true "state"
+// @Success 200 {object} Response[LoginResult] "登录成功"
+// @Success 302 "配置了登录后地址时跳转"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "单点登录失败或登录请求已过期"
+// @Failure 403 {object} middleware.Problem "账号已停用"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /auth/oidc/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 取出并清除发起登录时保存的参数，每次登录只能回调一次
+	state, _ := session.Values["oidcState"].(string)
+	nonce, _ := session.Values["oidcNonce"].(string)
+	verifier, _ := session.Values["oidcVerifier"].(string)
+	startedAt, _ := session.Values["oidcAt"].(int64)
+	delete(session.Values, "oidcState")
+	delete(session.Values, "oidcNonce")
+	delete(session.Values, "oidcVerifier")
+	delete(session.Values, "oidcAt")
+	// 立即保存，校验失败提前返回时state同样已被消费
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if idpErr := c.Query("error"); idpErr != "" {
+		c.Error(withCode(services.ErrOIDCLoginFailed, errors.New(idpErr+": "+c.Query("error_description"))))
+		return
+	}
+	if state == "" || time.Since(time.Unix(startedAt, 0)) > oidcLoginTTL ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		c.Error(withCode(services.ErrOIDCLoginFailed, errOIDCInvalidState))
+		return
+	}
+
+	user, err := h.oidcService.Exchange(c.Request.Context(), c.Query("code"), verifier, nonce)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 已启用两步验证的用户同样需要完成第二步
+	if user.TOTPEnabled {
+		session.Values["authenticated"] = false
+		session.Values["pendingUserID"] = user.ID
+		session.Values["pendingAt"] = time.Now().Unix()
+		if err := session.Save(c.Request, c.Writer); err != nil {
+			c.Error(err)
+			return
+		}
+
+		if h.postLoginURL != "" {
+			c.Redirect(http.StatusFound, h.postLoginURL+"?"+url.Values{"two_factor_required": {"true"}}.Encode())
+			return
+		}
+		Success(c, http.StatusOK, "auth.two_factor_required", LoginResult{TwoFactorRequired: true})
+		return
+	}
+
+	// 保存Session
+	csrfToken, err := establishSession(c, session, user, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 跳转后前端需要重新获取CSRF令牌
+	if h.postLoginURL != "" {
+		c.Redirect(http.StatusFound, h.postLoginURL)
+		return
+	}
+	Success(c, http.StatusOK, "auth.login_success", LoginResult{User: user, CSRFToken: csrfToken})
+}
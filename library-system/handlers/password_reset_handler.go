@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"library-system/services"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordResetHandler struct {
+	passwordResetService *services.PasswordResetService
+}
+
+func NewPasswordResetHandler(passwordResetService *services.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{passwordResetService: passwordResetService}
+}
+
+// RequestReset godoc
+// @Summary 申请重置密码
+// @Description 向注册邮箱发送一次性重置链接。无论邮箱是否存在都返回相同的响应
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body PasswordResetRequest true "注册邮箱"
+// @Success 202 {object} Response[any] "请求已受理"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Router /auth/password-reset [post]
+func (h *PasswordResetHandler) RequestReset(c *gin.Context) {
+	var req PasswordResetRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 内部错误只记录日志，保证响应与邮箱是否存在无关
+	if err := h.passwordResetService.RequestReset(c.Request.Context(), req.Email); err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			c.Error(err)
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "申请重置密码失败", slog.Any("error", err))
+	}
+
+	Message(c, http.StatusAccepted, "password_reset.requested")
+}
+
+// ConfirmReset godoc
+// @Summary 确认重置密码
+// @Description 使用邮件中的一次性令牌设置新密码，成功后该用户的所有登录状态失效
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body PasswordResetConfirmRequest true "令牌和新密码"
+// @Success 200 {object} Response[any] "重置成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误或令牌无效"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /auth/password-reset/confirm [post]
+func (h *PasswordResetHandler) ConfirmReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := h.passwordResetService.ConfirmReset(c.Request.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Message(c, http.StatusOK, "password_reset.done")
+}
+
+// 请求和响应结构体定义
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required" example:"lemon@example.com"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required" example:"q1w2e3r4t5y6"`
+	NewPassword string `json:"new_password" binding:"required" example:"lemon2024tree"`
+}
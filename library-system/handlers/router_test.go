@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/repositories"
+	"library-system/repositories/memory"
+	"library-system/services"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试用户的密码，满足默认密码策略
+const testPassword = "lemon2024tree"
+
+// testApp 使用内存仓库的完整路由，路由结构与main.go一致
+type testApp struct {
+	repos         repositories.Repositories
+	notifications *services.NotificationService
+	server        *httptest.Server
+}
+
+// newTestApp requireAdminTwoFactor对应配置项two_factor.require_for_admins
+func newTestApp(t *testing.T, requireAdminTwoFactor bool) *testApp {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore()
+	repos := store.Repositories()
+	sessionStore := middleware.NewDBStore(repos.Sessions, []byte("test-session-secret-0123456789abcdef"))
+
+	loginGuard := services.NewLoginGuard(repos.LoginThrottles, services.DefaultLoginGuardPolicy())
+	authService := services.NewAuthService(repos.Users, []services.Authenticator{services.NewLocalAuthenticator(repos.Users)}, loginGuard, services.DefaultPasswordPolicy())
+	userService := services.NewUserService(store, repos.Users, repos.BorrowRecords, loginGuard, services.DefaultPasswordPolicy(), services.DefaultLoanPolicy())
+	twoFactorService := services.NewTwoFactorService(store, repos.Users, loginGuard, "LibrarySystem")
+	notificationService := services.NewNotificationService(store, repos.Users, repos.Books, repos.BorrowRecords, repos.Notifications, repos.NotificationPreferences, map[string]services.Notifier{
+		models.ChannelInApp: services.NewInboxNotifier(repos.Notifications),
+	}, services.DefaultNotificationPolicy())
+	eventBus := services.NewEventBus(100)
+	borrowService := services.NewBorrowService(store, repos.BorrowRecords, services.DefaultLoanPolicy(), notificationService, eventBus)
+	adminService := services.NewAdminService(store, repos.BorrowRecords, repos.LoginThrottles, eventBus)
+
+	authHandler := NewAuthHandler(authService, sessionStore)
+	userHandler := NewUserHandler(userService, sessionStore)
+	twoFactorHandler := NewTwoFactorHandler(twoFactorService, sessionStore)
+	borrowHandler := NewBorrowHandler(borrowService)
+	notificationHandler := NewNotificationHandler(notificationService)
+	adminHandler := NewAdminHandler(adminService)
+	streamHandler := NewStreamHandler(eventBus, services.NewBookService(repos.Books), 50*time.Millisecond)
+
+	router := gin.New()
+	router.Use(middleware.LocaleMiddleware())
+	router.Use(middleware.ErrorHandler())
+	router.NoRoute(middleware.NoRoute)
+	v1 := router.Group("/api/v1")
+	v1.Use(middleware.CSRFMiddleware(sessionStore))
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.GET("/csrf", authHandler.CSRFToken)
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/2fa", twoFactorHandler.VerifyLogin)
+			auth.POST("/logout", authHandler.Logout)
+		}
+
+		protected := v1.Group("")
+		protected.Use(middleware.AuthMiddleware(sessionStore))
+		{
+			protected.GET("/me", userHandler.GetProfile)
+			protected.PUT("/me", userHandler.UpdateProfile)
+			protected.POST("/me/password", userHandler.ChangePassword)
+			protected.GET("/me/notifications", notificationHandler.GetNotifications)
+			protected.POST("/me/notifications/read-all", notificationHandler.MarkAllRead)
+			protected.POST("/me/notifications/:id/read", notificationHandler.MarkRead)
+			protected.POST("/me/notifications/:id/unread", notificationHandler.MarkUnread)
+			protected.GET("/me/notification-preferences", notificationHandler.GetPreference)
+			protected.PUT("/me/notification-preferences", notificationHandler.UpdatePreference)
+
+			borrow := protected.Group("/borrow")
+			{
+				borrow.POST("", borrowHandler.BorrowBook)
+				borrow.POST("/return", borrowHandler.ReturnBook)
+				borrow.GET("/records", borrowHandler.GetUserBorrowRecords)
+			}
+
+			events := protected.Group("/events")
+			{
+				events.GET("/availability", streamHandler.Availability)
+				events.GET("/circulation", middleware.AdminMiddleware(requireAdminTwoFactor), streamHandler.Circulation)
+			}
+
+			admin := protected.Group("/admin")
+			admin.Use(middleware.AdminMiddleware(requireAdminTwoFactor))
+			{
+				admin.POST("/books", adminHandler.AddBook)
+				admin.DELETE("/books", adminHandler.DeleteBook)
+				admin.GET("/borrow-records", adminHandler.GetAllBorrowRecords)
+				admin.DELETE("/lockouts", adminHandler.ClearLockout)
+				admin.POST("/checkouts", borrowHandler.CheckOut)
+				admin.POST("/checkins", borrowHandler.CheckIn)
+				admin.PUT("/users/:id/card-number", userHandler.SetCardNumber)
+			}
+		}
+	}
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	// 等待借还书后异步发送的通知，避免关闭后仍在写入
+	t.Cleanup(notificationService.Wait)
+	return &testApp{repos: repos, notifications: notificationService, server: server}
+}
+
+// createUser 创建本地用户，密码为testPassword
+func (a *testApp) createUser(t *testing.T, name, role string) *models.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	user := &models.User{Name: name, Password: string(hash), Role: role, AuthProvider: models.AuthProviderLocal, Active: true}
+	if err := a.repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %q: %v", name, err)
+	}
+	return user
+}
+
+// enableTwoFactor 直接在仓库中为用户启用两步验证，登录时使用返回的恢复码
+func (a *testApp) enableTwoFactor(t *testing.T, user *models.User) string {
+	t.Helper()
+	ctx := context.Background()
+	const code = "abcde-23456"
+
+	user.TOTPEnabled = true
+	user.TOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+	if err := a.repos.Users.Update(ctx, user); err != nil {
+		t.Fatalf("enable two factor: %v", err)
+	}
+	sum := sha256.Sum256([]byte(code))
+	if err := a.repos.RecoveryCodes.CreateBatch(ctx, []*models.RecoveryCode{{UserID: user.ID, CodeHash: hex.EncodeToString(sum[:])}}); err != nil {
+		t.Fatalf("create recovery code: %v", err)
+	}
+	return code
+}
+
+// createBook
+func (a *testApp) createBook(t *testing.T, title string, stock int) *models.Book {
+	t.Helper()
+	book := &models.Book{Title: title, Author: "Lemon", Stock: stock}
+	if err := a.repos.Books.Create(context.Background(), book); err != nil {
+		t.Fatalf("create book %q: %v", title, err)
+	}
+	return book
+}
+
+// testClient 保存Cookie和CSRF令牌的客户端
+type testClient struct {
+	t    *testing.T
+	app  *testApp
+	http *http.Client
+	csrf string
+	// 为true时不携带CSRF令牌
+	noCSRF bool
+	// 不为空时作为Accept-Language请求头
+	acceptLanguage string
+	// 最近一次请求的响应，用于检查响应头
+	lastResponse *http.Response
+}
+
+// newClient 创建客户端并获取CSRF令牌
+func (a *testApp) newClient(t *testing.T) *testClient {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New() error = %v", err)
+	}
+	c := &testClient{t: t, app: a, http: &http.Client{Jar: jar}}
+	c.refreshCSRF()
+	return c
+}
+
+// refreshCSRF 注销后Session被删除，需要重新获取令牌
+func (c *testClient) refreshCSRF() {
+	c.t.Helper()
+	var resp Response[CSRFTokenResult]
+	if status := c.do(http.MethodGet, "/api/v1/auth/csrf", nil, &resp); status != http.StatusOK {
+		c.t.Fatalf("GET /auth/csrf status = %d", status)
+	}
+	c.csrf = resp.Data.CSRFToken
+}
+
+// login 登录并断言成功，之后使用登录后的新CSRF令牌
+func (c *testClient) login(username string) {
+	c.t.Helper()
+	var resp Response[LoginResult]
+	if status := c.do(http.MethodPost, "/api/v1/auth/login", LoginRequest{Username: username, Password: testPassword}, &resp); status != http.StatusOK {
+		c.t.Fatalf("login %q status = %d", username, status)
+	}
+	if resp.Data.CSRFToken != "" {
+		c.csrf = resp.Data.CSRFToken
+	}
+}
+
+// verifyTwoFactor 完成两步验证并断言成功，之后使用登录后的新CSRF令牌
+func (c *testClient) verifyTwoFactor(code string) {
+	c.t.Helper()
+	var resp Response[LoginResult]
+	if status := c.do(http.MethodPost, "/api/v1/auth/login/2fa", TwoFactorCodeRequest{Code: code}, &resp); status != http.StatusOK {
+		c.t.Fatalf("2fa status = %d", status)
+	}
+	c.csrf = resp.Data.CSRFToken
+}
+
+// sessionCookie 返回Cookie中的Session，不存在时返回空字符串
+func (c *testClient) sessionCookie() string {
+	u, _ := url.Parse(c.app.server.URL)
+	for _, cookie := range c.http.Jar.Cookies(u) {
+		if cookie.Name == "library-session" {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// setSessionCookie 替换Cookie中的Session
+func (c *testClient) setSessionCookie(value string) {
+	u, _ := url.Parse(c.app.server.URL)
+	c.http.Jar.SetCookies(u, []*http.Cookie{{Name: "library-session", Value: value, Path: "/"}})
+}
+
+// do 发送JSON请求，out不为nil时解析响应体，返回状态码
+func (c *testClient) do(method, path string, body, out any) int {
+	c.t.Helper()
+
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			c.t.Fatalf("marshal request: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.app.server.URL+path, bytes.NewReader(data))
+	if err != nil {
+		c.t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if !c.noCSRF {
+		req.Header.Set(middleware.CSRFHeader, c.csrf)
+	}
+	if c.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", c.acceptLanguage)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	c.lastResponse = resp
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatalf("decode %s %s response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"library-system/services"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 一次最多订阅的图书数
+const maxStreamBooks = 50
+
+// 客户端断线后重连的等待时间（毫秒）
+const streamRetryMs = 3000
+
+type StreamHandler struct {
+	events      *services.EventBus
+	bookService *services.BookService
+	// 没有事件时发送注释行的间隔，避免代理断开空闲连接
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(events *services.EventBus, bookService *services.BookService, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{events: events, bookService: bookService, heartbeat: heartbeat}
+}
+
+// Availability godoc
+// @Summary 订阅图书库存变化
+// @Description Server-Sent Events 流。每次借还书后推送 event: availability，data为图书ID和当前库存（需要登录）。
+// @Description 新连接先推送一次当前库存；断线重连时浏览器会带上Last-Event-ID请求头，服务端补发错过的事件，
+// @Description 错过的事件已不在缓存中时重新推送当前库存。没有事件时定期发送注释行保持连接
+// @Tags events
+// @Produce text/event-stream
+// @Param book_id query []int true "图书ID，可以重复，最多50个" collectionFormat(multi)
+// @Param Last-Event-ID header string false "最后收到的事件ID"
+// @Param last_event_id query string false "同Last-Event-ID，用于无法设置请求头的客户端"
+// @Success 200 {object} services.Availability "事件流"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 404 {object} middleware.Problem "图书不存在"
+// @Router /events/availability [get]
+func (h *StreamHandler) Availability(c *gin.Context) {
+	// 从查询参数获取图书ID
+	raw := c.QueryArray("book_id")
+	if len(raw) == 0 {
+		c.Error(invalidParam("book_id", "required"))
+		return
+	}
+	if len(raw) > maxStreamBooks {
+		c.Error(invalidParam("book_id", "invalid"))
+		return
+	}
+	bookIDs := make([]int, 0, len(raw))
+	for _, s := range raw {
+		id, err := strconv.Atoi(s)
+		if err != nil || id <= 0 {
+			c.Error(invalidParam("book_id", "invalid"))
+			return
+		}
+		bookIDs = append(bookIDs, id)
+	}
+	lastEventID, ok := h.lastEventID(c)
+	if !ok {
+		return
+	}
+
+	// 先订阅再读取当前库存，避免遗漏两者之间的借还
+	sub, replay, complete := h.events.Subscribe(lastEventID, func(e *services.BusEvent) bool {
+		return e.Type == services.BusEventAvailability && slices.Contains(bookIDs, e.BookID)
+	})
+	defer sub.Close()
+
+	var snapshot []services.Availability
+	if !complete {
+		for _, id := range bookIDs {
+			book, err := h.bookService.GetBookInfoByID(c.Request.Context(), id)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			snapshot = append(snapshot, services.Availability{BookID: book.ID, Stock: book.Stock})
+		}
+	}
+
+	h.stream(c, sub, func(w *sseWriter) error {
+		// 当前库存不带事件ID，不影响客户端记录的最后事件ID
+		for _, a := range snapshot {
+			if err := w.event(0, services.BusEventAvailability, a); err != nil {
+				return err
+			}
+		}
+		for _, e := range replay {
+			if err := w.event(e.ID, e.Type, e.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Circulation godoc
+// @Summary 订阅全部借还事件
+// @Description Server-Sent Events 流，推送全部借书（loan.created）、还书（loan.returned）事件和库存变化（availability），data为借阅记录或库存（管理员）。
+// @Description 断线重连时根据Last-Event-ID补发缓存中错过的事件
+// @Tags events
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "最后收到的事件ID"
+// @Param last_event_id query string false "同Last-Event-ID，用于无法设置请求头的客户端"
+// @Success 200 {object} models.BorrowRecord "事件流"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 403 {object} middleware.Problem "权限不足"
+// @Router /events/circulation [get]
+func (h *StreamHandler) Circulation(c *gin.Context) {
+	lastEventID, ok := h.lastEventID(c)
+	if !ok {
+		return
+	}
+
+	sub, replay, _ := h.events.Subscribe(lastEventID, func(*services.BusEvent) bool { return true })
+	defer sub.Close()
+
+	h.stream(c, sub, func(w *sseWriter) error {
+		for _, e := range replay {
+			if err := w.event(e.ID, e.Type, e.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lastEventID 优先使用浏览器重连时发送的请求头
+func (h *StreamHandler) lastEventID(c *gin.Context) (uint64, bool) {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		c.Error(invalidParam("last_event_id", "invalid"))
+		return 0, false
+	}
+	return id, true
+}
+
+// stream 发送响应头和初始事件，之后转发订阅的事件，直到客户端断开、订阅结束或关闭服务
+func (h *StreamHandler) stream(c *gin.Context, sub *services.Subscription, initial func(w *sseWriter) error) {
+	// 事件流是长连接，不受服务器写超时限制
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// 禁止nginx缓冲
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := &sseWriter{w: c.Writer}
+	if _, err := fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetryMs); err != nil {
+		return
+	}
+	if err := initial(w); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := w.event(e.ID, e.Type, e.Data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// sseWriter
+type sseWriter struct {
+	w io.Writer
+}
+
+// event id为0时不发送事件ID
+func (w *sseWriter) event(id uint64, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		if _, err := fmt.Fprintf(w.w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w.w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
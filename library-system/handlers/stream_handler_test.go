@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent 收到的一个事件，注释行（心跳）记为event为空的事件
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// openStream 打开事件流并在后台解析，测试结束时断开
+func (c *testClient) openStream(path, lastEventID string) <-chan sseEvent {
+	c.t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	c.t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.app.server.URL+path, nil)
+	if err != nil {
+		c.t.Fatalf("NewRequest() error = %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("GET %s: %v", path, err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		resp.Body.Close()
+		c.t.Fatalf("GET %s status = %d, content type = %q", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		var e sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if e != (sseEvent{}) {
+					events <- e
+				}
+				e = sseEvent{}
+			case strings.HasPrefix(line, ":"):
+				e.data = "ping"
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+// next 跳过心跳，返回下一个事件
+func next(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			if e.event != "" {
+				return e
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for event")
+		}
+	}
+}
+
+// availability 解析库存事件
+func availability(t *testing.T, e sseEvent) services.Availability {
+	t.Helper()
+	if e.event != services.BusEventAvailability {
+		t.Fatalf("event = %q, want %q", e.event, services.BusEventAvailability)
+	}
+	var a services.Availability
+	if err := json.Unmarshal([]byte(e.data), &a); err != nil {
+		t.Fatalf("decode %q: %v", e.data, err)
+	}
+	return a
+}
+
+func TestStreamHandler_Availability(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	book := app.createBook(t, "Go", 2)
+	other := app.createBook(t, "Rust", 2)
+
+	lemon := app.newClient(t)
+	lemon.login("lemon")
+	path := "/api/v1/events/availability?book_id=" + strconv.Itoa(book.ID)
+
+	// 新连接先收到当前库存，不带事件ID
+	events := lemon.openStream(path, "")
+	if e := next(t, events); e.id != "" || availability(t, e) != (services.Availability{BookID: book.ID, Stock: 2}) {
+		t.Fatalf("snapshot = %+v", e)
+	}
+
+	// 未订阅的图书不推送
+	if status := lemon.do(http.MethodPost, "/api/v1/borrow", BorrowBookRequest{BookID: other.ID}, nil); status != http.StatusOK {
+		t.Fatalf("borrow status = %d", status)
+	}
+	if status := lemon.do(http.MethodPost, "/api/v1/borrow", BorrowBookRequest{BookID: book.ID}, nil); status != http.StatusOK {
+		t.Fatalf("borrow status = %d", status)
+	}
+	first := next(t, events)
+	if first.id == "" || availability(t, first) != (services.Availability{BookID: book.ID, Stock: 1}) {
+		t.Fatalf("event = %+v, want stock 1 with an ID", first)
+	}
+
+	// 断线期间的事件在重连时补发，不再推送当前库存
+	if status := lemon.do(http.MethodPost, "/api/v1/borrow", BorrowBookRequest{BookID: book.ID}, nil); status != http.StatusOK {
+		t.Fatalf("borrow status = %d", status)
+	}
+	resumed := lemon.openStream(path, first.id)
+	if e := next(t, resumed); e.id == "" || availability(t, e).Stock != 0 {
+		t.Fatalf("replayed event = %+v, want stock 0", e)
+	}
+
+	// 没有事件时发送心跳
+	select {
+	case e := <-resumed:
+		if e.data != "ping" {
+			t.Fatalf("event = %+v, want heartbeat", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no heartbeat")
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "缺少图书ID", path: "/api/v1/events/availability", wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "无效的图书ID", path: "/api/v1/events/availability?book_id=abc", wantStatus: http.StatusBadRequest, wantCode: "VALIDATION_FAILED"},
+		{name: "图书不存在", path: "/api/v1/events/availability?book_id=404", wantStatus: http.StatusNotFound, wantCode: "BOOK_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem middleware.Problem
+			if status := lemon.do(http.MethodGet, tt.path, nil, &problem); status != tt.wantStatus || problem.Code != tt.wantCode {
+				t.Errorf("status = %d, code = %q, want %d, %q", status, problem.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestStreamHandler_Circulation(t *testing.T) {
+	app := newTestApp(t, false)
+	app.createUser(t, "lemon", models.RoleUser)
+	app.createUser(t, "admin", models.RoleAdmin)
+	book := app.createBook(t, "Go", 1)
+
+	lemon := app.newClient(t)
+	lemon.login("lemon")
+	admin := app.newClient(t)
+	admin.login("admin")
+
+	// 只有管理员可以订阅
+	var problem middleware.Problem
+	if status := lemon.do(http.MethodGet, "/api/v1/events/circulation", nil, &problem); status != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	events := admin.openStream("/api/v1/events/circulation", "")
+	if status := lemon.do(http.MethodPost, "/api/v1/borrow", BorrowBookRequest{BookID: book.ID}, nil); status != http.StatusOK {
+		t.Fatalf("borrow status = %d", status)
+	}
+
+	e := next(t, events)
+	var record models.BorrowRecord
+	if err := json.Unmarshal([]byte(e.data), &record); err != nil {
+		t.Fatalf("decode %q: %v", e.data, err)
+	}
+	if e.event != models.EventLoanCreated || record.BookID != book.ID {
+		t.Errorf("event = %+v, want loan.created for book %d", e, book.ID)
+	}
+	if e := next(t, events); availability(t, e).Stock != 0 {
+		t.Errorf("availability = %+v, want stock 0", e)
+	}
+}
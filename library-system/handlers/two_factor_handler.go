@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"errors"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// 用户名密码验证通过后，输入两步验证码的有效期
+const pendingLoginTTL = 5 * time.Minute
+
+type TwoFactorHandler struct {
+	twoFactorService *services.TwoFactorService
+	sessionStore     sessions.Store
+}
+
+func NewTwoFactorHandler(twoFactorService *services.TwoFactorService, sessionStore sessions.Store) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactorService: twoFactorService, sessionStore: sessionStore}
+}
+
+// VerifyLogin godoc
+// @Summary 两步验证登录
+// @Description 用户名密码验证通过后，在5分钟内提交TOTP验证码或恢复码完成登录
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "验证码或恢复码"
+// @Success 200 {object} Response[LoginResult] "登录成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误或验证码错误"
+// @Failure 401 {object} middleware.Problem "登录已过期"
+// @Failure 403 {object} middleware.Problem "账号已停用"
+// @Failure 429 {object} middleware.Problem "失败次数过多，Retry-After头给出等待秒数"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /auth/login/2fa [post]
+func (h *TwoFactorHandler) VerifyLogin(c *gin.Context) {
+	var req TwoFactorCodeRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取Session中待验证的登录
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userID, _ := session.Values["pendingUserID"].(int)
+	pendingAt, _ := session.Values["pendingAt"].(int64)
+	if userID == 0 || time.Since(time.Unix(pendingAt, 0)) > pendingLoginTTL {
+		c.Error(services.ErrLoginExpired)
+		return
+	}
+
+	user, err := h.twoFactorService.VerifyLogin(c.Request.Context(), userID, req.Code, c.ClientIP())
+	if err != nil {
+		// 待验证的用户已被删除或关闭了两步验证时需要重新登录
+		if errors.Is(err, services.ErrUserNotFound) || errors.Is(err, services.ErrTwoFactorNotEnabled) {
+			err = withCode(services.ErrLoginExpired, err)
+		}
+		c.Error(err)
+		return
+	}
+
+	// 保存Session
+	csrfToken, err := establishSession(c, session, user, true)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "auth.login_success", LoginResult{User: user, CSRFToken: csrfToken})
+}
+
+// Setup godoc
+// @Summary 生成两步验证密钥
+// @Description 生成新的TOTP密钥和otpauth URI，需调用启用接口确认后才生效（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Success 200 {object} Response[services.TOTPSetup] "密钥和URI"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 409 {object} middleware.Problem "两步验证已启用"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/2fa/setup [post]
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	setup, err := h.twoFactorService.Setup(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", setup)
+}
+
+// QRCode godoc
+// @Summary 获取两步验证二维码
+// @Description 返回待确认密钥的二维码PNG图片，供验证器应用扫描（需要登录）
+// @Tags me
+// @Produce png
+// @Success 200 {file} file "二维码图片"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 404 {object} middleware.Problem "尚未生成密钥"
+// @Failure 409 {object} middleware.Problem "两步验证已启用"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/2fa/qrcode [get]
+func (h *TwoFactorHandler) QRCode(c *gin.Context) {
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	png, err := h.twoFactorService.QRCode(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// Enable godoc
+// @Summary 启用两步验证
+// @Description 提交验证器应用生成的验证码确认密钥，返回只显示一次的恢复码（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "验证码"
+// @Success 200 {object} Response[RecoveryCodesResult] "启用成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误或验证码错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 404 {object} middleware.Problem "尚未生成密钥"
+// @Failure 409 {object} middleware.Problem "两步验证已启用"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	var req TwoFactorCodeRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	codes, err := h.twoFactorService.Enable(c.Request.Context(), user.ID, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 刚完成验证，当前Session视为已通过两步验证
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	session.Values["twoFactorVerified"] = true
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "two_factor.enabled", RecoveryCodesResult{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary 关闭两步验证
+// @Description 验证密码和验证码（或恢复码）后关闭两步验证（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body DisableTwoFactorRequest true "密码和验证码"
+// @Success 200 {object} Response[any] "关闭成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误或验证码错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 403 {object} middleware.Problem "密码错误"
+// @Failure 409 {object} middleware.Problem "两步验证未启用"
+// @Failure 429 {object} middleware.Problem "失败次数过多，Retry-After头给出等待秒数"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var req DisableTwoFactorRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	err := h.twoFactorService.Disable(c.Request.Context(), user.ID, req.Password, req.Code, c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Message(c, http.StatusOK, "two_factor.disabled")
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary 重新生成恢复码
+// @Description 验证码校验通过后作废所有旧恢复码并生成新的一组（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "验证码"
+// @Success 200 {object} Response[RecoveryCodesResult] "新的恢复码"
+// @Failure 400 {object} middleware.Problem "请求参数错误或验证码错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 409 {object} middleware.Problem "两步验证未启用"
+// @Failure 429 {object} middleware.Problem "失败次数过多，Retry-After头给出等待秒数"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(c.Request.Context(), user.ID, req.Code, c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "two_factor.recovery_codes_regenerated", RecoveryCodesResult{RecoveryCodes: codes})
+}
+
+// 请求和响应结构体定义
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required" example:"lemon2024tree"`
+	Code     string `json:"code" binding:"required" example:"123456"`
+}
+
+type RecoveryCodesResult struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"abcde-23456,fghjk-78923"`
+}
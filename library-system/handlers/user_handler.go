@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+type UserHandler struct {
+	userService  *services.UserService
+	sessionStore sessions.Store
+}
+
+func NewUserHandler(userService *services.UserService, sessionStore sessions.Store) *UserHandler {
+	return &UserHandler{userService: userService, sessionStore: sessionStore}
+}
+
+// GetProfile godoc
+// @Summary 获取个人资料
+// @Description 获取当前登录用户的个人资料（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Success 200 {object} Response[models.User] "个人资料"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 404 {object} middleware.Problem "用户不存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me [get]
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	profile, err := h.userService.GetProfile(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", profile)
+}
+
+// UpdateProfile godoc
+// @Summary 更新个人资料
+// @Description 更新当前登录用户的邮箱、手机号和语言偏好，传空字符串表示清除（需要登录）。语言偏好为zh-CN或en-US，设置后优先于Accept-Language
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body UpdateProfileRequest true "个人资料"
+// @Success 200 {object} Response[models.User] "更新后的个人资料"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 404 {object} middleware.Problem "用户不存在"
+// @Failure 409 {object} middleware.Problem "邮箱已被使用"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me [put]
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	profile, err := h.userService.UpdateProfile(c.Request.Context(), user.ID, req.Email, req.Phone, req.Locale)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 新的语言偏好从下一个请求开始生效
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	session.Values["locale"] = profile.Locale
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", profile)
+}
+
+// ChangePassword godoc
+// @Summary 修改密码
+// @Description 验证当前密码后设置新密码（需要登录）。当前密码错误计入登录失败次数；修改成功后其他设备上的登录全部失效，当前Session更换ID和CSRF令牌
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "密码信息"
+// @Success 200 {object} Response[CSRFTokenResult] "修改成功，返回当前Session新的CSRF令牌"
+// @Failure 400 {object} middleware.Problem "请求参数错误或新密码不符合要求"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 403 {object} middleware.Problem "当前密码错误"
+// @Failure 429 {object} middleware.Problem "当前密码错误次数过多，Retry-After头给出等待秒数"
+// @Failure 404 {object} middleware.Problem "用户不存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/password [post]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	err := h.userService.ChangePassword(c.Request.Context(), user.ID, req.CurrentPassword, req.NewPassword, c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 全部Session已被吊销，当前请求使用新的Session ID和CSRF令牌继续保持登录
+	session, err := h.sessionStore.Get(c.Request, "library-session")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := middleware.RenewSession(c.Request, session); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		c.Error(err)
+		return
+	}
+	token, err := middleware.CSRFToken(session)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "user.password_changed", CSRFTokenResult{CSRFToken: token})
+}
+
+// GetAccountSummary godoc
+// @Summary 获取账户概览
+// @Description 一次性返回当前用户的在借图书、逾期数量和借阅上限（需要登录）
+// @Tags me
+// @Accept json
+// @Produce json
+// @Success 200 {object} Response[services.AccountSummary] "账户概览"
+// @Failure 401 {object} middleware.Problem "用户未认证"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /me/summary [get]
+func (h *UserHandler) GetAccountSummary(c *gin.Context) {
+	// 获取用户信息
+	userObj, exists := c.Get("user")
+	if !exists {
+		c.Error(middleware.ErrUnauthenticated)
+		return
+	}
+	user := userObj.(*models.User)
+
+	summary, err := h.userService.GetAccountSummary(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", summary)
+}
+
+// SetCardNumber godoc
+// @Summary 登记借书证号
+// @Description 管理员为读者登记或更换借书证号，card_number为空时注销。馆员可凭借书证号在服务台为读者借书
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param request body SetCardNumberRequest true "借书证号"
+// @Success 200 {object} Response[models.User] "登记成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误或格式不正确"
+// @Failure 404 {object} middleware.Problem "用户不存在"
+// @Failure 409 {object} middleware.Problem "借书证号已被使用"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/users/{id}/card-number [put]
+func (h *UserHandler) SetCardNumber(c *gin.Context) {
+	// 从路径参数获取ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.Error(invalidParam("id", "invalid"))
+		return
+	}
+
+	var req SetCardNumberRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.userService.SetCardNumber(c.Request.Context(), id, req.CardNumber)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "admin.card_number_set", user)
+}
+
+// 请求和响应结构体定义
+type UpdateProfileRequest struct {
+	Email  string `json:"email" example:"lemon@example.com"`
+	Phone  string `json:"phone" example:"13800000000"`
+	Locale string `json:"locale" example:"en-US"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"password123"`
+	NewPassword     string `json:"new_password" binding:"required" example:"lemon2024tree"`
+}
+
+type SetCardNumberRequest struct {
+	CardNumber string `json:"card_number" example:"L2024000123"`
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"library-system/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// GetWebhooks godoc
+// @Summary 获取Webhook列表
+// @Description 管理员查看全部登记的Webhook，不返回签名密钥
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} Response[[]models.Webhook] "Webhook数组"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/webhooks [get]
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.GetWebhooks(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", webhooks)
+}
+
+// CreateWebhook godoc
+// @Summary 登记Webhook
+// @Description 管理员登记事件接收地址。事件以POST请求投递，请求头X-Webhook-Signature为 t=<时间戳>,v1=<HMAC-SHA256(secret, "<时间戳>.<请求体>")>，
+// @Description 签名密钥只在本接口返回一次。接收方返回非2xx时按指数退避重试
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body WebhookRequest true "Webhook信息"
+// @Success 201 {object} Response[services.CreatedWebhook] "登记成功，包含签名密钥"
+// @Failure 400 {object} middleware.Problem "请求参数错误或格式不正确"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), req.URL, req.Events, req.active())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusCreated, "admin.webhook_created", webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary 修改Webhook
+// @Description 管理员修改接收地址、订阅的事件或启用状态，签名密钥不变。停用后尚未投递的事件不再重试
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param request body WebhookRequest true "Webhook信息"
+// @Success 200 {object} Response[models.Webhook] "修改成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误或格式不正确"
+// @Failure 404 {object} middleware.Problem "Webhook不存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	// 从路径参数获取ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.Error(invalidParam("id", "invalid"))
+		return
+	}
+
+	var req WebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(c.Request.Context(), id, req.URL, req.Events, req.active())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "admin.webhook_updated", webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary 删除Webhook
+// @Description 管理员删除Webhook及其投递记录
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 204 "删除成功"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 404 {object} middleware.Problem "Webhook不存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	// 从路径参数获取ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.Error(invalidParam("id", "invalid"))
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetDeliveries godoc
+// @Summary 获取Webhook投递记录
+// @Description 管理员查看Webhook的投递记录，包括尝试次数、响应状态码和错误，最新的在前
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "返回条数，默认20，最多100"
+// @Success 200 {object} Response[[]models.WebhookDelivery] "投递记录数组"
+// @Failure 400 {object} middleware.Problem "请求参数错误"
+// @Failure 404 {object} middleware.Problem "Webhook不存在"
+// @Failure 500 {object} middleware.Problem "服务器内部错误"
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	// 从路径参数获取ID
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.Error(invalidParam("id", "invalid"))
+		return
+	}
+	// 从查询参数获取条数
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.Error(invalidParam("limit", "invalid"))
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(c.Request.Context(), id, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	Success(c, http.StatusOK, "", deliveries)
+}
+
+// 请求和响应结构体定义
+type WebhookRequest struct {
+	URL string `json:"url" binding:"required" example:"https://portal.example.com/hooks/library"`
+	// book.created、book.updated、book.deleted、loan.created、loan.returned或loan.overdue
+	Events []string `json:"events" binding:"required" example:"loan.created,loan.returned"`
+	// 不传时为true
+	Active *bool `json:"active" example:"true"`
+}
+
+// active
+func (r *WebhookRequest) active() bool {
+	return r.Active == nil || *r.Active
+}
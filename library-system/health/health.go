@@ -0,0 +1,168 @@
+// Package health 汇总就绪检查，供 /readyz 使用
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 检查状态
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// 单个检查的超时时间
+const checkTimeout = 2 * time.Second
+
+// CheckFunc 返回nil表示检查通过
+type CheckFunc func(ctx context.Context) error
+
+// 失败时返回给调用方的说明，/readyz无需认证，具体错误只写入日志
+const (
+	errCheckFailed   = "check failed"
+	errCheckTimedOut = "check timed out"
+)
+
+// CheckResult 单个检查的结果
+type CheckResult struct {
+	Status     string `json:"status" example:"ok"`
+	Error      string `json:"error,omitempty" example:"check failed"`
+	DurationMS int64  `json:"duration_ms" example:"2"`
+}
+
+// Report 所有检查的结果，任意一项失败时Status为fail
+type Report struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Checker 就绪检查集合
+type Checker struct {
+	mu       sync.RWMutex
+	names    []string
+	checks   map[string]CheckFunc
+	draining atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]CheckFunc)}
+}
+
+// Add 注册检查，同名检查会被替换
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Drain 服务开始关闭后不再就绪，负载均衡可以提前摘除实例
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Run 并发执行所有检查
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	names := append([]string(nil), c.names...)
+	checks := make([]CheckFunc, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Go(func() {
+			results[i] = runCheck(ctx, names[i], checks[i])
+		})
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(names)+1)}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	if c.draining.Load() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = CheckResult{Status: StatusFail, Error: "server is shutting down"}
+	}
+
+	return report
+}
+
+// runCheck 失败时记录完整错误，结果中只包含固定的说明
+func runCheck(ctx context.Context, name string, check CheckFunc) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = errCheckFailed
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result.Error = errCheckTimedOut
+		}
+		slog.WarnContext(ctx, "就绪检查失败", slog.String("check", name), slog.Any("error", err))
+	}
+	return result
+}
+
+// Heartbeat 后台任务每轮执行后调用Beat，超过MaxAge未更新视为已停止
+type Heartbeat struct {
+	maxAge time.Duration
+	last   atomic.Int64
+}
+
+// NewHeartbeat 创建时视为刚刚更新过，避免启动阶段误报
+func NewHeartbeat(maxAge time.Duration) *Heartbeat {
+	h := &Heartbeat{maxAge: maxAge}
+	h.Beat()
+	return h
+}
+
+// Beat
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Check 可直接注册为CheckFunc
+func (h *Heartbeat) Check(ctx context.Context) error {
+	age := time.Since(time.Unix(0, h.last.Load()))
+	if age > h.maxAge {
+		return fmt.Errorf("no heartbeat for %s", age.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// ErrSchemaOutdated 数据库结构版本与代码期望的不一致
+var ErrSchemaOutdated = errors.New("schema version mismatch")
+
+// SchemaCheck 比较数据库中记录的版本和期望版本
+func SchemaCheck(current func(ctx context.Context) (int, error), expected int) CheckFunc {
+	return func(ctx context.Context) error {
+		version, err := current(ctx)
+		if err != nil {
+			return err
+		}
+		if version != expected {
+			return fmt.Errorf("%w: database at %d, expected %d", ErrSchemaOutdated, version, expected)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker_Run(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	// 驱动返回的错误中包含地址和账号，不能出现在结果中
+	checker.Add("schema", func(ctx context.Context) error {
+		return errors.New("Error 1045 (28000): Access denied for user 'library'@'10.0.0.5'")
+	})
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report := checker.Run(ctx)
+
+	if report.Status != StatusFail {
+		t.Errorf("status = %q, want %q", report.Status, StatusFail)
+	}
+	want := map[string]CheckResult{
+		"database": {Status: StatusOK},
+		"schema":   {Status: StatusFail, Error: errCheckFailed},
+		"slow":     {Status: StatusFail, Error: errCheckTimedOut},
+	}
+	for name, w := range want {
+		got := report.Checks[name]
+		if got.Status != w.Status || got.Error != w.Error {
+			t.Errorf("%s = %+v, want status %q error %q", name, got, w.Status, w.Error)
+		}
+	}
+
+	checker.Drain()
+	if got := checker.Run(ctx); got.Status != StatusFail || got.Checks["shutdown"].Status != StatusFail {
+		t.Errorf("report after Drain = %+v", got)
+	}
+}
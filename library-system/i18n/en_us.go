@@ -0,0 +1,114 @@
+package i18n
+
+// enUS 英文目录
+var enUS = map[string]string{
+	// 认证
+	"auth.login_success":       "Logged in successfully",
+	"auth.two_factor_required": "Please enter your two-factor authentication code",
+	"auth.registered":          "Registered successfully",
+
+	// 两步验证
+	"two_factor.enabled":                    "Two-factor authentication is enabled, keep your recovery codes safe",
+	"two_factor.disabled":                   "Two-factor authentication is disabled",
+	"two_factor.recovery_codes_regenerated": "Recovery codes regenerated, the old codes no longer work",
+
+	// 个人资料和密码重置
+	"user.password_changed":    "Password changed",
+	"password_reset.requested": "If the email address is registered, a reset email has been sent",
+	"password_reset.done":      "Password reset, please log in again",
+
+	// 图书和借阅
+	"borrow.borrowed":    "Book borrowed",
+	"borrow.returned":    "Book returned",
+	"borrow.checked_out": "Book checked out to the patron",
+	"borrow.checked_in":  "Book checked in",
+
+	// 通知
+	"notification.preferences_updated": "Notification settings updated",
+
+	// 管理
+	"admin.book_added":      "Book added",
+	"admin.book_updated":    "Book updated",
+	"admin.book_deleted":    "Book deleted",
+	"admin.lockout_cleared": "Lockout cleared",
+	"admin.job_triggered":   "The job has been started",
+	"admin.webhook_created": "Webhook created",
+	"admin.webhook_updated": "Webhook updated",
+	"admin.card_number_set": "Library card number updated",
+
+	// 定时任务说明，key为job.加任务名
+	"job.overdue_scan":     "Send due-soon and overdue reminders and mark the reminded loans",
+	"job.session_purge":    "Delete expired login sessions",
+	"job.ldap_sync":        "Synchronise users from the directory",
+	"job.webhook_dispatch": "Deliver catalogue and circulation events to registered webhooks",
+
+	// 接口层错误
+	"error.invalid_request":     "Malformed request body",
+	"error.unauthenticated":     "Unauthorised, please log in first",
+	"error.admin_required":      "Administrator permission required",
+	"error.two_factor_required": "Administrators must enable and pass two-factor authentication",
+	"error.csrf_token_invalid":  "Missing or invalid CSRF token, please fetch a new one",
+	"error.not_found":           "No such endpoint",
+	"error.service_unavailable": "The request timed out, please try again later",
+	"error.internal_error":      "Internal server error",
+
+	// 业务错误，key为error.加小写的错误码
+	"error.user_not_found":             "User not found",
+	"error.user_exists":                "The username is already taken",
+	"error.invalid_password":           "Incorrect password",
+	"error.invalid_credentials":        "Incorrect username or password",
+	"error.book_not_found":             "Book not found",
+	"error.book_exists":                "The book already exists",
+	"error.out_of_stock":               "The book is out of stock",
+	"error.borrow_limit_reached":       "You have reached the borrowing limit",
+	"error.record_not_found":           "Borrow record not found",
+	"error.already_returned":           "The book has already been returned",
+	"error.permission_denied":          "Permission denied",
+	"error.validation_failed":          "Invalid input",
+	"error.email_exists":               "The email address is already in use",
+	"error.card_number_exists":         "The library card number is already in use",
+	"error.invalid_reset_token":        "The reset link is invalid or has expired",
+	"error.too_many_attempts":          "Too many login attempts, please try again later",
+	"error.lockout_not_found":          "Lockout not found",
+	"error.two_factor_already_enabled": "Two-factor authentication is already enabled",
+	"error.two_factor_not_enabled":     "Two-factor authentication is not enabled",
+	"error.two_factor_not_setup":       "Please generate a two-factor secret first",
+	"error.invalid_two_factor_code":    "Incorrect verification code",
+	"error.oidc_login_failed":          "Single sign-on failed",
+	"error.user_disabled":              "The account is disabled",
+	"error.username_conflict":          "The username is already used by another account, please contact an administrator",
+	"error.login_expired":              "The login has expired, please enter your username and password again",
+	"error.notification_not_found":     "Notification not found",
+	"error.job_not_found":              "Job not found",
+	"error.job_running":                "The job is running or has just run, please try again later",
+	"error.webhook_not_found":          "Webhook not found",
+
+	// 字段校验，key为validation.加规则名
+	"validation.required":     "%s is required",
+	"validation.invalid":      "%s is invalid",
+	"validation.type":         "%s must be of type %s",
+	"validation.min_length":   "The password must be at least %d characters long",
+	"validation.max_length":   "The password must not exceed %d bytes",
+	"validation.uppercase":    "The password must contain an uppercase letter",
+	"validation.lowercase":    "The password must contain a lowercase letter",
+	"validation.digit":        "The password must contain a digit",
+	"validation.symbol":       "The password must contain a special character",
+	"validation.not_username": "The password must not be the same as the username",
+	"validation.common":       "The password is too common, please choose another",
+	"validation.length":       "The username must be between %d and %d characters long",
+	"validation.format":       "The username must start with a letter and contain only letters, digits, underscores, dots and hyphens",
+
+	// 邮件
+	"mail.password_reset.subject": "Reset your password",
+	"mail.password_reset.body":    "Hello %s,\n\nOpen the following link within %d minutes to reset your password:\n%s?token=%s\n\nIf you did not request this, please ignore this email.\n",
+
+	// 借阅通知，正文参数依次为用户姓名、书名、应还日期
+	"notification.loan_created.title":  "Book borrowed",
+	"notification.loan_created.body":   "Hello %s, you have borrowed \"%s\". Please return it by %s.",
+	"notification.loan_returned.title": "Book returned",
+	"notification.loan_returned.body":  "Hello %s, \"%s\" has been returned (due %s). Thank you.",
+	"notification.due_soon.title":      "Book due soon",
+	"notification.due_soon.body":       "Hello %s, \"%s\" is due on %s. Please return it in time.",
+	"notification.overdue.title":       "Book overdue",
+	"notification.overdue.body":        "Hello %s, \"%s\" was due on %s. Please return it as soon as possible.",
+}
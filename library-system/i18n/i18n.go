@@ -0,0 +1,122 @@
+// Package i18n 接口提示信息的多语言目录和语言协商
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的语言，使用BCP 47规范形式
+const (
+	ZhCN = "zh-CN"
+	EnUS = "en-US"
+)
+
+// DefaultLocale 无法协商出支持的语言时使用，也是服务端日志和内部错误信息的语言
+const DefaultLocale = ZhCN
+
+var catalogs = map[string]map[string]string{
+	ZhCN: zhCN,
+	EnUS: enUS,
+}
+
+// Locales 支持的语言，默认语言在前
+func Locales() []string {
+	return []string{ZhCN, EnUS}
+}
+
+// Lookup 返回locale目录中key对应的原始文本
+func Lookup(locale, key string) (string, bool) {
+	message, ok := catalogs[locale][key]
+	return message, ok
+}
+
+// T 翻译key并按fmt格式化args
+// locale缺少该key时使用默认语言，都没有时返回key本身，key为空时返回空字符串
+func T(locale, key string, args ...any) string {
+	if key == "" {
+		return ""
+	}
+	message, ok := Lookup(locale, key)
+	if !ok {
+		if message, ok = Lookup(DefaultLocale, key); !ok {
+			return key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Match 将语言标签匹配到支持的语言，不区分大小写
+// 完全相同时直接匹配，否则按主语言匹配，如en、en-GB匹配en-US，zh、zh-Hans-CN匹配zh-CN
+func Match(tag string) (string, bool) {
+	tag = strings.TrimSpace(strings.ReplaceAll(tag, "_", "-"))
+	if tag == "" {
+		return "", false
+	}
+	for _, locale := range Locales() {
+		if strings.EqualFold(tag, locale) {
+			return locale, true
+		}
+	}
+	primary, _, _ := strings.Cut(tag, "-")
+	for _, locale := range Locales() {
+		if lang, _, _ := strings.Cut(locale, "-"); strings.EqualFold(primary, lang) {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate 按Accept-Language中的q值选择支持的语言，q值相同时保持原顺序
+// 没有可用的语言时返回DefaultLocale
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 && tag != "" && tag != "*" {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if locale, ok := Match(c.tag); ok {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+type localeKey struct{}
+
+// WithLocale 返回带有响应语言的context
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext 返回context中的响应语言，没有时返回DefaultLocale
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,84 @@
+package i18n
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var verbPattern = regexp.MustCompile(`%[a-z]`)
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, locale := range Locales() {
+		for _, other := range Locales() {
+			for key, message := range catalogs[locale] {
+				translated, ok := catalogs[other][key]
+				if !ok {
+					t.Errorf("%s: key %q missing in %s", locale, key, other)
+					continue
+				}
+				// 格式化参数的顺序和类型必须一致
+				if want, got := verbPattern.FindAllString(message, -1), verbPattern.FindAllString(translated, -1); !slices.Equal(want, got) {
+					t.Errorf("%s: verbs of %q = %v, %s has %v", other, key, got, locale, want)
+				}
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		key    string
+		args   []any
+		want   string
+	}{
+		{name: "中文", locale: ZhCN, key: "error.out_of_stock", want: "库存不足"},
+		{name: "英文", locale: EnUS, key: "error.out_of_stock", want: "The book is out of stock"},
+		{name: "格式化参数", locale: EnUS, key: "validation.min_length", args: []any{8}, want: "The password must be at least 8 characters long"},
+		{name: "不支持的语言使用默认语言", locale: "fr-FR", key: "error.out_of_stock", want: "库存不足"},
+		{name: "key不存在", locale: EnUS, key: "no.such.key", want: "no.such.key"},
+		{name: "key为空", locale: EnUS, key: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := T(tt.locale, tt.key, tt.args...); got != tt.want {
+				t.Errorf("T() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: DefaultLocale},
+		{header: "en-US,en;q=0.9", want: EnUS},
+		{header: "en-GB", want: EnUS},
+		{header: "zh-Hans-CN", want: ZhCN},
+		{header: "fr-FR,en;q=0.5,zh;q=0.8", want: ZhCN},
+		{header: "zh;q=0.1,EN", want: EnUS},
+		{header: "en;q=0,zh;q=0.5", want: ZhCN},
+		{header: "fr-FR,de", want: DefaultLocale},
+		{header: "*", want: DefaultLocale},
+		{header: "en;q=abc,zh", want: ZhCN},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != DefaultLocale {
+		t.Errorf("FromContext() = %q, want %q", got, DefaultLocale)
+	}
+	if got := FromContext(WithLocale(context.Background(), EnUS)); got != EnUS {
+		t.Errorf("FromContext() = %q, want %q", got, EnUS)
+	}
+}
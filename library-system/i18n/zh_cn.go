@@ -0,0 +1,114 @@
+package i18n
+
+// zhCN 简体中文目录，也是默认语言
+var zhCN = map[string]string{
+	// 认证
+	"auth.login_success":       "登录成功",
+	"auth.two_factor_required": "请输入两步验证码",
+	"auth.registered":          "用户注册成功",
+
+	// 两步验证
+	"two_factor.enabled":                    "两步验证已启用，请妥善保存恢复码",
+	"two_factor.disabled":                   "两步验证已关闭",
+	"two_factor.recovery_codes_regenerated": "恢复码已重新生成，旧恢复码已失效",
+
+	// 个人资料和密码重置
+	"user.password_changed":    "密码修改成功",
+	"password_reset.requested": "如果该邮箱已注册，重置邮件已发送",
+	"password_reset.done":      "密码重置成功，请重新登录",
+
+	// 图书和借阅
+	"borrow.borrowed":    "借书成功",
+	"borrow.returned":    "还书成功",
+	"borrow.checked_out": "已为读者办理借书",
+	"borrow.checked_in":  "已办理还书",
+
+	// 通知
+	"notification.preferences_updated": "通知设置已更新",
+
+	// 管理
+	"admin.book_added":      "图书添加成功",
+	"admin.book_updated":    "图书更新成功",
+	"admin.book_deleted":    "图书删除成功",
+	"admin.lockout_cleared": "已解除锁定",
+	"admin.job_triggered":   "任务已开始运行",
+	"admin.webhook_created": "Webhook已创建",
+	"admin.webhook_updated": "Webhook已更新",
+	"admin.card_number_set": "借书证号已更新",
+
+	// 定时任务说明，key为job.加任务名
+	"job.overdue_scan":     "发送即将到期和逾期提醒，并标记已提醒的借阅",
+	"job.session_purge":    "清理已过期的登录会话",
+	"job.ldap_sync":        "同步目录服务中的用户",
+	"job.webhook_dispatch": "向登记的Webhook投递图书和借阅事件",
+
+	// 接口层错误
+	"error.invalid_request":     "请求参数格式错误",
+	"error.unauthenticated":     "未授权，请先登录",
+	"error.admin_required":      "权限不足，需要管理员权限",
+	"error.two_factor_required": "管理员需要启用并通过两步验证",
+	"error.csrf_token_invalid":  "CSRF令牌无效或缺失，请先获取令牌",
+	"error.not_found":           "接口不存在",
+	"error.service_unavailable": "请求处理超时，请稍后重试",
+	"error.internal_error":      "服务器内部错误",
+
+	// 业务错误，key为error.加小写的错误码
+	"error.user_not_found":             "用户不存在",
+	"error.user_exists":                "用户名已存在",
+	"error.invalid_password":           "密码错误",
+	"error.invalid_credentials":        "用户名或密码错误",
+	"error.book_not_found":             "未找到该图书",
+	"error.book_exists":                "图书已存在",
+	"error.out_of_stock":               "库存不足",
+	"error.borrow_limit_reached":       "借阅次数已达上限",
+	"error.record_not_found":           "未找到该借阅记录",
+	"error.already_returned":           "图书已归还",
+	"error.permission_denied":          "权限不足",
+	"error.validation_failed":          "无效的输入参数",
+	"error.email_exists":               "邮箱已被使用",
+	"error.card_number_exists":         "借书证号已被使用",
+	"error.invalid_reset_token":        "重置链接无效或已过期",
+	"error.too_many_attempts":          "登录尝试次数过多，请稍后再试",
+	"error.lockout_not_found":          "锁定记录不存在",
+	"error.two_factor_already_enabled": "两步验证已启用",
+	"error.two_factor_not_enabled":     "两步验证未启用",
+	"error.two_factor_not_setup":       "请先生成两步验证密钥",
+	"error.invalid_two_factor_code":    "验证码错误",
+	"error.oidc_login_failed":          "单点登录失败",
+	"error.user_disabled":              "账号已停用",
+	"error.username_conflict":          "用户名已被其他账号占用，请联系管理员",
+	"error.login_expired":              "登录已过期，请重新输入用户名和密码",
+	"error.notification_not_found":     "通知不存在",
+	"error.job_not_found":              "任务不存在",
+	"error.job_running":                "任务正在运行或刚刚运行过，请稍后再试",
+	"error.webhook_not_found":          "Webhook不存在",
+
+	// 字段校验，key为validation.加规则名
+	"validation.required":     "%s不能为空",
+	"validation.invalid":      "%s格式不正确",
+	"validation.type":         "%s的类型应为%s",
+	"validation.min_length":   "密码长度不能少于%d位",
+	"validation.max_length":   "密码长度不能超过%d个字节",
+	"validation.uppercase":    "密码必须包含大写字母",
+	"validation.lowercase":    "密码必须包含小写字母",
+	"validation.digit":        "密码必须包含数字",
+	"validation.symbol":       "密码必须包含特殊字符",
+	"validation.not_username": "密码不能与用户名相同",
+	"validation.common":       "密码过于常见，请更换",
+	"validation.length":       "用户名长度必须在%d到%d位之间",
+	"validation.format":       "用户名必须以字母开头，只能包含字母、数字、下划线、点和连字符",
+
+	// 邮件
+	"mail.password_reset.subject": "重置密码",
+	"mail.password_reset.body":    "%s，您好：\n\n请在%d分钟内打开以下链接重置密码：\n%s?token=%s\n\n如果这不是您本人的操作，请忽略此邮件。\n",
+
+	// 借阅通知，正文参数依次为用户姓名、书名、应还日期
+	"notification.loan_created.title":  "借书成功",
+	"notification.loan_created.body":   "%s，您好：您已借阅《%s》，请在%s前归还。",
+	"notification.loan_returned.title": "还书成功",
+	"notification.loan_returned.body":  "%s，您好：《%s》已归还（应还日期%s），感谢您的使用。",
+	"notification.due_soon.title":      "图书即将到期",
+	"notification.due_soon.body":       "%s，您好：您借阅的《%s》将于%s到期，请及时归还。",
+	"notification.overdue.title":       "图书已逾期",
+	"notification.overdue.body":        "%s，您好：您借阅的《%s》已于%s到期，请尽快归还。",
+}
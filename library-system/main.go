@@ -1,156 +1,578 @@
-package main
-
-import (
-	"fmt"
-	"library-system/handlers"
-	"library-system/middleware"
-	"library-system/models"
-	"library-system/repositories"
-	"library-system/services"
-	"log"
-	"os"
-
-	_ "library-system/docs"
-
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"github.com/gorilla/sessions"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-)
-
-// @title 图书管理系统 API
-// @version 1.0
-// @description hhahaaaahahahahaha
-
-// @host localhost:8080
-// @BasePath /api/v1
-
-// @securityDefinitions.apikey ApiKeyAuth
-// @in cookie
-// @name library-session
-// @description 用户登录后，Session Cookie会自动携带在请求中
-func main() {
-	dbHost := getEnv("MYSQL_HOST", "localhost")
-	dbUser := getEnv("MYSQL_USER", "root")
-	dbPassword := getEnv("MYSQL_PASSWORD", "")
-	dbName := getEnv("MYSQL_DBNAME", "library-system")
-	sessionSecret := getEnv("SESSION_SECRET", "SBSBSBSBSBSSBSBS")
-	serverPort := getEnv("SERVER_PORT", ":8080")
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:3306)/%s?charset=utf8mb4&parseTime=True&loc=Local", dbUser, dbPassword, dbHost, dbName)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatal("数据库连接失败:", err)
-	}
-
-	err = db.AutoMigrate(&models.User{})
-	if err != nil {
-		log.Fatal("数据库迁移失败:", err)
-	}
-	err = db.AutoMigrate(&models.Book{})
-	if err != nil {
-		log.Fatal("数据库迁移失败:", err)
-	}
-	err = db.AutoMigrate(&models.BorrowRecord{})
-	if err != nil {
-		log.Fatal("数据库迁移失败:", err)
-	}
-
-	// 初始化Session
-	sessionStore := sessions.NewCookieStore([]byte(sessionSecret))
-
-	sessionStore.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   24 * 60 * 60,
-		HttpOnly: true,
-		Secure:   false,
-	}
-
-	// 初始化各层组件
-	userRepo := repositories.NewUserRepository(db)
-	bookRepo := repositories.NewBookRepository(db)
-	authService := services.NewAuthService(userRepo)
-	bookService := services.NewBookService(bookRepo)
-	borrowService := services.NewBorrowService(db)
-	adminService := services.NewAdminService(db)
-	authHandler := handlers.NewAuthHandler(authService, sessionStore)
-	bookHandler := handlers.NewBookHandler(bookService)
-	borrowHandler := handlers.NewBorrowHandler(borrowService)
-	adminHandler := handlers.NewAdminHandler(adminService)
-
-	// 创建路由
-	router := gin.Default()
-
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		AllowCredentials: true,
-	}))
-
-	// Swagger
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	v1 := router.Group("/api/v1")
-	{
-		// 认证路由
-		auth := v1.Group("/auth")
-		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/logout", authHandler.Logout)
-		}
-
-		// 需要认证的路由
-		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware(sessionStore))
-		{
-			// 图书路由
-			books := protected.Group("/books")
-			{
-				books.GET("", bookHandler.GetAllBooks)                            // GET /api/v1/books
-				books.GET("/search", bookHandler.SearchBooksByKeyword)            // GET /api/v1/books/search?keyword=xxx
-				books.GET("/search/title", bookHandler.SearchBooksByTitleKeyword) // GET /api/v1/books/search/title?titlekeyword=xxx
-				books.GET("/search/author", bookHandler.SearchBooksByAuthor)      // GET /api/v1/books/search/author?author=xxx
-				books.GET("/:id", bookHandler.GetBookInfoByID)                    // GET /api/v1/books/1
-				books.GET("/title", bookHandler.GetBookInfoByTitle)               // GET /api/v1/books/title?title=具体的标题
-			}
-
-			// 借阅路由
-			borrow := protected.Group("/borrow")
-			{
-				borrow.POST("", borrowHandler.BorrowBook)                  // POST /api/v1/borrow
-				borrow.POST("/return", borrowHandler.ReturnBook)           // POST /api/v1/borrow/return
-				borrow.GET("/records", borrowHandler.GetUserBorrowRecords) // GET /api/v1/borrow/records
-			}
-
-			// 管理员路由
-			admin := protected.Group("/admin")
-			admin.Use(middleware.AdminMiddleware())
-			{
-				admin.POST("/books", adminHandler.AddBook)                     // POST /api/v1/admin/books
-				admin.PUT("/books", adminHandler.UpdateBook)                   // PUT /api/v1/admin/books
-				admin.DELETE("/books", adminHandler.DeleteBook)                // DELETE /api/v1/admin/books
-				admin.GET("/borrow-records", adminHandler.GetAllBorrowRecords) // GET /api/v1/admin/borrow-records
-			}
-		}
-	}
-
-	// 启动服务器
-	log.Println("服务器启动")
-	if err := router.Run(serverPort); err != nil {
-		log.Fatal("服务器启动失败:", err)
-	}
-}
-
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
-}
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"library-system/buildinfo"
+	"library-system/config"
+	"library-system/handlers"
+	"library-system/health"
+	"library-system/logging"
+	"library-system/mailer"
+	"library-system/metrics"
+	"library-system/middleware"
+	"library-system/models"
+	"library-system/repositories"
+	"library-system/services"
+	"library-system/tracing"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	_ "library-system/docs"
+
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+)
+
+// @title 图书管理系统 API
+// @version 1.0
+// @description hhahaaaahahahahaha
+
+// @host localhost:8080
+// @BasePath /api/v1
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in cookie
+// @name library-session
+// @description 用户登录后，Session Cookie会自动携带在请求中
+
+// @securityDefinitions.apikey CSRFToken
+// @in header
+// @name X-CSRF-Token
+// @description POST/PUT/DELETE请求需要携带 GET /auth/csrf 返回的令牌
+func main() {
+	// 加载配置: 默认值 < 配置文件 < 环境变量 < 命令行参数
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("配置加载失败", err)
+	}
+	gin.SetMode(cfg.Mode)
+
+	// 结构化日志
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("日志初始化失败", err)
+	}
+	slog.SetDefault(logger)
+	passwordPolicy := cfg.Password.PasswordPolicy()
+
+	// 收到SIGINT或SIGTERM时开始关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 链路追踪
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		Version:     buildinfo.Version,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("链路追踪初始化失败", err)
+	}
+
+	// 后台任务，关闭时等待其退出
+	var background sync.WaitGroup
+
+	// 就绪检查
+	checker := health.NewChecker()
+
+	db, err := gorm.Open(mysql.Open(cfg.Database.DSN()), &gorm.Config{
+		Logger: gormlogger.NewSlogLogger(logger, gormlogger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  gormlogger.Warn,
+			IgnoreRecordNotFoundError: true,
+		}),
+	})
+	if err != nil {
+		fatal("数据库连接失败", err)
+	}
+
+	// 每条SQL语句生成一个span，不记录参数以免泄露密码哈希等数据
+	if err := db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics(), gormtracing.WithoutQueryVariables())); err != nil {
+		fatal("链路追踪初始化失败", err)
+	}
+
+	// 连接池
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("数据库连接失败", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	if err := metrics.RegisterDBStats(sqlDB, cfg.Database.Name); err != nil {
+		fatal("指标注册失败", err)
+	}
+
+	err = db.AutoMigrate(&models.User{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.Book{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.BorrowRecord{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.Session{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.PasswordResetToken{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.LoginThrottle{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.RecoveryCode{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.Notification{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.NotificationPreference{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.JobLock{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.JobRun{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.OutboxEvent{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.Webhook{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.WebhookDelivery{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	err = db.AutoMigrate(&models.SchemaMigration{})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+
+	// 记录迁移版本，就绪检查据此判断数据库结构是否与代码一致
+	schemaRepo := repositories.NewSchemaMigrationRepository(db)
+	err = schemaRepo.Record(ctx, &models.SchemaMigration{Version: models.SchemaVersion, AppliedAt: time.Now()})
+	if err != nil {
+		fatal("数据库迁移失败", err)
+	}
+	checker.Add("database", sqlDB.PingContext)
+	checker.Add("migrations", health.SchemaCheck(schemaRepo.CurrentVersion, models.SchemaVersion))
+
+	// 初始化邮件发送，未配置SMTP时写入本地目录
+	var mailSender mailer.Mailer
+	if cfg.Mail.SMTPHost != "" {
+		mailSender = mailer.NewSMTPMailer(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUser, cfg.Mail.SMTPPassword, cfg.Mail.From)
+	} else {
+		mailSender, err = mailer.NewFileMailer(cfg.Mail.Dir, cfg.Mail.From)
+		if err != nil {
+			fatal("邮件目录初始化失败", err)
+		}
+	}
+
+	// 初始化Session，数据保存在数据库中以便服务端吊销
+	sessionRepo := repositories.NewSessionRepository(db)
+	sessionStore := middleware.NewDBStore(sessionRepo, []byte(cfg.Session.Secret))
+
+	sessionStore.Options = &sessions.Options{
+		Path:     "/",
+		MaxAge:   int(cfg.Session.MaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   cfg.Session.CookieSecure,
+		SameSite: cfg.Session.SameSite(),
+	}
+
+	// 初始化各层组件
+	userRepo := repositories.NewUserRepository(db)
+	bookRepo := repositories.NewBookRepository(db)
+	recordRepo := repositories.NewBorrowRecordRepository(db)
+	throttleRepo := repositories.NewLoginThrottleRepository(db)
+	tokenRepo := repositories.NewPasswordResetTokenRepository(db)
+	notificationRepo := repositories.NewNotificationRepository(db)
+	preferenceRepo := repositories.NewNotificationPreferenceRepository(db)
+	transactor := repositories.NewTransactor(db)
+
+	// 定时任务，多个实例通过数据库中的任务锁保证同一调度时间只运行一次
+	hostname, _ := os.Hostname()
+	scheduler := services.NewScheduler(repositories.NewJobLockRepository(db), repositories.NewJobRunRepository(db), fmt.Sprintf("%s:%d", hostname, os.Getpid()), cfg.Scheduler.LockTTL)
+	// 调度规则已通过配置校验
+	jobSchedule := func(spec string) services.Schedule {
+		schedule, _ := cfg.Scheduler.Jobs.Schedule(spec)
+		return schedule
+	}
+
+	loginGuard := services.NewLoginGuard(throttleRepo, services.DefaultLoginGuardPolicy())
+	authenticators := []services.Authenticator{services.NewLocalAuthenticator(userRepo)}
+
+	// 配置了目录服务时，本地找不到的用户再尝试LDAP认证
+	if cfg.LDAP.URL != "" {
+		ldapDirectory := services.NewLDAPDirectory(services.LDAPConfig{
+			URL:                cfg.LDAP.URL,
+			StartTLS:           cfg.LDAP.StartTLS,
+			InsecureSkipVerify: cfg.LDAP.InsecureSkipVerify,
+			BindDN:             cfg.LDAP.BindDN,
+			BindPassword:       cfg.LDAP.BindPassword,
+			BaseDN:             cfg.LDAP.BaseDN,
+			UserFilter:         cfg.LDAP.UserFilter,
+			SyncFilter:         cfg.LDAP.SyncFilter,
+			UsernameAttribute:  cfg.LDAP.UsernameAttribute,
+			EmailAttribute:     cfg.LDAP.EmailAttribute,
+			GroupAttribute:     cfg.LDAP.GroupAttribute,
+			AdminGroups:        cfg.LDAP.AdminGroups,
+		}, nil)
+		authenticators = append(authenticators, services.NewLDAPAuthenticator(ldapDirectory, userRepo))
+
+		// 定时同步目录用户
+		ldapSyncService := services.NewLDAPSyncService(transactor, ldapDirectory)
+		scheduler.Register(services.Job{
+			Name:     "ldap_sync",
+			Schedule: jobSchedule(cfg.Scheduler.Jobs.LDAPSync),
+			Run: func(ctx context.Context) (string, error) {
+				result, err := ldapSyncService.Sync(ctx)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("created=%d updated=%d deactivated=%d conflicts=%d", result.Created, result.Updated, result.Deactivated, len(result.Conflicts)), nil
+			},
+		})
+	}
+	authService := services.NewAuthService(userRepo, authenticators, loginGuard, passwordPolicy)
+	userService := services.NewUserService(transactor, userRepo, recordRepo, loginGuard, passwordPolicy, cfg.Loan.LoanPolicy())
+	passwordResetService := services.NewPasswordResetService(transactor, userRepo, tokenRepo, mailSender, passwordPolicy, cfg.PasswordReset.URL, cfg.PasswordReset.TokenTTL)
+	twoFactorService := services.NewTwoFactorService(transactor, userRepo, loginGuard, cfg.TwoFactor.Issuer)
+	bookService := services.NewBookService(bookRepo)
+	notificationService := services.NewNotificationService(transactor, userRepo, bookRepo, recordRepo, notificationRepo, preferenceRepo, map[string]services.Notifier{
+		models.ChannelEmail: services.NewMailNotifier(mailSender),
+		models.ChannelInApp: services.NewInboxNotifier(notificationRepo),
+	}, cfg.Notifications.NotificationPolicy())
+	scheduler.Register(services.Job{
+		Name:     "overdue_scan",
+		Schedule: jobSchedule(cfg.Scheduler.Jobs.OverdueScan),
+		Run: func(ctx context.Context) (string, error) {
+			result, err := notificationService.ScanDueDates(ctx)
+			return fmt.Sprintf("due_soon=%d overdue=%d", result.DueSoon, result.Overdue), err
+		},
+	})
+	webhookService := services.NewWebhookService(transactor, repositories.NewWebhookRepository(db), repositories.NewWebhookDeliveryRepository(db), repositories.NewOutboxRepository(db), cfg.Webhooks.WebhookPolicy())
+	scheduler.Register(services.Job{
+		Name:     "webhook_dispatch",
+		Schedule: jobSchedule(cfg.Scheduler.Jobs.WebhookDispatch),
+		Run: func(ctx context.Context) (string, error) {
+			result, err := webhookService.Dispatch(ctx)
+			return fmt.Sprintf("events=%d succeeded=%d retrying=%d failed=%d", result.Events, result.Succeeded, result.Retrying, result.Failed), err
+		},
+	})
+	housekeepingService := services.NewHousekeepingService(sessionRepo)
+	scheduler.Register(services.Job{
+		Name:     "session_purge",
+		Schedule: jobSchedule(cfg.Scheduler.Jobs.SessionPurge),
+		Run: func(ctx context.Context) (string, error) {
+			deleted, err := housekeepingService.PurgeExpiredSessions(ctx)
+			return fmt.Sprintf("deleted=%d", deleted), err
+		},
+	})
+	// 调度循环至少每分钟更新一次心跳
+	schedulerHeartbeat := health.NewHeartbeat(3 * time.Minute)
+	checker.Add("scheduler", schedulerHeartbeat.Check)
+	background.Go(func() { scheduler.Run(ctx, schedulerHeartbeat.Beat) })
+	// 借还书后推送给实时事件流的订阅者
+	eventBus := services.NewEventBus(cfg.Stream.History)
+	borrowService := services.NewBorrowService(transactor, recordRepo, cfg.Loan.LoanPolicy(), notificationService, eventBus)
+	if err := borrowService.RegisterMetrics(metrics.Registry); err != nil {
+		fatal("指标注册失败", err)
+	}
+	adminService := services.NewAdminService(transactor, recordRepo, throttleRepo, eventBus)
+	authHandler := handlers.NewAuthHandler(authService, sessionStore)
+	userHandler := handlers.NewUserHandler(userService, sessionStore)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, sessionStore)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	jobHandler := handlers.NewJobHandler(scheduler)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	streamHandler := handlers.NewStreamHandler(eventBus, bookService, cfg.Stream.Heartbeat)
+
+	// 配置了身份提供方时启用单点登录
+	var oidcHandler *handlers.OIDCHandler
+	if cfg.OIDC.IssuerURL != "" {
+		oidcService := services.NewOIDCService(userRepo, services.OIDCConfig{
+			IssuerURL:    cfg.OIDC.IssuerURL,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			GroupsClaim:  cfg.OIDC.GroupsClaim,
+			AdminGroups:  cfg.OIDC.AdminGroups,
+		}, nil)
+		oidcHandler = handlers.NewOIDCHandler(oidcService, sessionStore, cfg.OIDC.PostLoginURL)
+	}
+	bookHandler := handlers.NewBookHandler(bookService)
+	borrowHandler := handlers.NewBorrowHandler(borrowService)
+	adminHandler := handlers.NewAdminHandler(adminService)
+	healthHandler := handlers.NewHealthHandler(checker)
+
+	// 创建路由
+	router := gin.New()
+	// 链路追踪放在最前，之后记录的日志都带有trace ID
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/healthz" && r.URL.Path != "/readyz" && r.URL.Path != "/metrics"
+	})))
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.LocaleMiddleware())
+	router.Use(middleware.RequestLogger("/healthz", "/readyz", "/metrics"))
+	router.Use(middleware.Recovery())
+
+	// 只信任显式配置的反向代理转发的客户端IP，登录限流依赖真实IP
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("反向代理配置错误", err)
+	}
+
+	// 只允许白名单内的前端跨域携带Cookie访问，未配置时不允许跨域
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		router.Use(cors.New(cors.Config{
+			AllowOrigins:     cfg.CORS.AllowedOrigins,
+			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", middleware.CSRFHeader},
+			AllowCredentials: true,
+		}))
+	}
+
+	// 请求指标
+	router.Use(metrics.Middleware())
+	// 放在最后，日志和指标记录的是错误转换后的状态码
+	router.Use(middleware.ErrorHandler())
+	router.NoRoute(middleware.NoRoute)
+
+	// 探针和指标
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/version", healthHandler.Version)
+
+	// Swagger
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// 各路由组的数据库截止时间，未配置的使用默认值
+	deadlines := cfg.Database.Deadlines
+
+	v1 := router.Group("/api/v1")
+	v1.Use(middleware.CSRFMiddleware(sessionStore))
+	{
+		// 认证路由
+		auth := v1.Group("/auth")
+		auth.Use(middleware.DeadlineMiddleware(cmp.Or(deadlines.Auth, deadlines.Default)))
+		{
+			auth.GET("/csrf", authHandler.CSRFToken) // GET /api/v1/auth/csrf
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/2fa", twoFactorHandler.VerifyLogin) // POST /api/v1/auth/login/2fa
+			if oidcHandler != nil {
+				auth.GET("/oidc/login", oidcHandler.Login)       // GET /api/v1/auth/oidc/login
+				auth.GET("/oidc/callback", oidcHandler.Callback) // GET /api/v1/auth/oidc/callback
+			}
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/password-reset", passwordResetHandler.RequestReset)         // POST /api/v1/auth/password-reset
+			auth.POST("/password-reset/confirm", passwordResetHandler.ConfirmReset) // POST /api/v1/auth/password-reset/confirm
+		}
+
+		// 需要认证的路由
+		protected := v1.Group("")
+		protected.Use(middleware.AuthMiddleware(sessionStore))
+		{
+			// 个人中心路由
+			me := protected.Group("/me")
+			me.Use(middleware.DeadlineMiddleware(cmp.Or(deadlines.Me, deadlines.Default)))
+			{
+				me.GET("", userHandler.GetProfile)                // GET /api/v1/me
+				me.PUT("", userHandler.UpdateProfile)             // PUT /api/v1/me
+				me.POST("/password", userHandler.ChangePassword)  // POST /api/v1/me/password
+				me.GET("/summary", userHandler.GetAccountSummary) // GET /api/v1/me/summary
+
+				// 两步验证
+				me.POST("/2fa/setup", twoFactorHandler.Setup)                            // POST /api/v1/me/2fa/setup
+				me.GET("/2fa/qrcode", twoFactorHandler.QRCode)                           // GET /api/v1/me/2fa/qrcode
+				me.POST("/2fa/enable", twoFactorHandler.Enable)                          // POST /api/v1/me/2fa/enable
+				me.POST("/2fa/disable", twoFactorHandler.Disable)                        // POST /api/v1/me/2fa/disable
+				me.POST("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes) // POST /api/v1/me/2fa/recovery-codes
+
+				// 通知
+				me.GET("/notifications", notificationHandler.GetNotifications)            // GET /api/v1/me/notifications?unread=true&limit=50
+				me.POST("/notifications/read-all", notificationHandler.MarkAllRead)       // POST /api/v1/me/notifications/read-all
+				me.POST("/notifications/:id/read", notificationHandler.MarkRead)          // POST /api/v1/me/notifications/:id/read
+				me.POST("/notifications/:id/unread", notificationHandler.MarkUnread)      // POST /api/v1/me/notifications/:id/unread
+				me.GET("/notification-preferences", notificationHandler.GetPreference)    // GET /api/v1/me/notification-preferences
+				me.PUT("/notification-preferences", notificationHandler.UpdatePreference) // PUT /api/v1/me/notification-preferences
+			}
+
+			// 图书路由
+			books := protected.Group("/books")
+			books.Use(middleware.DeadlineMiddleware(cmp.Or(deadlines.Books, deadlines.Default)))
+			{
+				books.GET("", bookHandler.GetAllBooks)                            // GET /api/v1/books
+				books.GET("/search", bookHandler.SearchBooksByKeyword)            // GET /api/v1/books/search?keyword=xxx
+				books.GET("/search/title", bookHandler.SearchBooksByTitleKeyword) // GET /api/v1/books/search/title?titlekeyword=xxx
+				books.GET("/search/author", bookHandler.SearchBooksByAuthor)      // GET /api/v1/books/search/author?author=xxx
+				books.GET("/:id", bookHandler.GetBookInfoByID)                    // GET /api/v1/books/1
+				books.GET("/title", bookHandler.GetBookInfoByTitle)               // GET /api/v1/books/title?title=具体的标题
+			}
+
+			// 借阅路由
+			borrow := protected.Group("/borrow")
+			borrow.Use(middleware.DeadlineMiddleware(cmp.Or(deadlines.Borrow, deadlines.Default)))
+			{
+				borrow.POST("", borrowHandler.BorrowBook)                  // POST /api/v1/borrow
+				borrow.POST("/return", borrowHandler.ReturnBook)           // POST /api/v1/borrow/return
+				borrow.GET("/records", borrowHandler.GetUserBorrowRecords) // GET /api/v1/borrow/records
+			}
+
+			// 实时事件流，长连接不设置数据库截止时间
+			events := protected.Group("/events")
+			{
+				events.GET("/availability", streamHandler.Availability)                                                           // GET /api/v1/events/availability?book_id=1&book_id=2
+				events.GET("/circulation", middleware.AdminMiddleware(cfg.TwoFactor.RequireForAdmins), streamHandler.Circulation) // GET /api/v1/events/circulation
+			}
+
+			// 管理员路由
+			admin := protected.Group("/admin")
+			admin.Use(middleware.AdminMiddleware(cfg.TwoFactor.RequireForAdmins))
+			admin.Use(middleware.DeadlineMiddleware(cmp.Or(deadlines.Admin, deadlines.Default)))
+			{
+				admin.POST("/books", adminHandler.AddBook)                     // POST /api/v1/admin/books
+				admin.PUT("/books", adminHandler.UpdateBook)                   // PUT /api/v1/admin/books
+				admin.DELETE("/books", adminHandler.DeleteBook)                // DELETE /api/v1/admin/books
+				admin.GET("/borrow-records", adminHandler.GetAllBorrowRecords) // GET /api/v1/admin/borrow-records
+				admin.GET("/lockouts", adminHandler.GetLockouts)               // GET /api/v1/admin/lockouts
+				admin.DELETE("/lockouts", adminHandler.ClearLockout)           // DELETE /api/v1/admin/lockouts
+
+				// 服务台借还书
+				admin.POST("/checkouts", borrowHandler.CheckOut)               // POST /api/v1/admin/checkouts
+				admin.POST("/checkins", borrowHandler.CheckIn)                 // POST /api/v1/admin/checkins
+				admin.PUT("/users/:id/card-number", userHandler.SetCardNumber) // PUT /api/v1/admin/users/:id/card-number
+
+				// 定时任务
+				admin.GET("/jobs", jobHandler.GetJobs)               // GET /api/v1/admin/jobs
+				admin.GET("/jobs/:name/runs", jobHandler.GetJobRuns) // GET /api/v1/admin/jobs/:name/runs?limit=20
+				admin.POST("/jobs/:name/run", jobHandler.TriggerJob) // POST /api/v1/admin/jobs/:name/run
+
+				// Webhook
+				admin.GET("/webhooks", webhookHandler.GetWebhooks)                  // GET /api/v1/admin/webhooks
+				admin.POST("/webhooks", webhookHandler.CreateWebhook)               // POST /api/v1/admin/webhooks
+				admin.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)            // PUT /api/v1/admin/webhooks/:id
+				admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)         // DELETE /api/v1/admin/webhooks/:id
+				admin.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries) // GET /api/v1/admin/webhooks/:id/deliveries?limit=20
+			}
+		}
+	}
+
+	// 启动服务器
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	// Shutdown不会中断长连接，先结束事件流
+	server.RegisterOnShutdown(eventBus.Close)
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("服务器启动", slog.String("addr", cfg.Server.Addr), slog.String("version", buildinfo.Version))
+		var err error
+		if cfg.Server.TLSCertFile != "" {
+			err = server.ListenAndServeTLS(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal("服务器启动失败", err)
+	case <-ctx.Done():
+	}
+	// 再次收到信号时直接退出
+	stop()
+	checker.Drain()
+
+	// 停止接收新连接，等待进行中的请求完成
+	slog.Info("正在关闭服务器")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("等待请求完成超时", slog.Any("error", err))
+	}
+
+	// 等待后台任务和异步邮件
+	if err := waitAll(shutdownCtx, background.Wait, scheduler.Stop, passwordResetService.Wait, notificationService.Wait); err != nil {
+		slog.Warn("等待后台任务退出超时", slog.Any("error", err))
+	}
+
+	if err := sqlDB.Close(); err != nil {
+		slog.Error("关闭数据库连接失败", slog.Any("error", err))
+	}
+
+	// 导出剩余的span
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("导出链路追踪数据失败", slog.Any("error", err))
+	}
+	slog.Info("服务器已关闭")
+}
+
+// fatal 记录错误并退出
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
+
+// waitAll 依次执行等待函数，超过ctx期限时不再等待
+func waitAll(ctx context.Context, waits ...func()) error {
+	done := make(chan struct{})
+	go func() {
+		for _, wait := range waits {
+			wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"library-system/services"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// 校验错误中使用JSON字段名，与请求体保持一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// bindingError 将请求体的binding校验错误和字段类型错误转换为 services.ValidationError，
+// 其他错误如JSON格式错误报告为 ErrInvalidRequest
+func bindingError(err error) error {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]services.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			// 除required外的binding规则统一报告为格式不正确
+			rule := "invalid"
+			if fe.Tag() == "required" {
+				rule = "required"
+			}
+			fields = append(fields, services.NewFieldError(fe.Field(), rule, fe.Field()))
+		}
+		return &services.ValidationError{Fields: fields}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		field := services.NewFieldError(typeErr.Field, "type", typeErr.Field, typeErr.Type.Kind().String())
+		return &services.ValidationError{Fields: []services.FieldError{field}}
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+}
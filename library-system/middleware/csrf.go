@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// CSRFHeader 客户端提交CSRF令牌使用的请求头
+const CSRFHeader = "X-CSRF-Token"
+
+// Session中保存CSRF令牌的键
+const csrfSessionKey = "csrfToken"
+
+// CSRFToken 返回Session中的CSRF令牌，不存在时生成新的令牌，调用方负责保存Session
+func CSRFToken(session *sessions.Session) (string, error) {
+	if token, ok := session.Values[csrfSessionKey].(string); ok && token != "" {
+		return token, nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate csrf token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	session.Values[csrfSessionKey] = token
+
+	return token, nil
+}
+
+// CSRFMiddleware
+// 同步令牌模式: POST/PUT/DELETE等请求必须在X-CSRF-Token头中携带与Session中一致的令牌，
+// 令牌通过 GET /auth/csrf 获取，跨站页面无法读取
+func CSRFMiddleware(sessionStore sessions.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 安全方法不修改状态，无需校验
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		// 获取Session
+		session, err := sessionStore.Get(c.Request, "library-session")
+		if err != nil {
+			abortWithError(c, sessionError(err))
+			return
+		}
+
+		// 比较令牌
+		expected, _ := session.Values[csrfSessionKey].(string)
+		actual := c.GetHeader(CSRFHeader)
+		if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+			abortWithError(c, ErrCSRFTokenInvalid)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/i18n"
+	"library-system/services"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 接口层的错误，与业务错误一起登记在errorStatus中
+var (
+	ErrInvalidRequest     = &services.Error{Code: "INVALID_REQUEST", Message: "请求格式错误"}
+	ErrUnauthenticated    = &services.Error{Code: "UNAUTHENTICATED", Message: "未授权，请先登录"}
+	ErrAdminRequired      = &services.Error{Code: "ADMIN_REQUIRED", Message: "权限不足，需要管理员权限"}
+	ErrTwoFactorRequired  = &services.Error{Code: "TWO_FACTOR_REQUIRED", Message: "管理员需要启用并通过两步验证"}
+	ErrCSRFTokenInvalid   = &services.Error{Code: "CSRF_TOKEN_INVALID", Message: "CSRF令牌无效或缺失"}
+	ErrRouteNotFound      = &services.Error{Code: "NOT_FOUND", Message: "接口不存在"}
+	ErrServiceUnavailable = &services.Error{Code: "SERVICE_UNAVAILABLE", Message: "请求处理超时"}
+	ErrInternal           = &services.Error{Code: "INTERNAL_ERROR", Message: "服务器内部错误"}
+)
+
+// errorStatus 错误对应的HTTP状态码
+// 错误码取自 services.Error，返回给客户端的提示取消息目录中的 error.<小写错误码>，
+// 新增的业务错误需要在这里登记，未登记的错误按内部错误处理
+var errorStatus = map[error]int{
+	ErrInvalidRequest:     http.StatusBadRequest,
+	ErrUnauthenticated:    http.StatusUnauthorized,
+	ErrAdminRequired:      http.StatusForbidden,
+	ErrTwoFactorRequired:  http.StatusForbidden,
+	ErrCSRFTokenInvalid:   http.StatusForbidden,
+	ErrRouteNotFound:      http.StatusNotFound,
+	ErrServiceUnavailable: http.StatusServiceUnavailable,
+	ErrInternal:           http.StatusInternalServerError,
+
+	services.ErrInvalidInput:         http.StatusBadRequest,
+	services.ErrInvalidResetToken:    http.StatusBadRequest,
+	services.ErrInvalidCredentials:   http.StatusUnauthorized,
+	services.ErrLoginExpired:         http.StatusUnauthorized,
+	services.ErrOIDCLoginFailed:      http.StatusUnauthorized,
+	services.ErrInvalidPassword:      http.StatusForbidden,
+	services.ErrPermissionDenied:     http.StatusForbidden,
+	services.ErrUserDisabled:         http.StatusForbidden,
+	services.ErrUsernameConflict:     http.StatusConflict,
+	services.ErrUserNotFound:         http.StatusNotFound,
+	services.ErrBookNotFound:         http.StatusNotFound,
+	services.ErrRecordNotFound:       http.StatusNotFound,
+	services.ErrNotificationNotFound: http.StatusNotFound,
+	services.ErrJobNotFound:          http.StatusNotFound,
+	services.ErrWebhookNotFound:      http.StatusNotFound,
+	services.ErrLockoutNotFound:      http.StatusNotFound,
+	services.ErrTwoFactorNotSetup:    http.StatusNotFound,
+	services.ErrUserExists:           http.StatusConflict,
+	services.ErrBookExists:           http.StatusConflict,
+	services.ErrEmailExists:          http.StatusConflict,
+	services.ErrCardNumberExists:     http.StatusConflict,
+	services.ErrStockNotEnough:       http.StatusConflict,
+	services.ErrBorrowLimit:          http.StatusConflict,
+	services.ErrAlreadyReturned:      http.StatusConflict,
+	services.ErrTwoFactorEnabled:     http.StatusConflict,
+	services.ErrTwoFactorNotEnabled:  http.StatusConflict,
+	services.ErrJobRunning:           http.StatusConflict,
+	// 已登录用户提交的验证码错误属于参数错误，不使用401以免客户端误判为登录失效
+	services.ErrInvalidTwoFactorCode: http.StatusBadRequest,
+	services.ErrTooManyAttempts:      http.StatusTooManyRequests,
+}
+
+// errorKey 错误码对应的消息目录key
+func errorKey(code string) string {
+	return "error." + strings.ToLower(code)
+}
+
+// resolveError 返回错误链上第一个已登记的业务错误
+// 没有时超过截止时间的错误按服务不可用处理，客户端可以稍后重试，其他错误按内部错误处理
+func resolveError(err error) *services.Error {
+	var e *services.Error
+	if errors.As(err, &e) {
+		if _, ok := errorStatus[e]; ok {
+			return e
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrServiceUnavailable
+	}
+	return ErrInternal
+}
+
+// ErrorHandler 将中间件和处理函数通过 c.Error 报告的最后一个错误转换为问题详情
+// 类型为 gin.ErrorTypeBind 的错误是请求体解析失败，返回400；已经写出响应时不再处理
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+		err := last.Err
+		if last.IsType(gin.ErrorTypeBind) {
+			err = bindingError(err)
+		}
+		abortWithError(c, err)
+	}
+}
+
+// problemFor 生成err对应的问题详情
+func problemFor(c *gin.Context, err error) Problem {
+	e := resolveError(err)
+	status := errorStatus[e]
+	problem := NewProblem(c, status, e.Code, errorKey(e.Code))
+
+	// 校验错误总是返回未通过的规则，便于客户端定位字段
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		locale := i18n.FromContext(c.Request.Context())
+		for _, field := range validationErr.Fields {
+			problem.Errors = append(problem.Errors, field.Localize(locale))
+		}
+	}
+
+	// 生产环境隐藏详细错误
+	if gin.Mode() != gin.ReleaseMode && err != error(e) {
+		problem.Error = err.Error()
+	}
+	return problem
+}
+
+// abortWithError 以问题详情返回err并中止后续处理
+// 服务端错误总是记录到日志，日志使用默认语言
+func abortWithError(c *gin.Context, err error) {
+	problem := problemFor(c, err)
+	if problem.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(c.Request.Context(), resolveError(err).Message, slog.Int("status", problem.Status), slog.Any("error", err))
+	} else {
+		slog.DebugContext(c.Request.Context(), resolveError(err).Message, slog.Int("status", problem.Status), slog.Any("error", err))
+	}
+
+	// 被锁定时告知客户端需要等待的时间
+	var lockedErr *services.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		c.Header("Retry-After", retryAfterSeconds(lockedErr.RetryAfter))
+	}
+
+	AbortWithProblem(c, problem)
+}
+
+// retryAfterSeconds 向上取整的秒数，用于Retry-After头
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// sessionError 获取Session失败，按内部错误返回
+func sessionError(err error) error {
+	return fmt.Errorf("failed to get session: %w", err)
+}
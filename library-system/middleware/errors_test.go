@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"library-system/i18n"
+	"library-system/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorStatusKeys(t *testing.T) {
+	for err := range errorStatus {
+		e, ok := err.(*services.Error)
+		if !ok {
+			t.Errorf("%v: registered error is %T, want *services.Error", err, err)
+			continue
+		}
+		for _, locale := range i18n.Locales() {
+			if _, ok := i18n.Lookup(locale, errorKey(e.Code)); !ok {
+				t.Errorf("%s: key %q missing in %s", e.Code, errorKey(e.Code), locale)
+			}
+		}
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type bindRequest struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		{
+			name:       "业务错误",
+			handler:    func(c *gin.Context) { c.Error(services.ErrBookNotFound) },
+			wantStatus: http.StatusNotFound,
+			wantCode:   "BOOK_NOT_FOUND",
+		},
+		{
+			name:       "包装的业务错误",
+			handler:    func(c *gin.Context) { c.Error(fmt.Errorf("failed to borrow book: %w", services.ErrStockNotEnough)) },
+			wantStatus: http.StatusConflict,
+			wantCode:   "OUT_OF_STOCK",
+		},
+		{
+			name: "校验错误",
+			handler: func(c *gin.Context) {
+				c.Error(&services.ValidationError{Fields: []services.FieldError{services.NewFieldError("email", "invalid", "email")}})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_FAILED",
+			wantField:  "email",
+		},
+		{
+			name: "binding错误",
+			body: `{}`,
+			handler: func(c *gin.Context) {
+				var req bindRequest
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.Error(err).SetType(gin.ErrorTypeBind)
+				}
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_FAILED",
+			wantField:  "name",
+		},
+		{
+			name: "请求体格式错误",
+			body: `{`,
+			handler: func(c *gin.Context) {
+				var req bindRequest
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.Error(err).SetType(gin.ErrorTypeBind)
+				}
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "INVALID_REQUEST",
+		},
+		{
+			name:       "超过截止时间",
+			handler:    func(c *gin.Context) { c.Error(fmt.Errorf("failed to get books: %w", context.DeadlineExceeded)) },
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "SERVICE_UNAVAILABLE",
+		},
+		{
+			name:       "未登记的错误",
+			handler:    func(c *gin.Context) { c.Error(errors.New("boom")) },
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "INTERNAL_ERROR",
+		},
+		{
+			name:       "panic",
+			handler:    func(c *gin.Context) { panic("boom") },
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "INTERNAL_ERROR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Recovery(), ErrorHandler())
+			router.POST("/", tt.handler)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, ProblemContentType)
+			}
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decode problem: %v", err)
+			}
+			if problem.Status != tt.wantStatus || problem.Code != tt.wantCode {
+				t.Errorf("problem = %d %s, want %d %s", problem.Status, problem.Code, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantField != "" && (len(problem.Errors) == 0 || problem.Errors[0].Field != tt.wantField) {
+				t.Errorf("errors = %+v, want field %q", problem.Errors, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.POST("/", func(c *gin.Context) {
+		c.Error(&services.LoginLockedError{RetryAfter: 1500 * time.Millisecond})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want %q", got, "2")
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"library-system/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleMiddleware 按Accept-Language协商响应语言并写入请求context
+// 已登录用户设置了语言偏好时，由AuthMiddleware使用偏好覆盖
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		setLocale(c, i18n.Negotiate(c.GetHeader("Accept-Language")))
+		c.Next()
+	}
+}
+
+func setLocale(c *gin.Context, locale string) {
+	c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+	c.Header("Content-Language", locale)
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"library-system/i18n"
+	"library-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType RFC 7807 问题详情的媒体类型，所有错误响应都使用该类型
+const ProblemContentType = "application/problem+json"
+
+// Problem RFC 7807 问题详情
+// code是稳定的机器可读错误码，客户端应据此判断错误类型，不要匹配detail文本
+type Problem struct {
+	Type      string                `json:"type" example:"about:blank"`
+	Title     string                `json:"title" example:"Not Found"`
+	Status    int                   `json:"status" example:"404"`
+	Detail    string                `json:"detail,omitempty" example:"未找到该图书"`
+	Instance  string                `json:"instance,omitempty" example:"/api/v1/borrow"`
+	Code      string                `json:"code" example:"BOOK_NOT_FOUND"`
+	Errors    []services.FieldError `json:"errors,omitempty"`
+	RequestID string                `json:"request_id,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	// 非release模式下返回原始错误，便于调试
+	Error string `json:"error,omitempty"`
+}
+
+// NewProblem 生成当前请求的问题详情，detail为消息目录的key，按请求语言翻译
+func NewProblem(c *gin.Context, status int, code, key string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    i18n.T(i18n.FromContext(c.Request.Context()), key),
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: c.GetString("requestID"),
+	}
+}
+
+// AbortWithProblem 以 application/problem+json 返回问题详情并中止后续处理
+func AbortWithProblem(c *gin.Context, problem Problem) {
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// NoRoute 未匹配到路由时同样返回问题详情
+func NoRoute(c *gin.Context) {
+	abortWithError(c, ErrRouteNotFound)
+}
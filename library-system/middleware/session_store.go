@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"gorm.io/gorm"
+)
+
+// DBStore 将Session数据保存在数据库中，Cookie中只保存签名后的Session ID，
+// 因此服务端可以随时吊销某个用户的全部Session
+type DBStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	repo    repositories.SessionRepository
+}
+
+func NewDBStore(repo repositories.SessionRepository, keyPairs ...[]byte) *DBStore {
+	return &DBStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+		repo: repo,
+	}
+}
+
+// Get
+func (s *DBStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New
+func (s *DBStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	// Cookie不存在或签名无效时返回新Session
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	if err := securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...); err != nil {
+		return session, nil
+	}
+
+	record, err := s.repo.GetByID(r.Context(), session.ID)
+	if err != nil {
+		session.ID = ""
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return session, nil
+		}
+		return session, fmt.Errorf("failed to get session by ID: %w", err)
+	}
+
+	// 已过期的Session视为不存在
+	if record.ExpiresAt.Before(time.Now()) {
+		session.ID = ""
+		return session, nil
+	}
+
+	if err := securecookie.DecodeMulti(name, record.Data, &session.Values, s.Codecs...); err != nil {
+		session.ID = ""
+		return session, nil
+	}
+
+	session.IsNew = false
+	return session, nil
+}
+
+// Save
+func (s *DBStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	// MaxAge小于0表示删除Session
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.repo.Delete(r.Context(), session.ID); err != nil {
+				return fmt.Errorf("failed to delete session: %w", err)
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+	if err != nil {
+		return fmt.Errorf("failed to encode session values: %w", err)
+	}
+
+	userID, _ := session.Values["userID"].(int)
+	record := &models.Session{
+		ID:        session.ID,
+		UserID:    userID,
+		Data:      data,
+		ExpiresAt: time.Now().Add(time.Duration(session.Options.MaxAge) * time.Second),
+	}
+	if err := s.repo.Save(r.Context(), record); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return fmt.Errorf("failed to encode session ID: %w", err)
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// RenewSession 认证状态改变时调用：删除旧的Session记录，之后保存时生成新的Session ID，并更换CSRF令牌。
+// 防止会话固定攻击，即攻击者预先植入的Session在受害者登录后被攻击者继续使用
+func RenewSession(r *http.Request, session *sessions.Session) error {
+	if store, ok := session.Store().(*DBStore); ok && session.ID != "" {
+		if err := store.repo.Delete(r.Context(), session.ID); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+	session.ID = ""
+	session.IsNew = true
+
+	delete(session.Values, csrfSessionKey)
+	_, err := CSRFToken(session)
+	return err
+}
+
+// newSessionID 生成随机Session ID
+func newSessionID() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(buf), "="), nil
+}
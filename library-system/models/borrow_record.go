@@ -1,14 +1,20 @@
-package models
-
-import (
-	"time"
-)
-
-type BorrowRecord struct {
-	ID         int        `gorm:"primaryKey" json:"id" example:"1"`
-	UserID     int        `json:"user_id" example:"1"`
-	BookID     int        `json:"book_id" example:"1"`
-	BorrowedAt time.Time  `json:"borrowed_at" example:"2024-01-15T10:30:00Z"`
-	DueDate    time.Time  `json:"due_date" example:"2024-02-15T10:30:00Z"`
-	ReturnedAt *time.Time `json:"returned_at,omitempty" example:"2024-01-18T09:15:00Z"`
-}
+package models
+
+import (
+	"time"
+)
+
+type BorrowRecord struct {
+	ID         int        `gorm:"primaryKey" json:"id" example:"1"`
+	UserID     int        `json:"user_id" example:"1"`
+	BookID     int        `json:"book_id" example:"1"`
+	BorrowedAt time.Time  `json:"borrowed_at" example:"2024-01-15T10:30:00Z"`
+	DueDate    time.Time  `json:"due_date" example:"2024-02-15T10:30:00Z"`
+	ReturnedAt *time.Time `json:"returned_at,omitempty" example:"2024-01-18T09:15:00Z"`
+	// 已发送即将到期和逾期提醒的时间，定时扫描据此避免重复提醒
+	DueSoonNotifiedAt *time.Time `json:"-"`
+	OverdueNotifiedAt *time.Time `json:"-"`
+	// 在服务台经办借出和归还的馆员，读者自助借还时为空
+	CheckedOutBy *int `json:"checked_out_by,omitempty" example:"2"`
+	CheckedInBy  *int `json:"checked_in_by,omitempty" example:"2"`
+}
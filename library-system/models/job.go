@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// 任务运行状态
+const (
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// 任务触发方式
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// JobLock 定时任务的锁，多个实例中只有取得锁的实例运行该任务
+type JobLock struct {
+	Name  string `gorm:"type:varchar(64);primaryKey"`
+	Owner string `gorm:"type:varchar(191);not null"`
+	// 运行中的实例定期续期，实例异常退出后锁在此时间后失效
+	LockedUntil time.Time `gorm:"not null"`
+	// 最近一次运行对应的调度时间，同一调度时间只运行一次
+	ScheduledAt time.Time `gorm:"not null"`
+}
+
+// JobRun 任务的一次运行
+type JobRun struct {
+	ID       int    `gorm:"primaryKey" json:"id" example:"1"`
+	Job      string `gorm:"type:varchar(64);index;not null" json:"job" example:"session_purge"`
+	Trigger  string `gorm:"type:varchar(16);not null" json:"trigger" example:"schedule"`
+	Status   string `gorm:"type:varchar(16);not null" json:"status" example:"succeeded"`
+	Instance string `gorm:"type:varchar(191);not null" json:"instance" example:"library-1:4821"`
+	// 运行结果摘要，例如处理的记录数
+	Result     string     `gorm:"type:varchar(255)" json:"result,omitempty" example:"deleted=12"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  time.Time  `gorm:"not null" json:"started_at" example:"2024-01-15T03:30:00Z"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" example:"2024-01-15T03:30:01Z"`
+	DurationMs int64      `json:"duration_ms" example:"842"`
+}
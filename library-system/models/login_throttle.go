@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginThrottle 记录某个账号或IP的连续登录失败次数
+type LoginThrottle struct {
+	Key          string     `gorm:"type:varchar(191);primaryKey" json:"key" example:"user:lemon"`
+	Failures     int        `gorm:"not null" json:"failures" example:"6"`
+	LastFailedAt time.Time  `gorm:"not null" json:"last_failed_at" example:"2024-01-15T10:30:00Z"`
+	LockedUntil  *time.Time `gorm:"index" json:"locked_until,omitempty" example:"2024-01-15T10:32:00Z"`
+}
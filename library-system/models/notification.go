@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// 通知类型，也用于消息目录中模板的key: notification.<类型>.title/body
+const (
+	NotificationLoanCreated  = "loan_created"
+	NotificationLoanReturned = "loan_returned"
+	NotificationDueSoon      = "due_soon"
+	NotificationOverdue      = "overdue"
+)
+
+// 通知渠道
+const (
+	ChannelEmail = "email"
+	ChannelInApp = "in_app"
+)
+
+// Notification 站内信
+type Notification struct {
+	ID     int    `gorm:"primaryKey" json:"id" example:"1"`
+	UserID int    `gorm:"index;not null" json:"-"`
+	Type   string `gorm:"type:varchar(32);not null" json:"type" example:"due_soon"`
+	Title  string `gorm:"type:varchar(255);not null" json:"title" example:"图书即将到期"`
+	Body   string `gorm:"type:text;not null" json:"body" example:"您借阅的《三体》将于2024-02-15到期，请按时归还。"`
+	// 关联的借阅记录
+	RecordID  *int       `json:"record_id,omitempty" example:"1"`
+	ReadAt    *time.Time `json:"read_at,omitempty" example:"2024-01-18T09:15:00Z"`
+	CreatedAt time.Time  `json:"created_at" example:"2024-01-15T10:30:00Z"`
+}
+
+// NotificationPreference 用户的通知渠道偏好，没有记录时全部渠道开启
+type NotificationPreference struct {
+	UserID int  `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Email  bool `gorm:"not null" json:"email" example:"true"`
+	InApp  bool `gorm:"not null" json:"in_app" example:"true"`
+}
+
+// Enabled 是否通过channel投递
+func (p *NotificationPreference) Enabled(channel string) bool {
+	switch channel {
+	case ChannelEmail:
+		return p.Email
+	case ChannelInApp:
+		return p.InApp
+	}
+	return false
+}
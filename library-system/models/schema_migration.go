@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SchemaVersion 当前代码期望的数据库结构版本，新增或修改模型时递增
+const SchemaVersion = 6
+
+// SchemaMigration 记录已执行的数据库迁移版本
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version" example:"1"`
+	AppliedAt time.Time `gorm:"not null" json:"applied_at" example:"2024-01-15T10:30:00Z"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID        string    `gorm:"type:varchar(64);primaryKey"`
+	UserID    int       `gorm:"index"`
+	Data      string    `gorm:"type:text;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
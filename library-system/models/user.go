@@ -1,8 +1,39 @@
-package models
-
-type User struct {
-	ID       int    `gorm:"primaryKey" json:"id" example:"123"`
-	Name     string `gorm:"type:varchar(255);uniqueIndex;not null" json:"name" example:"lemon"`
-	Password string `gorm:"not null" json:"-"`
-	Role     string `gorm:"not null" json:"role" example:"admin"`
-}
+package models
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// 用户的认证来源
+const (
+	AuthProviderLocal = "local"
+	AuthProviderOIDC  = "oidc"
+	AuthProviderLDAP  = "ldap"
+)
+
+type User struct {
+	ID           int    `gorm:"primaryKey" json:"id" example:"123"`
+	Name         string `gorm:"type:varchar(255);uniqueIndex;not null" json:"name" example:"lemon"`
+	Password     string `gorm:"not null" json:"-"`
+	Role         string `gorm:"not null" json:"role" example:"admin"`
+	Email        string `gorm:"type:varchar(255);index" json:"email" example:"lemon@example.com"`
+	Phone        string `gorm:"type:varchar(32)" json:"phone" example:"13800000000"`
+	TOTPSecret   string `gorm:"column:totp_secret;type:varchar(64)" json:"-"`
+	TOTPEnabled  bool   `gorm:"column:totp_enabled;not null;default:false" json:"totp_enabled" example:"false"`
+	TOTPLastStep int64  `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+	// 外部身份提供方的用户标识，本地用户为空
+	AuthProvider string  `gorm:"type:varchar(32);not null;default:local;uniqueIndex:idx_users_external" json:"auth_provider" example:"local"`
+	ExternalID   *string `gorm:"type:varchar(255);uniqueIndex:idx_users_external" json:"-"`
+	// 停用的账号无法登录，目录同步时不再存在的用户会被停用
+	Active bool `gorm:"not null;default:true" json:"active" example:"true"`
+	// 界面语言偏好，为空时按请求的Accept-Language协商
+	Locale string `gorm:"type:varchar(16);not null;default:''" json:"locale" example:"en-US"`
+	// 借书证号，由管理员登记，馆员在服务台可凭证号为读者借书
+	CardNumber *string `gorm:"type:varchar(32);uniqueIndex" json:"card_number,omitempty" example:"L2024000123"`
+}
+
+// IsAdmin 是否拥有管理员权限
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
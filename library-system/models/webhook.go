@@ -0,0 +1,72 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// 领域事件类型
+const (
+	EventBookCreated  = "book.created"
+	EventBookUpdated  = "book.updated"
+	EventBookDeleted  = "book.deleted"
+	EventLoanCreated  = "loan.created"
+	EventLoanReturned = "loan.returned"
+	EventLoanOverdue  = "loan.overdue"
+)
+
+// EventTypes 可以订阅的全部事件类型
+var EventTypes = []string{EventBookCreated, EventBookUpdated, EventBookDeleted, EventLoanCreated, EventLoanReturned, EventLoanOverdue}
+
+// 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// OutboxEvent 与业务数据在同一事务中写入的领域事件，由后台任务分发给订阅的Webhook
+type OutboxEvent struct {
+	ID   int    `gorm:"primaryKey"`
+	Type string `gorm:"type:varchar(32);not null"`
+	// 事件数据，JSON
+	Payload   string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	// 已为订阅的Webhook创建投递记录的时间，为空表示尚未分发
+	DispatchedAt *time.Time `gorm:"index"`
+}
+
+// Webhook 管理员登记的事件接收地址
+type Webhook struct {
+	ID  int    `gorm:"primaryKey" json:"id" example:"1"`
+	URL string `gorm:"type:varchar(2048);not null" json:"url" example:"https://portal.example.com/hooks/library"`
+	// 订阅的事件类型
+	Events []string `gorm:"type:text;serializer:json;not null" json:"events" example:"loan.created,loan.returned"`
+	// 签名密钥，只在创建时返回一次
+	Secret    string    `gorm:"type:varchar(64);not null" json:"-"`
+	Active    bool      `gorm:"not null" json:"active" example:"true"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+}
+
+// Subscribes 是否订阅了eventType
+func (w *Webhook) Subscribes(eventType string) bool {
+	return slices.Contains(w.Events, eventType)
+}
+
+// WebhookDelivery 一个事件向一个Webhook的投递，失败时按退避时间重试
+type WebhookDelivery struct {
+	ID        int    `gorm:"primaryKey" json:"id" example:"1"`
+	WebhookID int    `gorm:"index;not null" json:"webhook_id" example:"1"`
+	EventID   int    `gorm:"not null" json:"event_id" example:"42"`
+	EventType string `gorm:"type:varchar(32);not null" json:"event_type" example:"loan.created"`
+	Status    string `gorm:"type:varchar(16);index:idx_delivery_due,priority:1;not null" json:"status" example:"succeeded"`
+	Attempts  int    `gorm:"not null" json:"attempts" example:"1"`
+	// 下次尝试的时间，只对pending有意义
+	NextAttemptAt time.Time `gorm:"index:idx_delivery_due,priority:2;not null" json:"next_attempt_at" example:"2024-01-15T10:31:00Z"`
+	// 最近一次尝试的响应状态码，请求未完成时为0
+	ResponseStatus int        `json:"response_status,omitempty" example:"200"`
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" example:"2024-01-15T10:31:00Z"`
+}
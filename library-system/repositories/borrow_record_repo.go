@@ -1,70 +1,126 @@
-package repositories
-
-import (
-	"library-system/models"
-
-	"gorm.io/gorm"
-)
-
-type BorrowRecordRepository interface {
-	Create(record *models.BorrowRecord) error
-	Update(record *models.BorrowRecord) error
-	GetByID(id int) (*models.BorrowRecord, error)
-	GetByUserID(userID int) ([]*models.BorrowRecord, error)
-	GetByBookID(bookID int) ([]*models.BorrowRecord, error)
-	CountActiveBorrowsByUserID(userID int) (int64, error)
-	GetAll() ([]*models.BorrowRecord, error)
-}
-
-type borrowRecordRepoImpl struct {
-	db *gorm.DB
-}
-
-func NewBorrowRecordRepository(db *gorm.DB) BorrowRecordRepository {
-	return &borrowRecordRepoImpl{db: db}
-}
-
-// Create
-func (r *borrowRecordRepoImpl) Create(record *models.BorrowRecord) error {
-	return r.db.Create(record).Error
-}
-
-// Update
-func (r *borrowRecordRepoImpl) Update(record *models.BorrowRecord) error {
-	return r.db.Save(record).Error
-}
-
-// GetByID
-func (r *borrowRecordRepoImpl) GetByID(id int) (*models.BorrowRecord, error) {
-	var record models.BorrowRecord
-	result := r.db.First(&record, id)
-	return &record, result.Error
-}
-
-// GetByUserID
-func (r *borrowRecordRepoImpl) GetByUserID(userid int) ([]*models.BorrowRecord, error) {
-	var records []*models.BorrowRecord
-	result := r.db.Where("user_id = ?", userid).Find(&records)
-	return records, result.Error
-}
-
-// GetByBookID
-func (r *borrowRecordRepoImpl) GetByBookID(bookid int) ([]*models.BorrowRecord, error) {
-	var records []*models.BorrowRecord
-	result := r.db.Where("book_id = ?", bookid).Find(&records)
-	return records, result.Error
-}
-
-// CountActiveBorrowsByUserID
-func (r *borrowRecordRepoImpl) CountActiveBorrowsByUserID(userID int) (int64, error) {
-	var count int64
-	result := r.db.Model(&models.BorrowRecord{}).Where("user_id = ? AND returned_at IS NULL", userID).Count(&count)
-	return count, result.Error
-}
-
-// GetAll
-func (r *borrowRecordRepoImpl) GetAll() ([]*models.BorrowRecord, error) {
-	var records []*models.BorrowRecord
-	result := r.db.Find(&records)
-	return records, result.Error
-}
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type BorrowRecordRepository interface {
+	Create(ctx context.Context, record *models.BorrowRecord) error
+	Update(ctx context.Context, record *models.BorrowRecord) error
+	GetByID(ctx context.Context, id int) (*models.BorrowRecord, error)
+	GetByUserID(ctx context.Context, userID int) ([]*models.BorrowRecord, error)
+	GetByBookID(ctx context.Context, bookID int) ([]*models.BorrowRecord, error)
+	CountActiveBorrowsByUserID(ctx context.Context, userID int) (int64, error)
+	GetActiveByUserID(ctx context.Context, userID int) ([]*models.BorrowRecord, error)
+	CountOverdue(ctx context.Context, now time.Time) (int64, error)
+	GetAll(ctx context.Context) ([]*models.BorrowRecord, error)
+	GetUnnotifiedDueSoon(ctx context.Context, now, until time.Time) ([]*models.BorrowRecord, error)
+	GetUnnotifiedOverdue(ctx context.Context, now time.Time) ([]*models.BorrowRecord, error)
+	MarkDueSoonNotified(ctx context.Context, id int, at time.Time) (bool, error)
+	MarkOverdueNotified(ctx context.Context, id int, at time.Time) (bool, error)
+}
+
+type borrowRecordRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewBorrowRecordRepository(db *gorm.DB) BorrowRecordRepository {
+	return &borrowRecordRepoImpl{db: db}
+}
+
+// Create
+func (r *borrowRecordRepoImpl) Create(ctx context.Context, record *models.BorrowRecord) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+// Update
+func (r *borrowRecordRepoImpl) Update(ctx context.Context, record *models.BorrowRecord) error {
+	return r.db.WithContext(ctx).Save(record).Error
+}
+
+// GetByID
+func (r *borrowRecordRepoImpl) GetByID(ctx context.Context, id int) (*models.BorrowRecord, error) {
+	var record models.BorrowRecord
+	result := r.db.WithContext(ctx).First(&record, id)
+	return &record, result.Error
+}
+
+// GetByUserID
+func (r *borrowRecordRepoImpl) GetByUserID(ctx context.Context, userid int) ([]*models.BorrowRecord, error) {
+	var records []*models.BorrowRecord
+	result := r.db.WithContext(ctx).Where("user_id = ?", userid).Find(&records)
+	return records, result.Error
+}
+
+// GetByBookID
+func (r *borrowRecordRepoImpl) GetByBookID(ctx context.Context, bookid int) ([]*models.BorrowRecord, error) {
+	var records []*models.BorrowRecord
+	result := r.db.WithContext(ctx).Where("book_id = ?", bookid).Find(&records)
+	return records, result.Error
+}
+
+// CountActiveBorrowsByUserID
+func (r *borrowRecordRepoImpl) CountActiveBorrowsByUserID(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).Where("user_id = ? AND returned_at IS NULL", userID).Count(&count)
+	return count, result.Error
+}
+
+// GetActiveByUserID
+func (r *borrowRecordRepoImpl) GetActiveByUserID(ctx context.Context, userID int) ([]*models.BorrowRecord, error) {
+	var records []*models.BorrowRecord
+	result := r.db.WithContext(ctx).Where("user_id = ? AND returned_at IS NULL", userID).Order("due_date").Find(&records)
+	return records, result.Error
+}
+
+// CountOverdue
+func (r *borrowRecordRepoImpl) CountOverdue(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).Where("returned_at IS NULL AND due_date < ?", now).Count(&count)
+	return count, result.Error
+}
+
+// GetAll
+func (r *borrowRecordRepoImpl) GetAll(ctx context.Context) ([]*models.BorrowRecord, error) {
+	var records []*models.BorrowRecord
+	result := r.db.WithContext(ctx).Find(&records)
+	return records, result.Error
+}
+
+// GetUnnotifiedDueSoon 未归还、应还日期在[now, until)之间且未发送过即将到期提醒的记录
+func (r *borrowRecordRepoImpl) GetUnnotifiedDueSoon(ctx context.Context, now, until time.Time) ([]*models.BorrowRecord, error) {
+	var records []*models.BorrowRecord
+	result := r.db.WithContext(ctx).
+		Where("returned_at IS NULL AND due_soon_notified_at IS NULL AND due_date >= ? AND due_date < ?", now, until).
+		Order("due_date").Find(&records)
+	return records, result.Error
+}
+
+// GetUnnotifiedOverdue 逾期未还且未发送过逾期提醒的记录
+func (r *borrowRecordRepoImpl) GetUnnotifiedOverdue(ctx context.Context, now time.Time) ([]*models.BorrowRecord, error) {
+	var records []*models.BorrowRecord
+	result := r.db.WithContext(ctx).
+		Where("returned_at IS NULL AND overdue_notified_at IS NULL AND due_date < ?", now).
+		Order("due_date").Find(&records)
+	return records, result.Error
+}
+
+// MarkDueSoonNotified 只更新提醒时间，不覆盖同时发生的还书；已被标记时返回false
+func (r *borrowRecordRepoImpl) MarkDueSoonNotified(ctx context.Context, id int, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("id = ? AND due_soon_notified_at IS NULL", id).
+		Update("due_soon_notified_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkOverdueNotified 同 MarkDueSoonNotified
+func (r *borrowRecordRepoImpl) MarkOverdueNotified(ctx context.Context, id int, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("id = ? AND overdue_notified_at IS NULL", id).
+		Update("overdue_notified_at", at)
+	return result.RowsAffected > 0, result.Error
+}
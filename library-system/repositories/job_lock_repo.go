@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobLockRepository interface {
+	TryAcquire(ctx context.Context, lock *models.JobLock, now time.Time) (bool, error)
+	Extend(ctx context.Context, name, owner string, until time.Time) (bool, error)
+	Release(ctx context.Context, name, owner string, at time.Time) error
+}
+
+type jobLockRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewJobLockRepository(db *gorm.DB) JobLockRepository {
+	return &jobLockRepoImpl{db: db}
+}
+
+// TryAcquire 锁不存在，或已过期且上次运行的调度时间早于lock.ScheduledAt时取得锁
+// 依赖单条语句的原子性，多个实例同时调用时只有一个返回true
+func (r *jobLockRepoImpl) TryAcquire(ctx context.Context, lock *models.JobLock, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(lock)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.RowsAffected > 0, result.Error
+	}
+
+	result = r.db.WithContext(ctx).Model(&models.JobLock{}).
+		Where("name = ? AND locked_until <= ? AND scheduled_at < ?", lock.Name, now, lock.ScheduledAt).
+		Updates(map[string]any{"owner": lock.Owner, "locked_until": lock.LockedUntil, "scheduled_at": lock.ScheduledAt})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Extend 续期，锁已被其他实例取得时返回false
+func (r *jobLockRepoImpl) Extend(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.JobLock{}).
+		Where("name = ? AND owner = ?", name, owner).
+		Update("locked_until", until)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Release 将锁的有效期设为at，保留调度时间
+func (r *jobLockRepoImpl) Release(ctx context.Context, name, owner string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.JobLock{}).
+		Where("name = ? AND owner = ?", name, owner).
+		Update("locked_until", at).Error
+}
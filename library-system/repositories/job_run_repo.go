@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+
+	"gorm.io/gorm"
+)
+
+type JobRunRepository interface {
+	Create(ctx context.Context, run *models.JobRun) error
+	Update(ctx context.Context, run *models.JobRun) error
+	GetByJob(ctx context.Context, job string, limit int) ([]*models.JobRun, error)
+}
+
+type jobRunRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewJobRunRepository(db *gorm.DB) JobRunRepository {
+	return &jobRunRepoImpl{db: db}
+}
+
+// Create
+func (r *jobRunRepoImpl) Create(ctx context.Context, run *models.JobRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// Update
+func (r *jobRunRepoImpl) Update(ctx context.Context, run *models.JobRun) error {
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+// GetByJob 最新的在前，最多返回limit条
+func (r *jobRunRepoImpl) GetByJob(ctx context.Context, job string, limit int) ([]*models.JobRun, error) {
+	var runs []*models.JobRun
+	result := r.db.WithContext(ctx).Where("job = ?", job).Order("id DESC").Limit(limit).Find(&runs)
+	return runs, result.Error
+}
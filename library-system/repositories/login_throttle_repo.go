@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepository interface {
+	GetByKeys(ctx context.Context, keys []string) ([]*models.LoginThrottle, error)
+	GetLocked(ctx context.Context, now time.Time) ([]*models.LoginThrottle, error)
+	Save(ctx context.Context, throttle *models.LoginThrottle) error
+	Increment(ctx context.Context, key string, now, resetBefore time.Time) (*models.LoginThrottle, error)
+	ExtendLock(ctx context.Context, key string, until time.Time) error
+	Delete(ctx context.Context, key string) (int64, error)
+}
+
+type loginThrottleRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) LoginThrottleRepository {
+	return &loginThrottleRepoImpl{db: db}
+}
+
+// GetByKeys
+func (r *loginThrottleRepoImpl) GetByKeys(ctx context.Context, keys []string) ([]*models.LoginThrottle, error) {
+	var throttles []*models.LoginThrottle
+	result := r.db.WithContext(ctx).Where("`key` IN ?", keys).Find(&throttles)
+	return throttles, result.Error
+}
+
+// GetLocked
+func (r *loginThrottleRepoImpl) GetLocked(ctx context.Context, now time.Time) ([]*models.LoginThrottle, error) {
+	var throttles []*models.LoginThrottle
+	result := r.db.WithContext(ctx).Where("locked_until > ?", now).Order("locked_until DESC").Find(&throttles)
+	return throttles, result.Error
+}
+
+// Save
+func (r *loginThrottleRepoImpl) Save(ctx context.Context, throttle *models.LoginThrottle) error {
+	return r.db.WithContext(ctx).Save(throttle).Error
+}
+
+// Increment 原子地增加失败次数并返回更新后的记录，上次失败早于resetBefore时重新计数并解除锁定
+// 并发的失败登录不会互相覆盖计数
+func (r *loginThrottleRepoImpl) Increment(ctx context.Context, key string, now, resetBefore time.Time) (*models.LoginThrottle, error) {
+	db := r.db.WithContext(ctx)
+	// MySQL按顺序执行赋值，last_failed_at必须最后更新
+	err := db.Clauses(clause.OnConflict{DoUpdates: clause.Set{
+		{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("IF(last_failed_at < ?, 1, failures + 1)", resetBefore)},
+		{Column: clause.Column{Name: "locked_until"}, Value: gorm.Expr("IF(last_failed_at < ?, NULL, locked_until)", resetBefore)},
+		{Column: clause.Column{Name: "last_failed_at"}, Value: now},
+	}}).Create(&models.LoginThrottle{Key: key, Failures: 1, LastFailedAt: now}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var throttle models.LoginThrottle
+	result := db.First(&throttle, "`key` = ?", key)
+	return &throttle, result.Error
+}
+
+// ExtendLock 锁定到until，已有更晚的锁定时不变
+func (r *loginThrottleRepoImpl) ExtendLock(ctx context.Context, key string, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.LoginThrottle{}).
+		Where("`key` = ? AND (locked_until IS NULL OR locked_until < ?)", key, until).
+		Update("locked_until", until).Error
+}
+
+// Delete
+func (r *loginThrottleRepoImpl) Delete(ctx context.Context, key string) (int64, error) {
+	result := r.db.WithContext(ctx).Delete(&models.LoginThrottle{}, "`key` = ?", key)
+	return result.RowsAffected, result.Error
+}
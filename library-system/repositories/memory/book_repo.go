@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+
+	"gorm.io/gorm"
+)
+
+type bookRepo struct {
+	db db
+}
+
+// GetAll
+func (r *bookRepo) GetAll(ctx context.Context) (books []*models.Book, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		books = find(&t.books, func(*models.Book) bool { return true })
+		return nil
+	})
+	return books, err
+}
+
+// GetByID
+func (r *bookRepo) GetByID(ctx context.Context, id int) (book *models.Book, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		book, err = t.books.get(id)
+		return err
+	})
+	return book, err
+}
+
+// GetByTitle
+func (r *bookRepo) GetByTitle(ctx context.Context, title string) (book *models.Book, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		book, err = first(&t.books, func(b *models.Book) bool { return equalFold(b.Title, title) })
+		return err
+	})
+	return book, err
+}
+
+// Create
+func (r *bookRepo) Create(ctx context.Context, book *models.Book) error {
+	return r.db.do(ctx, func(t *tables) error {
+		if book.ID == 0 {
+			book.ID = t.books.nextID()
+		}
+		return r.save(t, book)
+	})
+}
+
+// Update
+func (r *bookRepo) Update(ctx context.Context, book *models.Book) error {
+	return r.db.do(ctx, func(t *tables) error {
+		return r.save(t, book)
+	})
+}
+
+// Delete
+func (r *bookRepo) Delete(ctx context.Context, book *models.Book) error {
+	return r.db.do(ctx, func(t *tables) error {
+		delete(t.books.rows, book.ID)
+		return nil
+	})
+}
+
+// SearchByKeyword
+func (r *bookRepo) SearchByKeyword(ctx context.Context, keyword string) (books []*models.Book, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		books = find(&t.books, func(b *models.Book) bool {
+			return containsFold(b.Title, keyword) || containsFold(b.Author, keyword)
+		})
+		return nil
+	})
+	return books, err
+}
+
+// SearchByTitleKeyword
+func (r *bookRepo) SearchByTitleKeyword(ctx context.Context, titlekeyword string) (books []*models.Book, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		books = find(&t.books, func(b *models.Book) bool { return containsFold(b.Title, titlekeyword) })
+		return nil
+	})
+	return books, err
+}
+
+// SearchByAuthor
+func (r *bookRepo) SearchByAuthor(ctx context.Context, author string) (books []*models.Book, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		books = find(&t.books, func(b *models.Book) bool { return equalFold(b.Author, author) })
+		return nil
+	})
+	return books, err
+}
+
+// save 书名唯一
+func (r *bookRepo) save(t *tables, book *models.Book) error {
+	for id, existing := range t.books.rows {
+		if id != book.ID && equalFold(existing.Title, book.Title) {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	t.books.rows[book.ID] = *book
+	return nil
+}
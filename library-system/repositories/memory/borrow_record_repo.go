@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"slices"
+	"time"
+)
+
+type borrowRecordRepo struct {
+	db db
+}
+
+// Create
+func (r *borrowRecordRepo) Create(ctx context.Context, record *models.BorrowRecord) error {
+	return r.db.do(ctx, func(t *tables) error {
+		if record.ID == 0 {
+			record.ID = t.records.nextID()
+		}
+		t.records.rows[record.ID] = *record
+		return nil
+	})
+}
+
+// Update
+func (r *borrowRecordRepo) Update(ctx context.Context, record *models.BorrowRecord) error {
+	return r.db.do(ctx, func(t *tables) error {
+		t.records.rows[record.ID] = *record
+		return nil
+	})
+}
+
+// GetByID
+func (r *borrowRecordRepo) GetByID(ctx context.Context, id int) (record *models.BorrowRecord, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		record, err = t.records.get(id)
+		return err
+	})
+	return record, err
+}
+
+// GetByUserID
+func (r *borrowRecordRepo) GetByUserID(ctx context.Context, userID int) ([]*models.BorrowRecord, error) {
+	return r.find(ctx, func(rec *models.BorrowRecord) bool { return rec.UserID == userID })
+}
+
+// GetByBookID
+func (r *borrowRecordRepo) GetByBookID(ctx context.Context, bookID int) ([]*models.BorrowRecord, error) {
+	return r.find(ctx, func(rec *models.BorrowRecord) bool { return rec.BookID == bookID })
+}
+
+// CountActiveBorrowsByUserID
+func (r *borrowRecordRepo) CountActiveBorrowsByUserID(ctx context.Context, userID int) (int64, error) {
+	records, err := r.GetActiveByUserID(ctx, userID)
+	return int64(len(records)), err
+}
+
+// GetActiveByUserID 按应还日期排序
+func (r *borrowRecordRepo) GetActiveByUserID(ctx context.Context, userID int) ([]*models.BorrowRecord, error) {
+	records, err := r.find(ctx, func(rec *models.BorrowRecord) bool { return rec.UserID == userID && rec.ReturnedAt == nil })
+	slices.SortStableFunc(records, func(a, b *models.BorrowRecord) int { return a.DueDate.Compare(b.DueDate) })
+	return records, err
+}
+
+// CountOverdue
+func (r *borrowRecordRepo) CountOverdue(ctx context.Context, now time.Time) (int64, error) {
+	records, err := r.find(ctx, func(rec *models.BorrowRecord) bool { return rec.ReturnedAt == nil && rec.DueDate.Before(now) })
+	return int64(len(records)), err
+}
+
+// GetAll
+func (r *borrowRecordRepo) GetAll(ctx context.Context) ([]*models.BorrowRecord, error) {
+	return r.find(ctx, func(*models.BorrowRecord) bool { return true })
+}
+
+// GetUnnotifiedDueSoon 按应还日期排序
+func (r *borrowRecordRepo) GetUnnotifiedDueSoon(ctx context.Context, now, until time.Time) ([]*models.BorrowRecord, error) {
+	records, err := r.find(ctx, func(rec *models.BorrowRecord) bool {
+		return rec.ReturnedAt == nil && rec.DueSoonNotifiedAt == nil && !rec.DueDate.Before(now) && rec.DueDate.Before(until)
+	})
+	slices.SortStableFunc(records, func(a, b *models.BorrowRecord) int { return a.DueDate.Compare(b.DueDate) })
+	return records, err
+}
+
+// GetUnnotifiedOverdue 按应还日期排序
+func (r *borrowRecordRepo) GetUnnotifiedOverdue(ctx context.Context, now time.Time) ([]*models.BorrowRecord, error) {
+	records, err := r.find(ctx, func(rec *models.BorrowRecord) bool {
+		return rec.ReturnedAt == nil && rec.OverdueNotifiedAt == nil && rec.DueDate.Before(now)
+	})
+	slices.SortStableFunc(records, func(a, b *models.BorrowRecord) int { return a.DueDate.Compare(b.DueDate) })
+	return records, err
+}
+
+// MarkDueSoonNotified 已被标记或记录不存在时返回false
+func (r *borrowRecordRepo) MarkDueSoonNotified(ctx context.Context, id int, at time.Time) (marked bool, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		rec, ok := t.records.rows[id]
+		if ok && rec.DueSoonNotifiedAt == nil {
+			rec.DueSoonNotifiedAt = &at
+			t.records.rows[id] = rec
+			marked = true
+		}
+		return nil
+	})
+	return marked, err
+}
+
+// MarkOverdueNotified 已被标记或记录不存在时返回false
+func (r *borrowRecordRepo) MarkOverdueNotified(ctx context.Context, id int, at time.Time) (marked bool, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		rec, ok := t.records.rows[id]
+		if ok && rec.OverdueNotifiedAt == nil {
+			rec.OverdueNotifiedAt = &at
+			t.records.rows[id] = rec
+			marked = true
+		}
+		return nil
+	})
+	return marked, err
+}
+
+// find
+func (r *borrowRecordRepo) find(ctx context.Context, match func(rec *models.BorrowRecord) bool) (records []*models.BorrowRecord, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		records = find(&t.records, match)
+		return nil
+	})
+	return records, err
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+)
+
+type jobLockRepo struct {
+	db db
+}
+
+// TryAcquire
+func (r *jobLockRepo) TryAcquire(ctx context.Context, lock *models.JobLock, now time.Time) (acquired bool, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		old, ok := t.locks.rows[lock.Name]
+		if ok && (old.LockedUntil.After(now) || !old.ScheduledAt.Before(lock.ScheduledAt)) {
+			return nil
+		}
+		t.locks.rows[lock.Name] = *lock
+		acquired = true
+		return nil
+	})
+	return acquired, err
+}
+
+// Extend
+func (r *jobLockRepo) Extend(ctx context.Context, name, owner string, until time.Time) (extended bool, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		lock, ok := t.locks.rows[name]
+		if ok && lock.Owner == owner {
+			lock.LockedUntil = until
+			t.locks.rows[name] = lock
+			extended = true
+		}
+		return nil
+	})
+	return extended, err
+}
+
+// Release
+func (r *jobLockRepo) Release(ctx context.Context, name, owner string, at time.Time) error {
+	_, err := r.Extend(ctx, name, owner, at)
+	return err
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"slices"
+)
+
+type jobRunRepo struct {
+	db db
+}
+
+// Create
+func (r *jobRunRepo) Create(ctx context.Context, run *models.JobRun) error {
+	return r.db.do(ctx, func(t *tables) error {
+		run.ID = t.runs.nextID()
+		t.runs.rows[run.ID] = *run
+		return nil
+	})
+}
+
+// Update
+func (r *jobRunRepo) Update(ctx context.Context, run *models.JobRun) error {
+	return r.db.do(ctx, func(t *tables) error {
+		t.runs.rows[run.ID] = *run
+		return nil
+	})
+}
+
+// GetByJob 最新的在前，最多返回limit条
+func (r *jobRunRepo) GetByJob(ctx context.Context, job string, limit int) (runs []*models.JobRun, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		runs = find(&t.runs, func(run *models.JobRun) bool { return run.Job == job })
+		return nil
+	})
+	slices.Reverse(runs)
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, err
+}
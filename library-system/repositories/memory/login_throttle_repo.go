@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"slices"
+	"time"
+)
+
+type loginThrottleRepo struct {
+	db db
+}
+
+// GetByKeys
+func (r *loginThrottleRepo) GetByKeys(ctx context.Context, keys []string) (throttles []*models.LoginThrottle, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		throttles = find(&t.throttles, func(lt *models.LoginThrottle) bool { return slices.Contains(keys, lt.Key) })
+		return nil
+	})
+	return throttles, err
+}
+
+// GetLocked 按解锁时间倒序
+func (r *loginThrottleRepo) GetLocked(ctx context.Context, now time.Time) (throttles []*models.LoginThrottle, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		throttles = find(&t.throttles, func(lt *models.LoginThrottle) bool { return lt.LockedUntil != nil && lt.LockedUntil.After(now) })
+		return nil
+	})
+	slices.SortStableFunc(throttles, func(a, b *models.LoginThrottle) int { return b.LockedUntil.Compare(*a.LockedUntil) })
+	return throttles, err
+}
+
+// Save
+func (r *loginThrottleRepo) Save(ctx context.Context, throttle *models.LoginThrottle) error {
+	return r.db.do(ctx, func(t *tables) error {
+		t.throttles.rows[throttle.Key] = *throttle
+		return nil
+	})
+}
+
+// Increment
+func (r *loginThrottleRepo) Increment(ctx context.Context, key string, now, resetBefore time.Time) (throttle *models.LoginThrottle, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		lt, ok := t.throttles.rows[key]
+		if !ok || lt.LastFailedAt.Before(resetBefore) {
+			lt = models.LoginThrottle{Key: key}
+		}
+		lt.Failures++
+		lt.LastFailedAt = now
+		t.throttles.rows[key] = lt
+		throttle = &lt
+		return nil
+	})
+	return throttle, err
+}
+
+// ExtendLock
+func (r *loginThrottleRepo) ExtendLock(ctx context.Context, key string, until time.Time) error {
+	return r.db.do(ctx, func(t *tables) error {
+		lt, ok := t.throttles.rows[key]
+		if !ok || (lt.LockedUntil != nil && !lt.LockedUntil.Before(until)) {
+			return nil
+		}
+		lt.LockedUntil = &until
+		t.throttles.rows[key] = lt
+		return nil
+	})
+}
+
+// Delete
+func (r *loginThrottleRepo) Delete(ctx context.Context, key string) (affected int64, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		if _, ok := t.throttles.rows[key]; ok {
+			delete(t.throttles.rows, key)
+			affected = 1
+		}
+		return nil
+	})
+	return affected, err
+}
@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+)
+
+type notificationPreferenceRepo struct {
+	db db
+}
+
+// GetByUserID
+func (r *notificationPreferenceRepo) GetByUserID(ctx context.Context, userID int) (preference *models.NotificationPreference, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		preference, err = t.prefs.get(userID)
+		return err
+	})
+	return preference, err
+}
+
+// Save 不存在时创建
+func (r *notificationPreferenceRepo) Save(ctx context.Context, preference *models.NotificationPreference) error {
+	return r.db.do(ctx, func(t *tables) error {
+		t.prefs.rows[preference.UserID] = *preference
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"slices"
+	"time"
+)
+
+type notificationRepo struct {
+	db db
+}
+
+// Create
+func (r *notificationRepo) Create(ctx context.Context, notification *models.Notification) error {
+	return r.db.do(ctx, func(t *tables) error {
+		notification.ID = t.notices.nextID()
+		notification.CreatedAt = time.Now()
+		t.notices.rows[notification.ID] = *notification
+		return nil
+	})
+}
+
+// Update
+func (r *notificationRepo) Update(ctx context.Context, notification *models.Notification) error {
+	return r.db.do(ctx, func(t *tables) error {
+		t.notices.rows[notification.ID] = *notification
+		return nil
+	})
+}
+
+// GetByID
+func (r *notificationRepo) GetByID(ctx context.Context, id int) (notification *models.Notification, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		notification, err = t.notices.get(id)
+		return err
+	})
+	return notification, err
+}
+
+// GetByUserID 最新的在前，最多返回limit条
+func (r *notificationRepo) GetByUserID(ctx context.Context, userID int, unreadOnly bool, limit int) (notifications []*models.Notification, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		notifications = find(&t.notices, func(n *models.Notification) bool {
+			return n.UserID == userID && (!unreadOnly || n.ReadAt == nil)
+		})
+		return nil
+	})
+	slices.Reverse(notifications)
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, err
+}
+
+// CountUnreadByUserID
+func (r *notificationRepo) CountUnreadByUserID(ctx context.Context, userID int) (count int64, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		count = int64(len(find(&t.notices, func(n *models.Notification) bool { return n.UserID == userID && n.ReadAt == nil })))
+		return nil
+	})
+	return count, err
+}
+
+// MarkAllRead 将用户所有未读通知标记为已读
+func (r *notificationRepo) MarkAllRead(ctx context.Context, userID int, at time.Time) error {
+	return r.db.do(ctx, func(t *tables) error {
+		for id, n := range t.notices.rows {
+			if n.UserID == userID && n.ReadAt == nil {
+				n.ReadAt = &at
+				t.notices.rows[id] = n
+			}
+		}
+		return nil
+	})
+}
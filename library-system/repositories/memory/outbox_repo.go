@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+)
+
+type outboxRepo struct {
+	db db
+}
+
+// Create
+func (r *outboxRepo) Create(ctx context.Context, event *models.OutboxEvent) error {
+	return r.db.do(ctx, func(t *tables) error {
+		event.ID = t.events.nextID()
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now()
+		}
+		t.events.rows[event.ID] = *event
+		return nil
+	})
+}
+
+// GetByID
+func (r *outboxRepo) GetByID(ctx context.Context, id int) (event *models.OutboxEvent, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		event, err = t.events.get(id)
+		return err
+	})
+	return event, err
+}
+
+// GetUndispatched 按写入顺序，最多返回limit条
+func (r *outboxRepo) GetUndispatched(ctx context.Context, limit int) (events []*models.OutboxEvent, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		events = find(&t.events, func(e *models.OutboxEvent) bool { return e.DispatchedAt == nil })
+		return nil
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, err
+}
+
+// MarkDispatched
+func (r *outboxRepo) MarkDispatched(ctx context.Context, id int, at time.Time) error {
+	return r.db.do(ctx, func(t *tables) error {
+		if event, ok := t.events.rows[id]; ok {
+			event.DispatchedAt = &at
+			t.events.rows[id] = event
+		}
+		return nil
+	})
+}
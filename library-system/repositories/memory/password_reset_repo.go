@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type passwordResetTokenRepo struct {
+	db db
+}
+
+// Create
+func (r *passwordResetTokenRepo) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	return r.db.do(ctx, func(t *tables) error {
+		for _, existing := range t.tokens.rows {
+			if existing.TokenHash == token.TokenHash {
+				return gorm.ErrDuplicatedKey
+			}
+		}
+		token.ID = t.tokens.nextID()
+		token.CreatedAt = time.Now()
+		t.tokens.rows[token.ID] = *token
+		return nil
+	})
+}
+
+// GetByTokenHash
+func (r *passwordResetTokenRepo) GetByTokenHash(ctx context.Context, tokenHash string) (token *models.PasswordResetToken, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		token, err = first(&t.tokens, func(tk *models.PasswordResetToken) bool { return tk.TokenHash == tokenHash })
+		return err
+	})
+	return token, err
+}
+
+// InvalidateByUserID 将用户所有未使用的令牌标记为已使用
+func (r *passwordResetTokenRepo) InvalidateByUserID(ctx context.Context, userID int, at time.Time) error {
+	return r.db.do(ctx, func(t *tables) error {
+		for id, token := range t.tokens.rows {
+			if token.UserID == userID && token.UsedAt == nil {
+				token.UsedAt = &at
+				t.tokens.rows[id] = token
+			}
+		}
+		return nil
+	})
+}
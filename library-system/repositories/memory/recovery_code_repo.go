@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"maps"
+	"time"
+)
+
+type recoveryCodeRepo struct {
+	db db
+}
+
+// CreateBatch
+func (r *recoveryCodeRepo) CreateBatch(ctx context.Context, codes []*models.RecoveryCode) error {
+	return r.db.do(ctx, func(t *tables) error {
+		now := time.Now()
+		for _, code := range codes {
+			code.ID = t.codes.nextID()
+			code.CreatedAt = now
+			t.codes.rows[code.ID] = *code
+		}
+		return nil
+	})
+}
+
+// GetUnusedByUserID
+func (r *recoveryCodeRepo) GetUnusedByUserID(ctx context.Context, userID int) (codes []*models.RecoveryCode, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		codes = find(&t.codes, func(c *models.RecoveryCode) bool { return c.UserID == userID && c.UsedAt == nil })
+		return nil
+	})
+	return codes, err
+}
+
+// Update
+func (r *recoveryCodeRepo) Update(ctx context.Context, code *models.RecoveryCode) error {
+	return r.db.do(ctx, func(t *tables) error {
+		t.codes.rows[code.ID] = *code
+		return nil
+	})
+}
+
+// DeleteByUserID
+func (r *recoveryCodeRepo) DeleteByUserID(ctx context.Context, userID int) error {
+	return r.db.do(ctx, func(t *tables) error {
+		maps.DeleteFunc(t.codes.rows, func(_ int, c models.RecoveryCode) bool { return c.UserID == userID })
+		return nil
+	})
+}
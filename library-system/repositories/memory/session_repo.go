@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"maps"
+	"time"
+)
+
+type sessionRepo struct {
+	db db
+}
+
+// GetByID
+func (r *sessionRepo) GetByID(ctx context.Context, id string) (session *models.Session, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		session, err = t.sessions.get(id)
+		return err
+	})
+	return session, err
+}
+
+// Save
+func (r *sessionRepo) Save(ctx context.Context, session *models.Session) error {
+	return r.db.do(ctx, func(t *tables) error {
+		now := time.Now()
+		if existing, ok := t.sessions.rows[session.ID]; ok {
+			session.CreatedAt = existing.CreatedAt
+		} else {
+			session.CreatedAt = now
+		}
+		session.UpdatedAt = now
+		t.sessions.rows[session.ID] = *session
+		return nil
+	})
+}
+
+// Delete
+func (r *sessionRepo) Delete(ctx context.Context, id string) error {
+	return r.db.do(ctx, func(t *tables) error {
+		delete(t.sessions.rows, id)
+		return nil
+	})
+}
+
+// DeleteByUserID
+func (r *sessionRepo) DeleteByUserID(ctx context.Context, userID int) error {
+	return r.db.do(ctx, func(t *tables) error {
+		maps.DeleteFunc(t.sessions.rows, func(_ string, s models.Session) bool { return s.UserID == userID })
+		return nil
+	})
+}
+
+// DeleteExpired
+func (r *sessionRepo) DeleteExpired(ctx context.Context, before time.Time) (deleted int64, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		maps.DeleteFunc(t.sessions.rows, func(_ string, s models.Session) bool {
+			if s.ExpiresAt.Before(before) {
+				deleted++
+				return true
+			}
+			return false
+		})
+		return nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,246 @@
+// Package memory 仓库接口的内存实现，供测试使用
+// 行为尽量与MySQL实现一致: 找不到记录时返回gorm.ErrRecordNotFound，违反唯一约束时返回gorm.ErrDuplicatedKey，
+// 读取返回副本，修改只有调用Update等方法后才会生效
+package memory
+
+import (
+	"cmp"
+	"context"
+	"library-system/models"
+	"library-system/repositories"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// Store 内存数据库，同时实现repositories.Transactor
+type Store struct {
+	mu     sync.Mutex
+	tables tables
+
+	// 事务之间串行执行
+	txMu sync.Mutex
+}
+
+type tables struct {
+	users      table[int, models.User]
+	books      table[int, models.Book]
+	records    table[int, models.BorrowRecord]
+	sessions   table[string, models.Session]
+	tokens     table[int, models.PasswordResetToken]
+	codes      table[int, models.RecoveryCode]
+	throttles  table[string, models.LoginThrottle]
+	notices    table[int, models.Notification]
+	prefs      table[int, models.NotificationPreference]
+	locks      table[string, models.JobLock]
+	runs       table[int, models.JobRun]
+	events     table[int, models.OutboxEvent]
+	webhooks   table[int, models.Webhook]
+	deliveries table[int, models.WebhookDelivery]
+}
+
+// table 一张表，自增ID在事务和非事务操作之间共享，避免提交时冲突
+type table[K comparable, V any] struct {
+	rows   map[K]V
+	lastID *atomic.Int64
+}
+
+func newTable[K comparable, V any]() table[K, V] {
+	return table[K, V]{rows: make(map[K]V), lastID: new(atomic.Int64)}
+}
+
+// nextID
+func (t *table[K, V]) nextID() int {
+	return int(t.lastID.Add(1))
+}
+
+// get 按主键返回行的副本
+func (t *table[K, V]) get(key K) (*V, error) {
+	row, ok := t.rows[key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &row, nil
+}
+
+// clone 复制表中的行，行本身是值类型
+func (t *table[K, V]) clone() table[K, V] {
+	return table[K, V]{rows: maps.Clone(t.rows), lastID: t.lastID}
+}
+
+// merge 把事务中相对base发生变化的行写回，未变化的行保留事务期间其他操作的修改
+func (t *table[K, V]) merge(base, changed table[K, V]) {
+	for k, v := range changed.rows {
+		if old, ok := base.rows[k]; !ok || !reflect.DeepEqual(old, v) {
+			t.rows[k] = v
+		}
+	}
+	for k := range base.rows {
+		if _, ok := changed.rows[k]; !ok {
+			delete(t.rows, k)
+		}
+	}
+}
+
+// clone
+func (t *tables) clone() tables {
+	return tables{
+		users:      t.users.clone(),
+		books:      t.books.clone(),
+		records:    t.records.clone(),
+		sessions:   t.sessions.clone(),
+		tokens:     t.tokens.clone(),
+		codes:      t.codes.clone(),
+		throttles:  t.throttles.clone(),
+		notices:    t.notices.clone(),
+		prefs:      t.prefs.clone(),
+		locks:      t.locks.clone(),
+		runs:       t.runs.clone(),
+		events:     t.events.clone(),
+		webhooks:   t.webhooks.clone(),
+		deliveries: t.deliveries.clone(),
+	}
+}
+
+// merge
+func (t *tables) merge(base, changed tables) {
+	t.users.merge(base.users, changed.users)
+	t.books.merge(base.books, changed.books)
+	t.records.merge(base.records, changed.records)
+	t.sessions.merge(base.sessions, changed.sessions)
+	t.tokens.merge(base.tokens, changed.tokens)
+	t.codes.merge(base.codes, changed.codes)
+	t.throttles.merge(base.throttles, changed.throttles)
+	t.notices.merge(base.notices, changed.notices)
+	t.prefs.merge(base.prefs, changed.prefs)
+	t.locks.merge(base.locks, changed.locks)
+	t.runs.merge(base.runs, changed.runs)
+	t.events.merge(base.events, changed.events)
+	t.webhooks.merge(base.webhooks, changed.webhooks)
+	t.deliveries.merge(base.deliveries, changed.deliveries)
+}
+
+var _ repositories.Transactor = (*Store)(nil)
+
+func NewStore() *Store {
+	return &Store{
+		tables: tables{
+			users:      newTable[int, models.User](),
+			books:      newTable[int, models.Book](),
+			records:    newTable[int, models.BorrowRecord](),
+			sessions:   newTable[string, models.Session](),
+			tokens:     newTable[int, models.PasswordResetToken](),
+			codes:      newTable[int, models.RecoveryCode](),
+			throttles:  newTable[string, models.LoginThrottle](),
+			notices:    newTable[int, models.Notification](),
+			prefs:      newTable[int, models.NotificationPreference](),
+			locks:      newTable[string, models.JobLock](),
+			runs:       newTable[int, models.JobRun](),
+			events:     newTable[int, models.OutboxEvent](),
+			webhooks:   newTable[int, models.Webhook](),
+			deliveries: newTable[int, models.WebhookDelivery](),
+		},
+	}
+}
+
+// db 仓库访问数据的句柄，指向Store本身或事务中的副本
+type db struct {
+	mu     *sync.Mutex
+	tables *tables
+}
+
+// do 加锁后执行fn，context已取消时直接返回错误
+func (d db) do(ctx context.Context, fn func(t *tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return fn(d.tables)
+}
+
+// Repositories 不在事务中的仓库
+func (s *Store) Repositories() repositories.Repositories {
+	return newRepositories(db{mu: &s.mu, tables: &s.tables})
+}
+
+// WithinTransaction fn在数据副本上执行，返回nil时提交，否则丢弃全部修改
+func (s *Store) WithinTransaction(ctx context.Context, operation string, fn func(repos repositories.Repositories) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	base := s.tables.clone()
+	s.mu.Unlock()
+
+	work := base.clone()
+	if err := fn(newRepositories(db{mu: new(sync.Mutex), tables: &work})); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables.merge(base, work)
+	return nil
+}
+
+// newRepositories
+func newRepositories(d db) repositories.Repositories {
+	return repositories.Repositories{
+		Users:                   &userRepo{db: d},
+		Books:                   &bookRepo{db: d},
+		BorrowRecords:           &borrowRecordRepo{db: d},
+		Sessions:                &sessionRepo{db: d},
+		PasswordResetTokens:     &passwordResetTokenRepo{db: d},
+		RecoveryCodes:           &recoveryCodeRepo{db: d},
+		LoginThrottles:          &loginThrottleRepo{db: d},
+		Notifications:           &notificationRepo{db: d},
+		NotificationPreferences: &notificationPreferenceRepo{db: d},
+		JobLocks:                &jobLockRepo{db: d},
+		JobRuns:                 &jobRunRepo{db: d},
+		Outbox:                  &outboxRepo{db: d},
+		Webhooks:                &webhookRepo{db: d},
+		WebhookDeliveries:       &webhookDeliveryRepo{db: d},
+	}
+}
+
+// MySQL默认的排序规则比较字符串时不区分大小写
+func equalFold(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// containsFold LIKE '%substr%'
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// find 按主键顺序返回满足条件的行的副本
+func find[K cmp.Ordered, V any](t *table[K, V], match func(row *V) bool) []*V {
+	keys := slices.Sorted(maps.Keys(t.rows))
+	result := make([]*V, 0, len(keys))
+	for _, k := range keys {
+		row := t.rows[k]
+		if match(&row) {
+			result = append(result, &row)
+		}
+	}
+	return result
+}
+
+// first 按主键顺序返回第一条满足条件的行
+func first[K cmp.Ordered, V any](t *table[K, V], match func(row *V) bool) (*V, error) {
+	rows := find(t, match)
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return rows[0], nil
+}
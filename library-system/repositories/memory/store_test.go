@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"library-system/models"
+	"library-system/repositories"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestWithinTransaction(t *testing.T) {
+	errRollback := errors.New("rollback")
+
+	tests := []struct {
+		name      string
+		fnErr     error
+		wantStock int
+		wantBooks int
+	}{
+		{name: "提交", fnErr: nil, wantStock: 4, wantBooks: 2},
+		{name: "回滚", fnErr: errRollback, wantStock: 5, wantBooks: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewStore()
+			repos := store.Repositories()
+
+			book := &models.Book{Title: "Go语言", Author: "Lemon", Stock: 5}
+			if err := repos.Books.Create(ctx, book); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			err := store.WithinTransaction(ctx, "test", func(tx repositories.Repositories) error {
+				b, err := tx.Books.GetByID(ctx, book.ID)
+				if err != nil {
+					return err
+				}
+				b.Stock--
+				if err := tx.Books.Update(ctx, b); err != nil {
+					return err
+				}
+				if err := tx.Books.Create(ctx, &models.Book{Title: "Rust语言", Author: "Lime", Stock: 1}); err != nil {
+					return err
+				}
+
+				// 提交前事务外看不到修改
+				outside, err := repos.Books.GetByID(ctx, book.ID)
+				if err != nil {
+					return err
+				}
+				if outside.Stock != 5 {
+					t.Errorf("stock outside transaction = %d, want 5", outside.Stock)
+				}
+				return tt.fnErr
+			})
+			if !errors.Is(err, tt.fnErr) {
+				t.Fatalf("WithinTransaction() error = %v, want %v", err, tt.fnErr)
+			}
+
+			got, err := repos.Books.GetByID(ctx, book.ID)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if got.Stock != tt.wantStock {
+				t.Errorf("stock = %d, want %d", got.Stock, tt.wantStock)
+			}
+			books, err := repos.Books.GetAll(ctx)
+			if err != nil {
+				t.Fatalf("GetAll() error = %v", err)
+			}
+			if len(books) != tt.wantBooks {
+				t.Errorf("len(books) = %d, want %d", len(books), tt.wantBooks)
+			}
+		})
+	}
+}
+
+func TestWithinTransactionKeepsConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	repos := store.Repositories()
+
+	first := &models.Book{Title: "A", Author: "Lemon", Stock: 1}
+	second := &models.Book{Title: "B", Author: "Lemon", Stock: 1}
+	for _, b := range []*models.Book{first, second} {
+		if err := repos.Books.Create(ctx, b); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	err := store.WithinTransaction(ctx, "test", func(tx repositories.Repositories) error {
+		// 事务外修改另一行，提交时不应被事务中的旧数据覆盖
+		second.Stock = 9
+		if err := repos.Books.Update(ctx, second); err != nil {
+			return err
+		}
+
+		b, err := tx.Books.GetByID(ctx, first.ID)
+		if err != nil {
+			return err
+		}
+		b.Stock = 0
+		return tx.Books.Update(ctx, b)
+	})
+	if err != nil {
+		t.Fatalf("WithinTransaction() error = %v", err)
+	}
+
+	for _, want := range []models.Book{{ID: first.ID, Stock: 0}, {ID: second.ID, Stock: 9}} {
+		got, err := repos.Books.GetByID(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.Stock != want.Stock {
+			t.Errorf("book %d stock = %d, want %d", want.ID, got.Stock, want.Stock)
+		}
+	}
+}
+
+func TestReadsReturnCopies(t *testing.T) {
+	ctx := context.Background()
+	repos := NewStore().Repositories()
+
+	book := &models.Book{Title: "Go语言", Author: "Lemon", Stock: 5}
+	if err := repos.Books.Create(ctx, book); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repos.Books.GetByID(ctx, book.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	got.Stock = 0
+
+	again, err := repos.Books.GetByID(ctx, book.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if again.Stock != 5 {
+		t.Errorf("stock = %d, want 5 until Update is called", again.Stock)
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	ctx := context.Background()
+	repos := NewStore().Repositories()
+
+	if err := repos.Books.Create(ctx, &models.Book{Title: "Go语言", Author: "Lemon"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repos.Users.Create(ctx, &models.User{Name: "lemon", Role: models.RoleUser}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+		want error
+	}{
+		{
+			name: "书名重复",
+			fn:   func() error { return repos.Books.Create(ctx, &models.Book{Title: "GO语言", Author: "Lime"}) },
+			want: gorm.ErrDuplicatedKey,
+		},
+		{
+			name: "用户名重复",
+			fn:   func() error { return repos.Users.Create(ctx, &models.User{Name: "Lemon", Role: models.RoleUser}) },
+			want: gorm.ErrDuplicatedKey,
+		},
+		{
+			name: "图书不存在",
+			fn: func() error {
+				_, err := repos.Books.GetByID(ctx, 404)
+				return err
+			},
+			want: gorm.ErrRecordNotFound,
+		},
+		{
+			name: "按用户名查找不区分大小写",
+			fn: func() error {
+				_, err := repos.Users.GetByUsername(ctx, "LEMON")
+				return err
+			},
+			want: nil,
+		},
+		{
+			name: "context已取消",
+			fn: func() error {
+				ctx, cancel := context.WithCancel(ctx)
+				cancel()
+				_, err := repos.Books.GetAll(ctx)
+				return err
+			},
+			want: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
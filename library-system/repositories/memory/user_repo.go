@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+
+	"gorm.io/gorm"
+)
+
+type userRepo struct {
+	db db
+}
+
+// Create
+func (r *userRepo) Create(ctx context.Context, user *models.User) error {
+	return r.db.do(ctx, func(t *tables) error {
+		if user.ID == 0 {
+			user.ID = t.users.nextID()
+		}
+		// 与数据库列的默认值一致
+		if user.AuthProvider == "" {
+			user.AuthProvider = models.AuthProviderLocal
+		}
+		return r.save(t, user)
+	})
+}
+
+// GetByUserID
+func (r *userRepo) GetByUserID(ctx context.Context, id int) (user *models.User, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		user, err = t.users.get(id)
+		return err
+	})
+	return user, err
+}
+
+// GetByUsername
+func (r *userRepo) GetByUsername(ctx context.Context, username string) (user *models.User, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		user, err = first(&t.users, func(u *models.User) bool { return equalFold(u.Name, username) })
+		return err
+	})
+	return user, err
+}
+
+// GetByEmail
+func (r *userRepo) GetByEmail(ctx context.Context, email string) (user *models.User, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		user, err = first(&t.users, func(u *models.User) bool { return equalFold(u.Email, email) })
+		return err
+	})
+	return user, err
+}
+
+// GetByExternalID
+func (r *userRepo) GetByExternalID(ctx context.Context, provider, externalID string) (user *models.User, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		user, err = first(&t.users, func(u *models.User) bool {
+			return u.AuthProvider == provider && u.ExternalID != nil && *u.ExternalID == externalID
+		})
+		return err
+	})
+	return user, err
+}
+
+// GetByCardNumber
+func (r *userRepo) GetByCardNumber(ctx context.Context, cardNumber string) (user *models.User, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		user, err = first(&t.users, func(u *models.User) bool { return u.CardNumber != nil && *u.CardNumber == cardNumber })
+		return err
+	})
+	return user, err
+}
+
+// GetByAuthProvider
+func (r *userRepo) GetByAuthProvider(ctx context.Context, provider string) (users []*models.User, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		users = find(&t.users, func(u *models.User) bool { return u.AuthProvider == provider })
+		return nil
+	})
+	return users, err
+}
+
+// Update
+func (r *userRepo) Update(ctx context.Context, user *models.User) error {
+	return r.db.do(ctx, func(t *tables) error {
+		return r.save(t, user)
+	})
+}
+
+// save 用户名和借书证号唯一，同一身份提供方的外部标识唯一
+func (r *userRepo) save(t *tables, user *models.User) error {
+	for id, existing := range t.users.rows {
+		if id == user.ID {
+			continue
+		}
+		if equalFold(existing.Name, user.Name) {
+			return gorm.ErrDuplicatedKey
+		}
+		if user.ExternalID != nil && existing.ExternalID != nil &&
+			existing.AuthProvider == user.AuthProvider && *existing.ExternalID == *user.ExternalID {
+			return gorm.ErrDuplicatedKey
+		}
+		if user.CardNumber != nil && existing.CardNumber != nil && *existing.CardNumber == *user.CardNumber {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	t.users.rows[user.ID] = *user
+	return nil
+}
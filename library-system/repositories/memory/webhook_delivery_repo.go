@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"slices"
+	"time"
+)
+
+type webhookDeliveryRepo struct {
+	db db
+}
+
+// Create
+func (r *webhookDeliveryRepo) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.do(ctx, func(t *tables) error {
+		delivery.ID = t.deliveries.nextID()
+		delivery.CreatedAt = time.Now()
+		t.deliveries.rows[delivery.ID] = *delivery
+		return nil
+	})
+}
+
+// Update
+func (r *webhookDeliveryRepo) Update(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.do(ctx, func(t *tables) error {
+		t.deliveries.rows[delivery.ID] = *delivery
+		return nil
+	})
+}
+
+// GetDue 到了重试时间的待投递记录，按创建顺序，最多返回limit条
+func (r *webhookDeliveryRepo) GetDue(ctx context.Context, now time.Time, limit int) (deliveries []*models.WebhookDelivery, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		deliveries = find(&t.deliveries, func(d *models.WebhookDelivery) bool {
+			return d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now)
+		})
+		return nil
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, err
+}
+
+// GetByWebhookID 最新的在前，最多返回limit条
+func (r *webhookDeliveryRepo) GetByWebhookID(ctx context.Context, webhookID int, limit int) (deliveries []*models.WebhookDelivery, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		deliveries = find(&t.deliveries, func(d *models.WebhookDelivery) bool { return d.WebhookID == webhookID })
+		return nil
+	})
+	slices.Reverse(deliveries)
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, err
+}
+
+// DeleteByWebhookID
+func (r *webhookDeliveryRepo) DeleteByWebhookID(ctx context.Context, webhookID int) error {
+	return r.db.do(ctx, func(t *tables) error {
+		for id, d := range t.deliveries.rows {
+			if d.WebhookID == webhookID {
+				delete(t.deliveries.rows, id)
+			}
+		}
+		return nil
+	})
+}
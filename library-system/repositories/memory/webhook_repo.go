@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+)
+
+type webhookRepo struct {
+	db db
+}
+
+// Create
+func (r *webhookRepo) Create(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.do(ctx, func(t *tables) error {
+		webhook.ID = t.webhooks.nextID()
+		webhook.CreatedAt = time.Now()
+		webhook.UpdatedAt = webhook.CreatedAt
+		t.webhooks.rows[webhook.ID] = *webhook
+		return nil
+	})
+}
+
+// Update
+func (r *webhookRepo) Update(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.do(ctx, func(t *tables) error {
+		webhook.UpdatedAt = time.Now()
+		t.webhooks.rows[webhook.ID] = *webhook
+		return nil
+	})
+}
+
+// Delete
+func (r *webhookRepo) Delete(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.do(ctx, func(t *tables) error {
+		delete(t.webhooks.rows, webhook.ID)
+		return nil
+	})
+}
+
+// GetByID
+func (r *webhookRepo) GetByID(ctx context.Context, id int) (webhook *models.Webhook, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		webhook, err = t.webhooks.get(id)
+		return err
+	})
+	return webhook, err
+}
+
+// GetAll
+func (r *webhookRepo) GetAll(ctx context.Context) (webhooks []*models.Webhook, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		webhooks = find(&t.webhooks, func(*models.Webhook) bool { return true })
+		return nil
+	})
+	return webhooks, err
+}
+
+// GetActive
+func (r *webhookRepo) GetActive(ctx context.Context) (webhooks []*models.Webhook, err error) {
+	err = r.db.do(ctx, func(t *tables) error {
+		webhooks = find(&t.webhooks, func(w *models.Webhook) bool { return w.Active })
+		return nil
+	})
+	return webhooks, err
+}
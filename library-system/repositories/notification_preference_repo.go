@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+
+	"gorm.io/gorm"
+)
+
+type NotificationPreferenceRepository interface {
+	GetByUserID(ctx context.Context, userID int) (*models.NotificationPreference, error)
+	Save(ctx context.Context, preference *models.NotificationPreference) error
+}
+
+type notificationPreferenceRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB) NotificationPreferenceRepository {
+	return &notificationPreferenceRepoImpl{db: db}
+}
+
+// GetByUserID
+func (r *notificationPreferenceRepoImpl) GetByUserID(ctx context.Context, userID int) (*models.NotificationPreference, error) {
+	var preference models.NotificationPreference
+	result := r.db.WithContext(ctx).First(&preference, "user_id = ?", userID)
+	return &preference, result.Error
+}
+
+// Save 不存在时创建
+func (r *notificationPreferenceRepoImpl) Save(ctx context.Context, preference *models.NotificationPreference) error {
+	return r.db.WithContext(ctx).Save(preference).Error
+}
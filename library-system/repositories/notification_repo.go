@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	Update(ctx context.Context, notification *models.Notification) error
+	GetByID(ctx context.Context, id int) (*models.Notification, error)
+	GetByUserID(ctx context.Context, userID int, unreadOnly bool, limit int) ([]*models.Notification, error)
+	CountUnreadByUserID(ctx context.Context, userID int) (int64, error)
+	MarkAllRead(ctx context.Context, userID int, at time.Time) error
+}
+
+type notificationRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepoImpl{db: db}
+}
+
+// Create
+func (r *notificationRepoImpl) Create(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
+
+// Update
+func (r *notificationRepoImpl) Update(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Save(notification).Error
+}
+
+// GetByID
+func (r *notificationRepoImpl) GetByID(ctx context.Context, id int) (*models.Notification, error) {
+	var notification models.Notification
+	result := r.db.WithContext(ctx).First(&notification, id)
+	return &notification, result.Error
+}
+
+// GetByUserID 最新的在前，最多返回limit条
+func (r *notificationRepoImpl) GetByUserID(ctx context.Context, userID int, unreadOnly bool, limit int) ([]*models.Notification, error) {
+	var notifications []*models.Notification
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	result := query.Order("id DESC").Limit(limit).Find(&notifications)
+	return notifications, result.Error
+}
+
+// CountUnreadByUserID
+func (r *notificationRepoImpl) CountUnreadByUserID(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count)
+	return count, result.Error
+}
+
+// MarkAllRead 将用户所有未读通知标记为已读
+func (r *notificationRepoImpl) MarkAllRead(ctx context.Context, userID int, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", at).Error
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OutboxRepository interface {
+	Create(ctx context.Context, event *models.OutboxEvent) error
+	GetByID(ctx context.Context, id int) (*models.OutboxEvent, error)
+	GetUndispatched(ctx context.Context, limit int) ([]*models.OutboxEvent, error)
+	MarkDispatched(ctx context.Context, id int, at time.Time) error
+}
+
+type outboxRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepoImpl{db: db}
+}
+
+// Create
+func (r *outboxRepoImpl) Create(ctx context.Context, event *models.OutboxEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// GetByID
+func (r *outboxRepoImpl) GetByID(ctx context.Context, id int) (*models.OutboxEvent, error) {
+	var event models.OutboxEvent
+	result := r.db.WithContext(ctx).First(&event, id)
+	return &event, result.Error
+}
+
+// GetUndispatched 按写入顺序，最多返回limit条
+func (r *outboxRepoImpl) GetUndispatched(ctx context.Context, limit int) ([]*models.OutboxEvent, error) {
+	var events []*models.OutboxEvent
+	result := r.db.WithContext(ctx).Where("dispatched_at IS NULL").Order("id").Limit(limit).Find(&events)
+	return events, result.Error
+}
+
+// MarkDispatched
+func (r *outboxRepoImpl) MarkDispatched(ctx context.Context, id int, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", id).Update("dispatched_at", at).Error
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+	InvalidateByUserID(ctx context.Context, userID int, at time.Time) error
+}
+
+type passwordResetTokenRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) PasswordResetTokenRepository {
+	return &passwordResetTokenRepoImpl{db: db}
+}
+
+// Create
+func (r *passwordResetTokenRepoImpl) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// GetByTokenHash
+func (r *passwordResetTokenRepoImpl) GetByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	result := r.db.WithContext(ctx).First(&token, "token_hash = ?", tokenHash)
+	return &token, result.Error
+}
+
+// InvalidateByUserID 将用户所有未使用的令牌标记为已使用
+func (r *passwordResetTokenRepoImpl) InvalidateByUserID(ctx context.Context, userID int, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", at).Error
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	CreateBatch(ctx context.Context, codes []*models.RecoveryCode) error
+	GetUnusedByUserID(ctx context.Context, userID int) ([]*models.RecoveryCode, error)
+	Update(ctx context.Context, code *models.RecoveryCode) error
+	DeleteByUserID(ctx context.Context, userID int) error
+}
+
+type recoveryCodeRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepoImpl{db: db}
+}
+
+// CreateBatch
+func (r *recoveryCodeRepoImpl) CreateBatch(ctx context.Context, codes []*models.RecoveryCode) error {
+	return r.db.WithContext(ctx).Create(codes).Error
+}
+
+// GetUnusedByUserID
+func (r *recoveryCodeRepoImpl) GetUnusedByUserID(ctx context.Context, userID int) ([]*models.RecoveryCode, error) {
+	var codes []*models.RecoveryCode
+	result := r.db.WithContext(ctx).Where("user_id = ? AND used_at IS NULL", userID).Find(&codes)
+	return codes, result.Error
+}
+
+// Update
+func (r *recoveryCodeRepoImpl) Update(ctx context.Context, code *models.RecoveryCode) error {
+	return r.db.WithContext(ctx).Save(code).Error
+}
+
+// DeleteByUserID
+func (r *recoveryCodeRepoImpl) DeleteByUserID(ctx context.Context, userID int) error {
+	return r.db.WithContext(ctx).Delete(&models.RecoveryCode{}, "user_id = ?", userID).Error
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SchemaMigrationRepository interface {
+	CurrentVersion(ctx context.Context) (int, error)
+	Record(ctx context.Context, migration *models.SchemaMigration) error
+}
+
+type schemaMigrationRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewSchemaMigrationRepository(db *gorm.DB) SchemaMigrationRepository {
+	return &schemaMigrationRepoImpl{db: db}
+}
+
+// CurrentVersion 没有记录时返回0
+func (r *schemaMigrationRepoImpl) CurrentVersion(ctx context.Context) (int, error) {
+	var version int
+	result := r.db.WithContext(ctx).Model(&models.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version)
+	return version, result.Error
+}
+
+// Record 同一版本重复记录时忽略
+func (r *schemaMigrationRepoImpl) Record(ctx context.Context, migration *models.SchemaMigration) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(migration).Error
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	GetByID(ctx context.Context, id string) (*models.Session, error)
+	Save(ctx context.Context, session *models.Session) error
+	Delete(ctx context.Context, id string) error
+	DeleteByUserID(ctx context.Context, userID int) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type sessionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepositoryImpl{db: db}
+}
+
+// GetByID
+func (r *sessionRepositoryImpl) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	result := r.db.WithContext(ctx).First(&session, "id = ?", id)
+	return &session, result.Error
+}
+
+// Save
+func (r *sessionRepositoryImpl) Save(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Save(session).Error
+}
+
+// Delete
+func (r *sessionRepositoryImpl) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&models.Session{}, "id = ?", id).Error
+}
+
+// DeleteByUserID
+func (r *sessionRepositoryImpl) DeleteByUserID(ctx context.Context, userID int) error {
+	return r.db.WithContext(ctx).Delete(&models.Session{}, "user_id = ?", userID).Error
+}
+
+// DeleteExpired
+func (r *sessionRepositoryImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Delete(&models.Session{}, "expires_at < ?", before)
+	return result.RowsAffected, result.Error
+}
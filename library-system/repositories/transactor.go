@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"library-system/metrics"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+// Repositories 一个工作单元中使用的仓库，由Transactor创建时共享同一个事务
+type Repositories struct {
+	Users                   UserRepository
+	Books                   BookRepository
+	BorrowRecords           BorrowRecordRepository
+	Sessions                SessionRepository
+	PasswordResetTokens     PasswordResetTokenRepository
+	RecoveryCodes           RecoveryCodeRepository
+	LoginThrottles          LoginThrottleRepository
+	Notifications           NotificationRepository
+	NotificationPreferences NotificationPreferenceRepository
+	JobLocks                JobLockRepository
+	JobRuns                 JobRunRepository
+	Outbox                  OutboxRepository
+	Webhooks                WebhookRepository
+	WebhookDeliveries       WebhookDeliveryRepository
+}
+
+// NewRepositories 使用同一个数据库连接创建全部仓库
+func NewRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Users:                   NewUserRepository(db),
+		Books:                   NewBookRepository(db),
+		BorrowRecords:           NewBorrowRecordRepository(db),
+		Sessions:                NewSessionRepository(db),
+		PasswordResetTokens:     NewPasswordResetTokenRepository(db),
+		RecoveryCodes:           NewRecoveryCodeRepository(db),
+		LoginThrottles:          NewLoginThrottleRepository(db),
+		Notifications:           NewNotificationRepository(db),
+		NotificationPreferences: NewNotificationPreferenceRepository(db),
+		JobLocks:                NewJobLockRepository(db),
+		JobRuns:                 NewJobRunRepository(db),
+		Outbox:                  NewOutboxRepository(db),
+		Webhooks:                NewWebhookRepository(db),
+		WebhookDeliveries:       NewWebhookDeliveryRepository(db),
+	}
+}
+
+// Transactor 在事务中执行工作单元，fn返回错误时回滚
+// operation用于指标标签；遇到死锁等可重试的错误时fn会被再次执行，不能有数据库以外的副作用
+type Transactor interface {
+	WithinTransaction(ctx context.Context, operation string, fn func(repos Repositories) error) error
+}
+
+// 事务遇到死锁或锁等待超时时最多重试的次数
+const maxTransactionRetries = 3
+
+// MySQL错误码
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+)
+
+var transactionRetries = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "library_db_transaction_retries_total",
+	Help: "因死锁或锁等待超时而重试的事务次数",
+}, []string{"operation"})
+
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &gormTransactor{db: db}
+}
+
+// WithinTransaction 遇到死锁或锁等待超时时整体重试
+func (t *gormTransactor) WithinTransaction(ctx context.Context, operation string, fn func(repos Repositories) error) error {
+	for attempt := 1; ; attempt++ {
+		err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(NewRepositories(tx))
+		})
+		if err == nil || attempt > maxTransactionRetries || !isRetryableTxError(err) {
+			return err
+		}
+
+		transactionRetries.WithLabelValues(operation).Inc()
+		// 请求已取消或超时时不再重试
+		timer := time.NewTimer(time.Duration(attempt) * 20 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryableTxError
+func isRetryableTxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == mysqlErrDeadlock || mysqlErr.Number == mysqlErrLockWaitTimeout
+}
@@ -1,40 +1,79 @@
-package repositories
-
-import (
-	"library-system/models"
-
-	"gorm.io/gorm"
-)
-
-type UserRepository interface {
-	Create(user *models.User) error
-	GetByUserID(id int) (*models.User, error)
-	GetByUsername(username string) (*models.User, error)
-}
-
-type userRepositoryImpl struct {
-	db *gorm.DB
-}
-
-func NewUserRepository(db *gorm.DB) UserRepository {
-	return &userRepositoryImpl{db: db}
-}
-
-// Create
-func (r *userRepositoryImpl) Create(user *models.User) error {
-	return r.db.Create(user).Error
-}
-
-// GetByUserID
-func (r *userRepositoryImpl) GetByUserID(id int) (*models.User, error) {
-	var user models.User
-	result := r.db.First(&user, id)
-	return &user, result.Error
-}
-
-// GetByUsername
-func (r *userRepositoryImpl) GetByUsername(username string) (*models.User, error) {
-	var user models.User
-	result := r.db.First(&user, "name = ?", username)
-	return &user, result.Error
-}
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByUserID(ctx context.Context, id int) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByExternalID(ctx context.Context, provider, externalID string) (*models.User, error)
+	GetByCardNumber(ctx context.Context, cardNumber string) (*models.User, error)
+	GetByAuthProvider(ctx context.Context, provider string) ([]*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+}
+
+type userRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepositoryImpl{db: db}
+}
+
+// Create
+func (r *userRepositoryImpl) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+// GetByUserID
+func (r *userRepositoryImpl) GetByUserID(ctx context.Context, id int) (*models.User, error) {
+	var user models.User
+	result := r.db.WithContext(ctx).First(&user, id)
+	return &user, result.Error
+}
+
+// GetByUsername
+func (r *userRepositoryImpl) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	result := r.db.WithContext(ctx).First(&user, "name = ?", username)
+	return &user, result.Error
+}
+
+// GetByEmail
+func (r *userRepositoryImpl) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	result := r.db.WithContext(ctx).First(&user, "email = ?", email)
+	return &user, result.Error
+}
+
+// GetByExternalID
+func (r *userRepositoryImpl) GetByExternalID(ctx context.Context, provider, externalID string) (*models.User, error) {
+	var user models.User
+	result := r.db.WithContext(ctx).First(&user, "auth_provider = ? AND external_id = ?", provider, externalID)
+	return &user, result.Error
+}
+
+// GetByCardNumber
+func (r *userRepositoryImpl) GetByCardNumber(ctx context.Context, cardNumber string) (*models.User, error) {
+	var user models.User
+	result := r.db.WithContext(ctx).First(&user, "card_number = ?", cardNumber)
+	return &user, result.Error
+}
+
+// GetByAuthProvider
+func (r *userRepositoryImpl) GetByAuthProvider(ctx context.Context, provider string) ([]*models.User, error) {
+	var users []*models.User
+	result := r.db.WithContext(ctx).Where("auth_provider = ?", provider).Find(&users)
+	return users, result.Error
+}
+
+// Update
+func (r *userRepositoryImpl) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.WebhookDelivery) error
+	Update(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDue(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	GetByWebhookID(ctx context.Context, webhookID int, limit int) ([]*models.WebhookDelivery, error)
+	DeleteByWebhookID(ctx context.Context, webhookID int) error
+}
+
+type webhookDeliveryRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepoImpl{db: db}
+}
+
+// Create
+func (r *webhookDeliveryRepoImpl) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+// Update
+func (r *webhookDeliveryRepoImpl) Update(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+// GetDue 到了重试时间的待投递记录，按创建顺序，最多返回limit条
+func (r *webhookDeliveryRepoImpl) GetDue(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("id").Limit(limit).Find(&deliveries)
+	return deliveries, result.Error
+}
+
+// GetByWebhookID 最新的在前，最多返回limit条
+func (r *webhookDeliveryRepoImpl) GetByWebhookID(ctx context.Context, webhookID int, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	result := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries)
+	return deliveries, result.Error
+}
+
+// DeleteByWebhookID
+func (r *webhookDeliveryRepoImpl) DeleteByWebhookID(ctx context.Context, webhookID int) error {
+	return r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).Delete(&models.WebhookDelivery{}).Error
+}
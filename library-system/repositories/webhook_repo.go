@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"library-system/models"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	Update(ctx context.Context, webhook *models.Webhook) error
+	Delete(ctx context.Context, webhook *models.Webhook) error
+	GetByID(ctx context.Context, id int) (*models.Webhook, error)
+	GetAll(ctx context.Context) ([]*models.Webhook, error)
+	GetActive(ctx context.Context) ([]*models.Webhook, error)
+}
+
+type webhookRepoImpl struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepoImpl{db: db}
+}
+
+// Create
+func (r *webhookRepoImpl) Create(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+// Update
+func (r *webhookRepoImpl) Update(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+// Delete
+func (r *webhookRepoImpl) Delete(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Delete(webhook).Error
+}
+
+// GetByID
+func (r *webhookRepoImpl) GetByID(ctx context.Context, id int) (*models.Webhook, error) {
+	var webhook models.Webhook
+	result := r.db.WithContext(ctx).First(&webhook, id)
+	return &webhook, result.Error
+}
+
+// GetAll
+func (r *webhookRepoImpl) GetAll(ctx context.Context) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	result := r.db.WithContext(ctx).Order("id").Find(&webhooks)
+	return webhooks, result.Error
+}
+
+// GetActive
+func (r *webhookRepoImpl) GetActive(ctx context.Context) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	result := r.db.WithContext(ctx).Where("active = ?", true).Order("id").Find(&webhooks)
+	return webhooks, result.Error
+}
@@ -1,143 +1,226 @@
-package services
-
-import (
-	"errors"
-	"fmt"
-	"library-system/models"
-	"library-system/repositories"
-	"time"
-
-	"gorm.io/gorm"
-)
-
-type AdminService struct {
-	db *gorm.DB
-}
-
-func NewAdminService(db *gorm.DB) *AdminService {
-	return &AdminService{
-		db: db,
-	}
-}
-
-// AddBook
-func (s *AdminService) AddBook(title, author string, stock int) error {
-	// 参数基础校验
-	if title == "" || author == "" || stock < 0 {
-		return ErrInvalidInput
-	}
-
-	// 创建仓库实例
-	bookRepo := repositories.NewBookRepository(s.db)
-
-	// 判断图书是否已存在
-	_, err := bookRepo.GetByTitle(title)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return fmt.Errorf("failed to check book existence: %w", err)
-	}
-	if err == nil {
-		return ErrBookExists
-	}
-
-	book := &models.Book{
-		Title:  title,
-		Author: author,
-		Stock:  stock,
-	}
-
-	if err := bookRepo.Create(book); err != nil {
-		return fmt.Errorf("failed to create book: %w", err)
-	}
-
-	return nil
-}
-
-// UpdateBook
-func (s *AdminService) UpdateBook(title, author string, ID, stock int) error {
-	// 参数基础校验
-	if title == "" || author == "" || ID < 0 || stock < 0 {
-		return ErrInvalidInput
-	}
-
-	// 创建仓库实例
-	bookRepo := repositories.NewBookRepository(s.db)
-
-	// 查询图书
-	book, err := bookRepo.GetByID(ID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrBookNotFound
-		}
-		return fmt.Errorf("failed to get book by ID: %w", err)
-	}
-
-	book.Title = title
-	book.Author = author
-	book.Stock = stock
-
-	if err := bookRepo.Update(book); err != nil {
-		return fmt.Errorf("failed to update book: %w", err)
-	}
-
-	return nil
-}
-
-// DeleteBook
-func (s *AdminService) DeleteBook(ID int) error {
-	// 参数基础校验
-	if ID < 0 {
-		return ErrInvalidInput
-	}
-
-	// 事务处理
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 创建仓库实例
-		txBookRepo := repositories.NewBookRepository(tx)
-		txRecordRepo := repositories.NewBorrowRecordRepository(tx)
-
-		// 查询图书
-		book, err := txBookRepo.GetByID(ID)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return ErrBookNotFound
-			}
-			return fmt.Errorf("failed to get book by ID: %w", err)
-		}
-
-		// 将所有借书记录改为已归还
-		borrowRecords, err := txRecordRepo.GetByBookID(ID)
-		if err != nil {
-			return fmt.Errorf("failed to get borrow records by book ID: %w", err)
-		}
-
-		for _, record := range borrowRecords {
-			currentTime := time.Now()
-			if record.ReturnedAt == nil {
-				record.ReturnedAt = &currentTime
-
-				if err := txRecordRepo.Update(record); err != nil {
-					return fmt.Errorf("failed to update borrow record: %w", err)
-				}
-			}
-		}
-
-		if err := txBookRepo.Delete(book); err != nil {
-			return fmt.Errorf("failed to delete book: %w", err)
-		}
-
-		return nil
-	})
-}
-
-// GetAllBorrowRecords
-func (s *AdminService) GetAllBorrowRecords() ([]*models.BorrowRecord, error) {
-	// 创建仓库实例
-	recordRepo := repositories.NewBorrowRecordRepository(s.db)
-
-	records, err := recordRepo.GetAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all borrow records: %w", err)
-	}
-
-	return records, nil
-}
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+type AdminService struct {
+	transactor   repositories.Transactor
+	recordRepo   repositories.BorrowRecordRepository
+	throttleRepo repositories.LoginThrottleRepository
+	// 为nil时不发布库存变化
+	events *EventBus
+}
+
+func NewAdminService(transactor repositories.Transactor, recordRepo repositories.BorrowRecordRepository, throttleRepo repositories.LoginThrottleRepository, events *EventBus) *AdminService {
+	return &AdminService{
+		transactor:   transactor,
+		recordRepo:   recordRepo,
+		throttleRepo: throttleRepo,
+		events:       events,
+	}
+}
+
+// AddBook
+func (s *AdminService) AddBook(ctx context.Context, title, author string, stock int) (err error) {
+	ctx, span := startSpan(ctx, "AdminService.AddBook")
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if title == "" || author == "" || stock < 0 {
+		return ErrInvalidInput
+	}
+
+	// 事务处理
+	return s.transactor.WithinTransaction(ctx, "add_book", func(repos repositories.Repositories) error {
+		// 判断图书是否已存在
+		_, err := repos.Books.GetByTitle(ctx, title)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check book existence: %w", err)
+		}
+		if err == nil {
+			return ErrBookExists
+		}
+
+		book := &models.Book{
+			Title:  title,
+			Author: author,
+			Stock:  stock,
+		}
+
+		if err := repos.Books.Create(ctx, book); err != nil {
+			return fmt.Errorf("failed to create book: %w", err)
+		}
+
+		return recordEvent(ctx, repos.Outbox, models.EventBookCreated, book)
+	})
+}
+
+// UpdateBook
+func (s *AdminService) UpdateBook(ctx context.Context, title, author string, ID, stock int) (err error) {
+	ctx, span := startSpan(ctx, "AdminService.UpdateBook", attribute.Int("book.id", ID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if title == "" || author == "" || ID < 0 || stock < 0 {
+		return ErrInvalidInput
+	}
+
+	// 事务处理
+	err = s.transactor.WithinTransaction(ctx, "update_book", func(repos repositories.Repositories) error {
+		// 查询图书
+		book, err := repos.Books.GetByID(ctx, ID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBookNotFound
+			}
+			return fmt.Errorf("failed to get book by ID: %w", err)
+		}
+
+		book.Title = title
+		book.Author = author
+		book.Stock = stock
+
+		if err := repos.Books.Update(ctx, book); err != nil {
+			return fmt.Errorf("failed to update book: %w", err)
+		}
+
+		return recordEvent(ctx, repos.Outbox, models.EventBookUpdated, book)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publish(ID, stock, nil)
+	return nil
+}
+
+// DeleteBook
+func (s *AdminService) DeleteBook(ctx context.Context, ID int) (err error) {
+	ctx, span := startSpan(ctx, "AdminService.DeleteBook", attribute.Int("book.id", ID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if ID < 0 {
+		return ErrInvalidInput
+	}
+
+	// 随图书一起归还的借阅，提交后发布
+	var returned []*models.BorrowRecord
+
+	// 事务处理
+	err = s.transactor.WithinTransaction(ctx, "delete_book", func(repos repositories.Repositories) error {
+		// 事务内的仓库
+		txBookRepo := repos.Books
+		txRecordRepo := repos.BorrowRecords
+
+		// 查询图书
+		book, err := txBookRepo.GetByID(ctx, ID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBookNotFound
+			}
+			return fmt.Errorf("failed to get book by ID: %w", err)
+		}
+
+		// 将所有借书记录改为已归还
+		borrowRecords, err := txRecordRepo.GetByBookID(ctx, ID)
+		if err != nil {
+			return fmt.Errorf("failed to get borrow records by book ID: %w", err)
+		}
+
+		for _, record := range borrowRecords {
+			currentTime := time.Now()
+			if record.ReturnedAt == nil {
+				record.ReturnedAt = &currentTime
+
+				if err := txRecordRepo.Update(ctx, record); err != nil {
+					return fmt.Errorf("failed to update borrow record: %w", err)
+				}
+				if err := recordEvent(ctx, repos.Outbox, models.EventLoanReturned, record); err != nil {
+					return err
+				}
+				returned = append(returned, record)
+			}
+		}
+
+		if err := txBookRepo.Delete(ctx, book); err != nil {
+			return fmt.Errorf("failed to delete book: %w", err)
+		}
+
+		return recordEvent(ctx, repos.Outbox, models.EventBookDeleted, book)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 图书删除后不可再借，库存按0发布
+	s.publish(ID, 0, returned)
+	return nil
+}
+
+// publish 提交后发布随图书变更归还的借阅和图书当前的库存
+func (s *AdminService) publish(bookID, stock int, returned []*models.BorrowRecord) {
+	if s.events == nil {
+		return
+	}
+	for _, record := range returned {
+		s.events.Publish(models.EventLoanReturned, record.BookID, record)
+	}
+	s.events.Publish(BusEventAvailability, bookID, Availability{BookID: bookID, Stock: stock})
+}
+
+// GetAllBorrowRecords
+func (s *AdminService) GetAllBorrowRecords(ctx context.Context) (_ []*models.BorrowRecord, err error) {
+	ctx, span := startSpan(ctx, "AdminService.GetAllBorrowRecords")
+	defer endSpan(span, &err)
+
+	records, err := s.recordRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all borrow records: %w", err)
+	}
+
+	return records, nil
+}
+
+// GetLockouts 获取当前处于锁定状态的账号和IP
+func (s *AdminService) GetLockouts(ctx context.Context) (_ []*models.LoginThrottle, err error) {
+	ctx, span := startSpan(ctx, "AdminService.GetLockouts")
+	defer endSpan(span, &err)
+
+	throttles, err := s.throttleRepo.GetLocked(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locked login throttles: %w", err)
+	}
+
+	return throttles, nil
+}
+
+// ClearLockout 清除账号或IP的失败记录和锁定
+func (s *AdminService) ClearLockout(ctx context.Context, key string) (err error) {
+	ctx, span := startSpan(ctx, "AdminService.ClearLockout")
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if key == "" {
+		return ErrInvalidInput
+	}
+
+	affected, err := s.throttleRepo.Delete(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete login throttle: %w", err)
+	}
+	if affected == 0 {
+		return ErrLockoutNotFound
+	}
+
+	return nil
+}
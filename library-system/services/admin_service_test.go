@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"library-system/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newTestAdminService(env *testEnv) *AdminService {
+	return NewAdminService(env.store, env.repos.BorrowRecords, env.repos.LoginThrottles, nil)
+}
+
+func TestAdminService_AddBook(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		author  string
+		stock   int
+		wantErr error
+	}{
+		{name: "添加成功", title: "Rust", author: "Lime", stock: 3},
+		{name: "库存可以为0", title: "Rust", author: "Lime", stock: 0},
+		{name: "书名为空", title: "", author: "Lime", stock: 3, wantErr: ErrInvalidInput},
+		{name: "作者为空", title: "Rust", author: "", stock: 3, wantErr: ErrInvalidInput},
+		{name: "库存为负数", title: "Rust", author: "Lime", stock: -1, wantErr: ErrInvalidInput},
+		{name: "书名已存在", title: "go", author: "Lime", stock: 3, wantErr: ErrBookExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.createBook(t, "Go", 1)
+			service := newTestAdminService(env)
+
+			err := service.AddBook(context.Background(), tt.title, tt.author, tt.stock)
+			checkErr(t, err, tt.wantErr)
+
+			if tt.wantErr == nil {
+				book, err := env.repos.Books.GetByTitle(context.Background(), tt.title)
+				checkErr(t, err, nil)
+				if book.Author != tt.author || book.Stock != tt.stock {
+					t.Errorf("book = %+v, want author %q stock %d", book, tt.author, tt.stock)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminService_UpdateBook(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int
+		title   string
+		stock   int
+		wantErr error
+	}{
+		{name: "更新成功", title: "Go程序设计语言", stock: 7},
+		{name: "书名为空", title: "", stock: 7, wantErr: ErrInvalidInput},
+		{name: "库存为负数", title: "Go", stock: -1, wantErr: ErrInvalidInput},
+		{name: "图书不存在", id: 404, title: "Go", stock: 7, wantErr: ErrBookNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			book := env.createBook(t, "Go", 1)
+			service := newTestAdminService(env)
+			id := book.ID
+			if tt.id != 0 {
+				id = tt.id
+			}
+
+			err := service.UpdateBook(context.Background(), tt.title, "Lemon", id, tt.stock)
+			checkErr(t, err, tt.wantErr)
+
+			got := env.getBook(t, book.ID)
+			if tt.wantErr == nil && (got.Title != tt.title || got.Stock != tt.stock) {
+				t.Errorf("book = %+v, want title %q stock %d", got, tt.title, tt.stock)
+			}
+			if tt.wantErr != nil && (got.Title != "Go" || got.Stock != 1) {
+				t.Errorf("book changed on error: %+v", got)
+			}
+		})
+	}
+}
+
+func TestAdminService_DeleteBook(t *testing.T) {
+	t.Run("删除图书并归还借阅", func(t *testing.T) {
+		env := newTestEnv(t)
+		service := newTestAdminService(env)
+		user := env.createUser(t, "lemon", models.RoleUser)
+		book := env.createBook(t, "Go", 1)
+		other := env.createBook(t, "Rust", 1)
+		active := env.createRecord(t, user.ID, book.ID, time.Hour)
+		untouched := env.createRecord(t, user.ID, other.ID, time.Hour)
+
+		checkErr(t, service.DeleteBook(context.Background(), book.ID), nil)
+
+		_, err := env.repos.Books.GetByID(context.Background(), book.ID)
+		checkErr(t, err, gorm.ErrRecordNotFound)
+
+		record, err := env.repos.BorrowRecords.GetByID(context.Background(), active.ID)
+		checkErr(t, err, nil)
+		if record.ReturnedAt == nil {
+			t.Error("active record not returned")
+		}
+		record, err = env.repos.BorrowRecords.GetByID(context.Background(), untouched.ID)
+		checkErr(t, err, nil)
+		if record.ReturnedAt != nil {
+			t.Error("record of another book was returned")
+		}
+	})
+
+	tests := []struct {
+		name    string
+		id      int
+		wantErr error
+	}{
+		{name: "无效的参数", id: -1, wantErr: ErrInvalidInput},
+		{name: "图书不存在", id: 404, wantErr: ErrBookNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			checkErr(t, newTestAdminService(env).DeleteBook(context.Background(), tt.id), tt.wantErr)
+		})
+	}
+}
+
+// received 取出订阅者已收到的全部事件，Publish是同步写入的，不需要等待
+func received(sub *Subscription) []BusEvent {
+	var events []BusEvent
+	for {
+		select {
+		case event := <-sub.C:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestAdminService_PublishEvents(t *testing.T) {
+	env := newTestEnv(t)
+	bus := NewEventBus(10)
+	service := NewAdminService(env.store, env.repos.BorrowRecords, env.repos.LoginThrottles, bus)
+	sub, _, _ := bus.Subscribe(0, func(*BusEvent) bool { return true })
+	defer sub.Close()
+	user := env.createUser(t, "lemon", models.RoleUser)
+	book := env.createBook(t, "Go", 1)
+	active := env.createRecord(t, user.ID, book.ID, time.Hour)
+
+	t.Run("更新图书发布库存", func(t *testing.T) {
+		checkErr(t, service.UpdateBook(context.Background(), "Go", "Lemon", book.ID, 5), nil)
+		events := received(sub)
+		if len(events) != 1 || events[0].Type != BusEventAvailability || events[0].Data != (Availability{BookID: book.ID, Stock: 5}) {
+			t.Errorf("events = %+v, want availability with stock 5", events)
+		}
+	})
+
+	t.Run("更新失败不发布", func(t *testing.T) {
+		checkErr(t, service.UpdateBook(context.Background(), "Go", "Lemon", 404, 5), ErrBookNotFound)
+		if events := received(sub); len(events) != 0 {
+			t.Errorf("events = %+v, want none", events)
+		}
+	})
+
+	t.Run("删除图书发布归还和库存", func(t *testing.T) {
+		checkErr(t, service.DeleteBook(context.Background(), book.ID), nil)
+		events := received(sub)
+		if len(events) != 2 {
+			t.Fatalf("events = %+v, want loan returned and availability", events)
+		}
+		if record, ok := events[0].Data.(*models.BorrowRecord); events[0].Type != models.EventLoanReturned || !ok || record.ID != active.ID || record.ReturnedAt == nil {
+			t.Errorf("first event = %+v, want returned loan %d", events[0], active.ID)
+		}
+		if events[1].Type != BusEventAvailability || events[1].Data != (Availability{BookID: book.ID, Stock: 0}) {
+			t.Errorf("second event = %+v, want availability with stock 0", events[1])
+		}
+	})
+}
+
+func TestAdminService_GetAllBorrowRecords(t *testing.T) {
+	env := newTestEnv(t)
+	service := newTestAdminService(env)
+	lemon := env.createUser(t, "lemon", models.RoleUser)
+	lime := env.createUser(t, "lime", models.RoleUser)
+	book := env.createBook(t, "Go", 5)
+	env.createRecord(t, lemon.ID, book.ID, time.Hour)
+	env.createRecord(t, lime.ID, book.ID, time.Hour)
+
+	records, err := service.GetAllBorrowRecords(context.Background())
+	checkErr(t, err, nil)
+	if len(records) != 2 {
+		t.Errorf("len(records) = %d, want 2", len(records))
+	}
+}
+
+func TestAdminService_Lockouts(t *testing.T) {
+	env := newTestEnv(t)
+	service := newTestAdminService(env)
+	ctx := context.Background()
+
+	// 一个已锁定，一个锁定已过期
+	lockedUntil := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Hour)
+	checkErr(t, env.repos.LoginThrottles.Save(ctx, &models.LoginThrottle{Key: "user:lemon", Failures: 5, LastFailedAt: time.Now(), LockedUntil: &lockedUntil}), nil)
+	checkErr(t, env.repos.LoginThrottles.Save(ctx, &models.LoginThrottle{Key: "ip:10.0.0.1", Failures: 20, LastFailedAt: time.Now(), LockedUntil: &expired}), nil)
+
+	lockouts, err := service.GetLockouts(ctx)
+	checkErr(t, err, nil)
+	if len(lockouts) != 1 || lockouts[0].Key != "user:lemon" {
+		t.Fatalf("lockouts = %+v, want only user:lemon", lockouts)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{name: "解除锁定", key: "user:lemon"},
+		{name: "重复解除", key: "user:lemon", wantErr: ErrLockoutNotFound},
+		{name: "key为空", key: "", wantErr: ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, service.ClearLockout(ctx, tt.key), tt.wantErr)
+		})
+	}
+
+	lockouts, err = service.GetLockouts(ctx)
+	checkErr(t, err, nil)
+	if len(lockouts) != 0 {
+		t.Errorf("lockouts = %+v, want none", lockouts)
+	}
+}
@@ -1,68 +1,153 @@
-package services
-
-import (
-	"errors"
-	"fmt"
-	"library-system/models"
-	"library-system/repositories"
-
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
-)
-
-type AuthService struct {
-	userRepo repositories.UserRepository
-}
-
-func NewAuthService(userRepo repositories.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
-}
-
-// Login
-func (s *AuthService) Login(username, password string) (*models.User, error) {
-	user, err := s.userRepo.GetByUsername(username)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrUserNotFound
-		}
-		return nil, fmt.Errorf("failed to get user by username: %w", err)
-	}
-
-	// 验证密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, ErrInvalidPassword
-	}
-
-	return user, nil
-}
-
-// Register
-func (s *AuthService) Register(username string, password string) error {
-	// 判断用户名是否已存在
-	_, err := s.userRepo.GetByUsername(username)
-
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return fmt.Errorf("failed to check username existence: %w", err)
-	}
-	if err == nil {
-		return ErrUserExists
-	}
-
-	// 加密密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
-	}
-
-	// 创建用户
-	user := &models.User{
-		Name:     username,
-		Password: string(hashedPassword),
-		Role:     "user",
-	}
-
-	if err := s.userRepo.Create(user); err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
-	}
-	return nil
-}
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/metrics"
+	"library-system/models"
+	"library-system/repositories"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 用户不存在时用于比较的哈希，使响应时间与密码错误时一致
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("library-system-dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+var loginAttempts = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "library_login_attempts_total",
+	Help: "登录尝试次数，method为password、totp或oidc，result为success、failure、locked、disabled或error",
+}, []string{"method", "result"})
+
+// recordLoginAttempt 按登录结果计数
+func recordLoginAttempt(method string, err error) {
+	var lockedErr *LoginLockedError
+	result := "success"
+	switch {
+	case err == nil:
+	case errors.As(err, &lockedErr):
+		result = "locked"
+	case errors.Is(err, ErrUserDisabled):
+		result = "disabled"
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrInvalidPassword),
+		errors.Is(err, ErrInvalidTwoFactorCode), errors.Is(err, ErrOIDCLoginFailed):
+		result = "failure"
+	default:
+		result = "error"
+	}
+	loginAttempts.WithLabelValues(method, result).Inc()
+}
+
+type AuthService struct {
+	userRepo       repositories.UserRepository
+	authenticators []Authenticator
+	loginGuard     *LoginGuard
+	passwordPolicy PasswordPolicy
+}
+
+// NewAuthService authenticators按顺序尝试，第一个认识该用户的认证方式决定结果
+func NewAuthService(userRepo repositories.UserRepository, authenticators []Authenticator, loginGuard *LoginGuard, passwordPolicy PasswordPolicy) *AuthService {
+	return &AuthService{userRepo: userRepo, authenticators: authenticators, loginGuard: loginGuard, passwordPolicy: passwordPolicy}
+}
+
+// Login
+func (s *AuthService) Login(ctx context.Context, username, password, clientIP string) (user *models.User, err error) {
+	ctx, span := startSpan(ctx, "AuthService.Login")
+	defer endSpan(span, &err)
+
+	defer func() { recordLoginAttempt("password", err) }()
+
+	accountKey := AccountKey(username)
+	ipKey := IPKey(clientIP)
+
+	// 检查账号或IP是否被锁定
+	if err := s.loginGuard.Check(ctx, accountKey, ipKey); err != nil {
+		return nil, err
+	}
+
+	user, err = s.authenticate(ctx, username, password)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrInvalidPassword) {
+			if err := s.loginGuard.RecordFailure(ctx, accountKey, ipKey); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.loginGuard.RecordSuccess(ctx, accountKey); err != nil {
+		return nil, err
+	}
+
+	// 密码正确后再检查账号状态，避免泄露账号是否存在
+	if !user.Active {
+		return nil, ErrUserDisabled
+	}
+
+	return user, nil
+}
+
+// authenticate 依次尝试各认证方式
+func (s *AuthService) authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	for _, authenticator := range s.authenticators {
+		user, err := authenticator.Authenticate(ctx, username, password)
+		if errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		return user, err
+	}
+
+	// 用户不存在时同样执行一次bcrypt比较
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+	return nil, ErrUserNotFound
+}
+
+// Register
+func (s *AuthService) Register(ctx context.Context, username string, password string) (err error) {
+	ctx, span := startSpan(ctx, "AuthService.Register")
+	defer endSpan(span, &err)
+
+	// 校验用户名格式和密码强度
+	fields := ValidateUsername(username)
+	fields = append(fields, s.passwordPolicy.Validate("password", username, password)...)
+	if err := newValidationError(fields); err != nil {
+		return err
+	}
+
+	// 判断用户名是否已存在
+	_, err = s.userRepo.GetByUsername(ctx, username)
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check username existence: %w", err)
+	}
+	if err == nil {
+		return ErrUserExists
+	}
+
+	// 加密密码
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	// 创建用户
+	user := &models.User{
+		Name:         username,
+		Password:     string(hashedPassword),
+		Role:         models.RoleUser,
+		AuthProvider: models.AuthProviderLocal,
+		Active:       true,
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
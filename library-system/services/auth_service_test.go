@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubAuthenticator 只认识一个用户的认证方式
+type stubAuthenticator struct {
+	user     *models.User
+	password string
+}
+
+func (a *stubAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	if username != a.user.Name {
+		return nil, ErrUserNotFound
+	}
+	if password != a.password {
+		return nil, ErrInvalidPassword
+	}
+	return a.user, nil
+}
+
+func TestAuthService_Login(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, env *testEnv)
+		username string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "登录成功",
+			setup:    func(t *testing.T, env *testEnv) { env.createUser(t, "lemon", models.RoleUser) },
+			username: "lemon",
+			password: testPassword,
+		},
+		{
+			name:     "密码错误",
+			setup:    func(t *testing.T, env *testEnv) { env.createUser(t, "lemon", models.RoleUser) },
+			username: "lemon",
+			password: "wrong-password1",
+			wantErr:  ErrInvalidPassword,
+		},
+		{
+			name:     "用户不存在",
+			setup:    func(t *testing.T, env *testEnv) {},
+			username: "nobody",
+			password: testPassword,
+			wantErr:  ErrUserNotFound,
+		},
+		{
+			name: "外部用户不能使用本地密码",
+			setup: func(t *testing.T, env *testEnv) {
+				user := env.createUser(t, "lemon", models.RoleUser)
+				user.AuthProvider = models.AuthProviderLDAP
+				checkErr(t, env.repos.Users.Update(context.Background(), user), nil)
+			},
+			username: "lemon",
+			password: testPassword,
+			wantErr:  ErrUserNotFound,
+		},
+		{
+			name: "账号已停用",
+			setup: func(t *testing.T, env *testEnv) {
+				user := env.createUser(t, "lemon", models.RoleUser)
+				user.Active = false
+				checkErr(t, env.repos.Users.Update(context.Background(), user), nil)
+			},
+			username: "lemon",
+			password: testPassword,
+			wantErr:  ErrUserDisabled,
+		},
+		{
+			name: "连续失败后锁定",
+			setup: func(t *testing.T, env *testEnv) {
+				env.createUser(t, "lemon", models.RoleUser)
+				guard := env.loginGuard()
+				for range DefaultLoginGuardPolicy().AccountThreshold {
+					checkErr(t, guard.RecordFailure(context.Background(), AccountKey("lemon"), IPKey("192.0.2.1")), nil)
+				}
+			},
+			username: "lemon",
+			password: testPassword,
+			wantErr:  ErrTooManyAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			tt.setup(t, env)
+			service := NewAuthService(env.repos.Users, []Authenticator{NewLocalAuthenticator(env.repos.Users)}, env.loginGuard(), DefaultPasswordPolicy())
+
+			user, err := service.Login(context.Background(), tt.username, tt.password, "192.0.2.1")
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr == nil && user.Name != tt.username {
+				t.Errorf("user.Name = %q, want %q", user.Name, tt.username)
+			}
+
+			var lockedErr *LoginLockedError
+			if errors.Is(tt.wantErr, ErrTooManyAttempts) && (!errors.As(err, &lockedErr) || lockedErr.RetryAfter <= 0) {
+				t.Errorf("error = %v, want LoginLockedError with RetryAfter", err)
+			}
+		})
+	}
+}
+
+func TestAuthService_LoginRecordsFailures(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "lemon", models.RoleUser)
+	service := NewAuthService(env.repos.Users, []Authenticator{NewLocalAuthenticator(env.repos.Users)}, env.loginGuard(), DefaultPasswordPolicy())
+	ctx := context.Background()
+
+	// 达到阈值的那次失败仍然返回密码错误，之后的登录才被锁定
+	for range DefaultLoginGuardPolicy().AccountThreshold {
+		_, err := service.Login(ctx, "lemon", "wrong-password1", "192.0.2.1")
+		checkErr(t, err, ErrInvalidPassword)
+	}
+
+	_, err := service.Login(ctx, "LEMON", testPassword, "192.0.2.1")
+	checkErr(t, err, ErrTooManyAttempts)
+
+	// 解除锁定后登录成功，并清除账号的失败记录
+	_, err = env.repos.LoginThrottles.Delete(ctx, AccountKey("lemon"))
+	checkErr(t, err, nil)
+	_, err = service.Login(ctx, "lemon", testPassword, "192.0.2.1")
+	checkErr(t, err, nil)
+
+	throttles, err := env.repos.LoginThrottles.GetByKeys(ctx, []string{AccountKey("lemon"), IPKey("192.0.2.1")})
+	checkErr(t, err, nil)
+	if len(throttles) != 1 || throttles[0].Key != IPKey("192.0.2.1") {
+		t.Errorf("throttles = %+v, want only the IP record", throttles)
+	}
+}
+
+// slowThrottleRepo 读取后等待，模拟数据库往返延迟，放大并发请求交错的窗口
+type slowThrottleRepo struct {
+	repositories.LoginThrottleRepository
+}
+
+func (r slowThrottleRepo) GetByKeys(ctx context.Context, keys []string) ([]*models.LoginThrottle, error) {
+	defer time.Sleep(time.Millisecond)
+	return r.LoginThrottleRepository.GetByKeys(ctx, keys)
+}
+
+func (r slowThrottleRepo) Increment(ctx context.Context, key string, now, resetBefore time.Time) (*models.LoginThrottle, error) {
+	defer time.Sleep(time.Millisecond)
+	return r.LoginThrottleRepository.Increment(ctx, key, now, resetBefore)
+}
+
+func TestLoginGuard_ConcurrentFailures(t *testing.T) {
+	env := newTestEnv(t)
+	guard := NewLoginGuard(slowThrottleRepo{env.repos.LoginThrottles}, DefaultLoginGuardPolicy())
+	ctx := context.Background()
+	policy := DefaultLoginGuardPolicy()
+
+	// 每个IP只失败一次，并发执行时计数仍然全部累计
+	const attempts = 50
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Go(func() {
+			checkErr(t, guard.RecordFailure(ctx, AccountKey("lemon"), IPKey(fmt.Sprintf("192.0.2.%d", i))), nil)
+		})
+	}
+	wg.Wait()
+
+	throttles, err := env.repos.LoginThrottles.GetByKeys(ctx, []string{AccountKey("lemon")})
+	checkErr(t, err, nil)
+	if len(throttles) != 1 || throttles[0].Failures != attempts {
+		t.Fatalf("throttles = %+v, want %d failures", throttles, attempts)
+	}
+	// 锁定时长按最终的失败次数计算
+	if until := throttles[0].LockedUntil; until == nil || time.Until(*until) <= policy.MaxDelay-time.Minute {
+		t.Errorf("LockedUntil = %v, want about %s from now", until, policy.MaxDelay)
+	}
+	checkErr(t, guard.Check(ctx, AccountKey("lemon"), IPKey("198.51.100.1")), ErrTooManyAttempts)
+}
+
+func TestAuthService_LoginAuthenticatorOrder(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, "lemon", models.RoleUser)
+	directoryUser := &models.User{ID: 99, Name: "lime", AuthProvider: models.AuthProviderLDAP, Active: true}
+	service := NewAuthService(env.repos.Users, []Authenticator{
+		NewLocalAuthenticator(env.repos.Users),
+		&stubAuthenticator{user: directoryUser, password: "directory-pass1"},
+	}, env.loginGuard(), DefaultPasswordPolicy())
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantID   int
+		wantErr  error
+	}{
+		{name: "本地用户", username: "lemon", password: testPassword, wantID: 1},
+		{name: "本地不认识时尝试下一个", username: "lime", password: "directory-pass1", wantID: 99},
+		{name: "下一个认证方式密码错误", username: "lime", password: testPassword, wantErr: ErrInvalidPassword},
+		{name: "都不认识", username: "nobody", password: testPassword, wantErr: ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := service.Login(context.Background(), tt.username, tt.password, "192.0.2.1")
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr == nil && user.ID != tt.wantID {
+				t.Errorf("user.ID = %d, want %d", user.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestAuthService_Register(t *testing.T) {
+	tests := []struct {
+		name       string
+		username   string
+		password   string
+		wantErr    error
+		wantFields []string
+	}{
+		{name: "注册成功", username: "lime", password: testPassword},
+		{name: "用户名已存在", username: "Lemon", password: testPassword, wantErr: ErrUserExists},
+		{name: "用户名格式错误", username: "1lime", password: testPassword, wantErr: ErrInvalidInput, wantFields: []string{"username"}},
+		{name: "密码太弱", username: "lime", password: "short", wantErr: ErrInvalidInput, wantFields: []string{"password"}},
+		{name: "常见密码", username: "lime", password: "password123", wantErr: ErrInvalidInput, wantFields: []string{"password"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.createUser(t, "lemon", models.RoleUser)
+			service := NewAuthService(env.repos.Users, nil, env.loginGuard(), DefaultPasswordPolicy())
+
+			err := service.Register(context.Background(), tt.username, tt.password)
+			checkErr(t, err, tt.wantErr)
+
+			if len(tt.wantFields) > 0 {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("error = %v, want ValidationError", err)
+				}
+				for _, field := range tt.wantFields {
+					found := false
+					for _, f := range validationErr.Fields {
+						found = found || f.Field == field
+					}
+					if !found {
+						t.Errorf("fields = %+v, want an error for %q", validationErr.Fields, field)
+					}
+				}
+			}
+
+			if tt.wantErr == nil {
+				user, err := env.repos.Users.GetByUsername(context.Background(), tt.username)
+				checkErr(t, err, nil)
+				if user.Role != models.RoleUser || !user.Active || user.Password == tt.password {
+					t.Errorf("registered user = %+v", user)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Authenticator 用户名密码认证方式
+// 不认识该用户时返回ErrUserNotFound，AuthService会继续尝试下一个认证方式；
+// 密码错误时返回ErrInvalidPassword
+type Authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+}
+
+// LocalAuthenticator 使用本地保存的bcrypt哈希认证，只处理本地用户
+type LocalAuthenticator struct {
+	userRepo repositories.UserRepository
+}
+
+func NewLocalAuthenticator(userRepo repositories.UserRepository) *LocalAuthenticator {
+	return &LocalAuthenticator{userRepo: userRepo}
+}
+
+// Authenticate
+func (a *LocalAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := a.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
+	}
+	if user.AuthProvider != models.AuthProviderLocal {
+		return nil, ErrUserNotFound
+	}
+
+	// 验证密码
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	return user, nil
+}
@@ -1,84 +1,104 @@
-package services
-
-import (
-	"errors"
-	"fmt"
-	"library-system/models"
-	"library-system/repositories"
-
-	"gorm.io/gorm"
-)
-
-type BookService struct {
-	bookRepo repositories.BookRepository
-}
-
-func NewBookService(bookRepo repositories.BookRepository) *BookService {
-	return &BookService{bookRepo: bookRepo}
-}
-
-// GetAllBooks
-func (s *BookService) GetAllBooks() ([]*models.Book, error) {
-	books, err := s.bookRepo.GetAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get all books: %w", err)
-	}
-
-	return books, nil
-}
-
-// GetBookInfoByID
-func (s *BookService) GetBookInfoByID(bookID int) (*models.Book, error) {
-	book, err := s.bookRepo.GetByID(bookID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrBookNotFound
-		}
-		return nil, fmt.Errorf("failed to get book by ID: %w", err)
-	}
-
-	return book, nil
-}
-
-// GetBookInfoByTitle
-func (s *BookService) GetBookInfoByTitle(title string) (*models.Book, error) {
-	book, err := s.bookRepo.GetByTitle(title)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrBookNotFound
-		}
-		return nil, fmt.Errorf("failed to get book by title: %w", err)
-	}
-
-	return book, nil
-}
-
-// SearchBooksByKeyword
-func (s *BookService) SearchBooksByKeyword(keyword string) ([]*models.Book, error) {
-	books, err := s.bookRepo.SearchByKeyword(keyword)
-	if err != nil {
-		return []*models.Book{}, fmt.Errorf("failed to search books by keyword: %w", err)
-	}
-
-	return books, nil
-}
-
-// SearchBooksByTitleKeyword
-func (s *BookService) SearchBooksByTitleKeyword(titlekeyword string) ([]*models.Book, error) {
-	books, err := s.bookRepo.SearchByTitleKeyword(titlekeyword)
-	if err != nil {
-		return []*models.Book{}, fmt.Errorf("failed to search books by title keyword: %w", err)
-	}
-
-	return books, nil
-}
-
-// SearchBooksByAuthor
-func (s *BookService) SearchBooksByAuthor(author string) ([]*models.Book, error) {
-	books, err := s.bookRepo.SearchByAuthor(author)
-	if err != nil {
-		return []*models.Book{}, fmt.Errorf("failed to search books by author: %w", err)
-	}
-
-	return books, nil
-}
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+type BookService struct {
+	bookRepo repositories.BookRepository
+}
+
+func NewBookService(bookRepo repositories.BookRepository) *BookService {
+	return &BookService{bookRepo: bookRepo}
+}
+
+// GetAllBooks
+func (s *BookService) GetAllBooks(ctx context.Context) (_ []*models.Book, err error) {
+	ctx, span := startSpan(ctx, "BookService.GetAllBooks")
+	defer endSpan(span, &err)
+
+	books, err := s.bookRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all books: %w", err)
+	}
+
+	return books, nil
+}
+
+// GetBookInfoByID
+func (s *BookService) GetBookInfoByID(ctx context.Context, bookID int) (_ *models.Book, err error) {
+	ctx, span := startSpan(ctx, "BookService.GetBookInfoByID", attribute.Int("book.id", bookID))
+	defer endSpan(span, &err)
+
+	book, err := s.bookRepo.GetByID(ctx, bookID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookNotFound
+		}
+		return nil, fmt.Errorf("failed to get book by ID: %w", err)
+	}
+
+	return book, nil
+}
+
+// GetBookInfoByTitle
+func (s *BookService) GetBookInfoByTitle(ctx context.Context, title string) (_ *models.Book, err error) {
+	ctx, span := startSpan(ctx, "BookService.GetBookInfoByTitle")
+	defer endSpan(span, &err)
+
+	book, err := s.bookRepo.GetByTitle(ctx, title)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookNotFound
+		}
+		return nil, fmt.Errorf("failed to get book by title: %w", err)
+	}
+
+	return book, nil
+}
+
+// SearchBooksByKeyword
+func (s *BookService) SearchBooksByKeyword(ctx context.Context, keyword string) (_ []*models.Book, err error) {
+	ctx, span := startSpan(ctx, "BookService.SearchBooksByKeyword")
+	defer endSpan(span, &err)
+
+	books, err := s.bookRepo.SearchByKeyword(ctx, keyword)
+	if err != nil {
+		return []*models.Book{}, fmt.Errorf("failed to search books by keyword: %w", err)
+	}
+
+	return books, nil
+}
+
+// SearchBooksByTitleKeyword
+func (s *BookService) SearchBooksByTitleKeyword(ctx context.Context, titlekeyword string) (_ []*models.Book, err error) {
+	ctx, span := startSpan(ctx, "BookService.SearchBooksByTitleKeyword")
+	defer endSpan(span, &err)
+
+	books, err := s.bookRepo.SearchByTitleKeyword(ctx, titlekeyword)
+	if err != nil {
+		return []*models.Book{}, fmt.Errorf("failed to search books by title keyword: %w", err)
+	}
+
+	return books, nil
+}
+
+// SearchBooksByAuthor
+func (s *BookService) SearchBooksByAuthor(ctx context.Context, author string) (_ []*models.Book, err error) {
+	ctx, span := startSpan(ctx, "BookService.SearchBooksByAuthor")
+	defer endSpan(span, &err)
+
+	books, err := s.bookRepo.SearchByAuthor(ctx, author)
+	if err != nil {
+		return []*models.Book{}, fmt.Errorf("failed to search books by author: %w", err)
+	}
+
+	return books, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"library-system/models"
+	"slices"
+	"testing"
+)
+
+func TestBookService(t *testing.T) {
+	env := newTestEnv(t)
+	goBook := env.createBook(t, "Go语言编程", 3)
+	env.createBook(t, "Rust权威指南", 1)
+	service := NewBookService(env.repos.Books)
+	ctx := context.Background()
+
+	titles := func(books []*models.Book) []string {
+		result := make([]string, 0, len(books))
+		for _, b := range books {
+			result = append(result, b.Title)
+		}
+		return result
+	}
+
+	t.Run("GetAllBooks", func(t *testing.T) {
+		books, err := service.GetAllBooks(ctx)
+		checkErr(t, err, nil)
+		if len(books) != 2 {
+			t.Errorf("len(books) = %d, want 2", len(books))
+		}
+	})
+
+	getTests := []struct {
+		name    string
+		get     func() (*models.Book, error)
+		wantErr error
+	}{
+		{name: "按ID查询", get: func() (*models.Book, error) { return service.GetBookInfoByID(ctx, goBook.ID) }},
+		{name: "ID不存在", get: func() (*models.Book, error) { return service.GetBookInfoByID(ctx, 404) }, wantErr: ErrBookNotFound},
+		{name: "按书名查询", get: func() (*models.Book, error) { return service.GetBookInfoByTitle(ctx, "go语言编程") }},
+		{name: "书名不存在", get: func() (*models.Book, error) { return service.GetBookInfoByTitle(ctx, "不存在") }, wantErr: ErrBookNotFound},
+	}
+	for _, tt := range getTests {
+		t.Run(tt.name, func(t *testing.T) {
+			book, err := tt.get()
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr == nil && book.ID != goBook.ID {
+				t.Errorf("book.ID = %d, want %d", book.ID, goBook.ID)
+			}
+		})
+	}
+
+	searchTests := []struct {
+		name   string
+		search func() ([]*models.Book, error)
+		want   []string
+	}{
+		{name: "关键字匹配书名", search: func() ([]*models.Book, error) { return service.SearchBooksByKeyword(ctx, "rust") }, want: []string{"Rust权威指南"}},
+		{name: "关键字匹配作者", search: func() ([]*models.Book, error) { return service.SearchBooksByKeyword(ctx, "lemon") }, want: []string{"Go语言编程", "Rust权威指南"}},
+		{name: "书名关键字", search: func() ([]*models.Book, error) { return service.SearchBooksByTitleKeyword(ctx, "编程") }, want: []string{"Go语言编程"}},
+		{name: "书名关键字不匹配作者", search: func() ([]*models.Book, error) { return service.SearchBooksByTitleKeyword(ctx, "lemon") }, want: []string{}},
+		{name: "作者", search: func() ([]*models.Book, error) { return service.SearchBooksByAuthor(ctx, "Lemon") }, want: []string{"Go语言编程", "Rust权威指南"}},
+		{name: "作者不存在", search: func() ([]*models.Book, error) { return service.SearchBooksByAuthor(ctx, "Lime") }, want: []string{}},
+	}
+	for _, tt := range searchTests {
+		t.Run(tt.name, func(t *testing.T) {
+			books, err := tt.search()
+			checkErr(t, err, nil)
+			if got := titles(books); !slices.Equal(got, tt.want) {
+				t.Errorf("titles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,156 +1,411 @@
-package services
-
-import (
-	"errors"
-	"fmt"
-	"library-system/models"
-	"library-system/repositories"
-	"time"
-
-	"gorm.io/gorm"
-)
-
-type BorrowService struct {
-	db *gorm.DB
-}
-
-func NewBorrowService(db *gorm.DB) *BorrowService {
-	return &BorrowService{
-		db: db,
-	}
-}
-
-// BorrowBook
-func (s *BorrowService) BorrowBook(userID int, bookID int) error {
-	// 参数基础校验
-	if userID <= 0 || bookID <= 0 {
-		return ErrInvalidInput
-	}
-
-	// 事务处理
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 创建仓库实例
-		txBookRepo := repositories.NewBookRepository(tx)
-		txRecordRepo := repositories.NewBorrowRecordRepository(tx)
-
-		// 查找图书
-		book, err := txBookRepo.GetByID(bookID)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return ErrBookNotFound
-			}
-			return fmt.Errorf("failed to get book by ID: %w", err)
-		}
-
-		// 检查图书是否可借
-		if book.Stock <= 0 {
-			return ErrStockNotEnough
-		}
-
-		// 检查用户借书是否已达上限
-		Count, err := txRecordRepo.CountActiveBorrowsByUserID(userID)
-		if err != nil {
-			return fmt.Errorf("failed to count active borrows by user ID: %w", err)
-		}
-		if Count >= 5 {
-			return ErrBorrowLimit
-		}
-
-		// 库存-1
-		book.Stock--
-		if err := txBookRepo.Update(book); err != nil {
-			return fmt.Errorf("failed to update book stock: %w", err)
-		}
-
-		// 创建新记录
-		newRecord := &models.BorrowRecord{
-			UserID:     userID,
-			BookID:     bookID,
-			BorrowedAt: time.Now(),
-			DueDate:    time.Now().AddDate(0, 1, 0),
-		}
-		if err := txRecordRepo.Create(newRecord); err != nil {
-			return fmt.Errorf("failed to create borrow record: %w", err)
-		}
-
-		return nil
-	})
-}
-
-// ReturnBook
-func (s *BorrowService) ReturnBook(recordID int, currentUserID int) error {
-	// 参数基础校验
-	if recordID <= 0 || currentUserID <= 0 {
-		return ErrInvalidInput
-	}
-
-	// 事务处理
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 创建仓库实例
-		txBookRepo := repositories.NewBookRepository(tx)
-		txRecordRepo := repositories.NewBorrowRecordRepository(tx)
-
-		// 查找记录
-		record, err := txRecordRepo.GetByID(recordID)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return ErrRecordNotFound
-			}
-			return fmt.Errorf("failed to get borrow record by ID: %w", err)
-		}
-
-		// 检查权限
-		if record.UserID != currentUserID {
-			return ErrPermissionDenied
-		}
-
-		// 检查记录是否已经归还
-		if record.ReturnedAt != nil {
-			return ErrAlreadyReturned
-		}
-
-		// 更新图书库存
-		book, err := txBookRepo.GetByID(record.BookID)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return ErrBookNotFound
-			}
-			return fmt.Errorf("failed to get book by ID: %w", err)
-		}
-
-		book.Stock++
-		if err := txBookRepo.Update(book); err != nil {
-			return fmt.Errorf("failed to update book stock: %w", err)
-		}
-
-		// 更新借阅记录
-		currentTime := time.Now()
-		record.ReturnedAt = &currentTime
-		if err := txRecordRepo.Update(record); err != nil {
-			return fmt.Errorf("failed to update borrow record: %w", err)
-		}
-
-		return nil
-	})
-}
-
-// GetUserBorrowRecords
-func (s *BorrowService) GetUserBorrowRecords(userID int) ([]*models.BorrowRecord, error) {
-	// 参数基础校验
-	if userID <= 0 {
-		return nil, ErrInvalidInput
-	}
-
-	// 创建仓库实例
-	RecordRepo := repositories.NewBorrowRecordRepository(s.db)
-
-	records, err := RecordRepo.GetByUserID(userID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return []*models.BorrowRecord{}, ErrRecordNotFound
-		}
-		return nil, fmt.Errorf("failed to get borrow records by user ID: %w", err)
-	}
-
-	return records, nil
-}
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/metrics"
+	"library-system/models"
+	"library-system/repositories"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// LoanPolicy 借阅规则
+type LoanPolicy struct {
+	// 每位用户同时在借的图书上限
+	MaxActiveBorrows int
+	// 借阅期限（天）
+	LoanDays int
+}
+
+// DefaultLoanPolicy 默认每人最多借5本，借期30天
+func DefaultLoanPolicy() LoanPolicy {
+	return LoanPolicy{
+		MaxActiveBorrows: 5,
+		LoanDays:         30,
+	}
+}
+
+var (
+	loansCreated = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Name: "library_loans_created_total",
+		Help: "借出的图书数量",
+	})
+	loansReturned = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "library_loans_returned_total",
+		Help: "归还的图书数量，overdue表示是否逾期归还",
+	}, []string{"overdue"})
+)
+
+type BorrowService struct {
+	transactor repositories.Transactor
+	recordRepo repositories.BorrowRecordRepository
+	loanPolicy LoanPolicy
+	// 借还书成功后通知借阅者，为nil时不发送通知
+	notifications *NotificationService
+	// 借还书成功后发布库存变化和借阅事件，为nil时不发布
+	events *EventBus
+}
+
+func NewBorrowService(transactor repositories.Transactor, recordRepo repositories.BorrowRecordRepository, loanPolicy LoanPolicy, notifications *NotificationService, events *EventBus) *BorrowService {
+	return &BorrowService{
+		transactor:    transactor,
+		recordRepo:    recordRepo,
+		loanPolicy:    loanPolicy,
+		notifications: notifications,
+		events:        events,
+	}
+}
+
+// RegisterMetrics 注册逾期未还数量指标，每次抓取时查询数据库
+func (s *BorrowService) RegisterMetrics(reg prometheus.Registerer) error {
+	return reg.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "library_loans_overdue",
+		Help: "当前逾期未还的借阅数量",
+	}, func() float64 {
+		// 抓取接口没有context，限制查询时间以免拖慢抓取
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		count, err := s.recordRepo.CountOverdue(ctx, time.Now())
+		if err != nil {
+			slog.Error("统计逾期借阅失败", slog.Any("error", err))
+			return math.NaN()
+		}
+		return float64(count)
+	}))
+}
+
+// BorrowBook
+func (s *BorrowService) BorrowBook(ctx context.Context, userID int, bookID int) (err error) {
+	ctx, span := startSpan(ctx, "BorrowService.BorrowBook", attribute.Int("user.id", userID), attribute.Int("book.id", bookID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 || bookID <= 0 {
+		return ErrInvalidInput
+	}
+
+	_, err = s.borrow(ctx, "borrow_book", bookID, nil, func(repositories.Repositories) (int, error) {
+		return userID, nil
+	})
+	return err
+}
+
+// CheckOut 馆员在服务台为读者借书，读者由用户ID或借书证号指定（二选一）
+func (s *BorrowService) CheckOut(ctx context.Context, staffID, patronID int, cardNumber string, bookID int) (_ *models.BorrowRecord, err error) {
+	ctx, span := startSpan(ctx, "BorrowService.CheckOut", attribute.Int("staff.id", staffID), attribute.Int("book.id", bookID))
+	defer endSpan(span, &err)
+
+	cardNumber = strings.TrimSpace(cardNumber)
+
+	// 参数基础校验
+	if staffID <= 0 {
+		return nil, ErrInvalidInput
+	}
+	var fields []FieldError
+	switch {
+	case patronID == 0 && cardNumber == "":
+		fields = append(fields, NewFieldError("user_id", "required", "user_id"))
+	case patronID < 0 || (patronID != 0 && cardNumber != ""):
+		fields = append(fields, NewFieldError("user_id", "invalid", "user_id"))
+	}
+	if bookID <= 0 {
+		fields = append(fields, NewFieldError("book_id", "invalid", "book_id"))
+	}
+	if err := newValidationError(fields); err != nil {
+		return nil, err
+	}
+
+	return s.borrow(ctx, "check_out", bookID, &staffID, func(repos repositories.Repositories) (int, error) {
+		// 查找读者
+		var patron *models.User
+		var err error
+		if cardNumber != "" {
+			patron, err = repos.Users.GetByCardNumber(ctx, cardNumber)
+		} else {
+			patron, err = repos.Users.GetByUserID(ctx, patronID)
+		}
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, ErrUserNotFound
+			}
+			return 0, fmt.Errorf("failed to get patron: %w", err)
+		}
+
+		// 停用的账号不能借书
+		if !patron.Active {
+			return 0, ErrUserDisabled
+		}
+		return patron.ID, nil
+	})
+}
+
+// borrow 在事务中借出图书，patron返回借阅者ID；staffID不为nil时记录经办的馆员
+func (s *BorrowService) borrow(ctx context.Context, name string, bookID int, staffID *int, patron func(repos repositories.Repositories) (int, error)) (*models.BorrowRecord, error) {
+	// 事务处理
+	var newRecord *models.BorrowRecord
+	var stock int
+	err := s.transactor.WithinTransaction(ctx, name, func(repos repositories.Repositories) error {
+		// 事务内的仓库
+		txBookRepo := repos.Books
+		txRecordRepo := repos.BorrowRecords
+
+		userID, err := patron(repos)
+		if err != nil {
+			return err
+		}
+
+		// 查找图书
+		book, err := txBookRepo.GetByID(ctx, bookID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBookNotFound
+			}
+			return fmt.Errorf("failed to get book by ID: %w", err)
+		}
+
+		// 检查图书是否可借
+		if book.Stock <= 0 {
+			return ErrStockNotEnough
+		}
+
+		// 检查用户借书是否已达上限
+		Count, err := txRecordRepo.CountActiveBorrowsByUserID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to count active borrows by user ID: %w", err)
+		}
+		if Count >= int64(s.loanPolicy.MaxActiveBorrows) {
+			return ErrBorrowLimit
+		}
+
+		// 库存-1
+		book.Stock--
+		if err := txBookRepo.Update(ctx, book); err != nil {
+			return fmt.Errorf("failed to update book stock: %w", err)
+		}
+		stock = book.Stock
+
+		// 创建新记录
+		newRecord = &models.BorrowRecord{
+			UserID:       userID,
+			BookID:       bookID,
+			BorrowedAt:   time.Now(),
+			DueDate:      time.Now().AddDate(0, 0, s.loanPolicy.LoanDays),
+			CheckedOutBy: staffID,
+		}
+		if err := txRecordRepo.Create(ctx, newRecord); err != nil {
+			return fmt.Errorf("failed to create borrow record: %w", err)
+		}
+
+		return recordEvent(ctx, repos.Outbox, models.EventLoanCreated, newRecord)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loansCreated.Inc()
+	// 提交后再通知，避免事务回滚后仍然发出通知
+	if s.notifications != nil {
+		s.notifications.LoanCreated(ctx, newRecord)
+	}
+	s.publish(models.EventLoanCreated, newRecord, stock)
+	return newRecord, nil
+}
+
+// ReturnBook
+func (s *BorrowService) ReturnBook(ctx context.Context, recordID int, currentUserID int) (err error) {
+	ctx, span := startSpan(ctx, "BorrowService.ReturnBook", attribute.Int("borrow_record.id", recordID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if recordID <= 0 || currentUserID <= 0 {
+		return ErrInvalidInput
+	}
+
+	_, err = s.giveBack(ctx, "return_book", nil, func(repos repositories.Repositories) (*models.BorrowRecord, error) {
+		record, err := getRecord(ctx, repos.BorrowRecords, recordID)
+		if err != nil {
+			return nil, err
+		}
+
+		// 检查权限
+		if record.UserID != currentUserID {
+			return nil, ErrPermissionDenied
+		}
+		return record, nil
+	})
+	return err
+}
+
+// CheckIn 馆员在服务台归还图书（如还书箱中的图书），不要求是借阅者本人
+// 借阅记录由recordID指定，或由cardNumber（借书证号）和bookID指定，二者只能选一种
+func (s *BorrowService) CheckIn(ctx context.Context, staffID, recordID int, cardNumber string, bookID int) (_ *models.BorrowRecord, err error) {
+	ctx, span := startSpan(ctx, "BorrowService.CheckIn", attribute.Int("staff.id", staffID), attribute.Int("borrow_record.id", recordID), attribute.Int("book.id", bookID))
+	defer endSpan(span, &err)
+
+	cardNumber = strings.TrimSpace(cardNumber)
+
+	// 参数基础校验
+	if staffID <= 0 {
+		return nil, ErrInvalidInput
+	}
+	var fields []FieldError
+	switch {
+	case recordID != 0:
+		if recordID < 0 || cardNumber != "" || bookID != 0 {
+			fields = append(fields, NewFieldError("record_id", "invalid", "record_id"))
+		}
+	case cardNumber == "" && bookID == 0:
+		fields = append(fields, NewFieldError("record_id", "required", "record_id"))
+	default:
+		if cardNumber == "" {
+			fields = append(fields, NewFieldError("card_number", "required", "card_number"))
+		}
+		if bookID <= 0 {
+			fields = append(fields, NewFieldError("book_id", "invalid", "book_id"))
+		}
+	}
+	if err := newValidationError(fields); err != nil {
+		return nil, err
+	}
+
+	return s.giveBack(ctx, "check_in", &staffID, func(repos repositories.Repositories) (*models.BorrowRecord, error) {
+		if recordID != 0 {
+			return getRecord(ctx, repos.BorrowRecords, recordID)
+		}
+
+		// 查找读者，已停用的读者也可以还书
+		patron, err := repos.Users.GetByCardNumber(ctx, cardNumber)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrUserNotFound
+			}
+			return nil, fmt.Errorf("failed to get patron: %w", err)
+		}
+
+		// 同一本书借了多册时先归还应还日期最早的一册
+		records, err := repos.BorrowRecords.GetActiveByUserID(ctx, patron.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get active borrow records by user ID: %w", err)
+		}
+		for _, record := range records {
+			if record.BookID == bookID {
+				return record, nil
+			}
+		}
+		return nil, ErrRecordNotFound
+	})
+}
+
+// getRecord 查找借阅记录
+func getRecord(ctx context.Context, recordRepo repositories.BorrowRecordRepository, recordID int) (*models.BorrowRecord, error) {
+	record, err := recordRepo.GetByID(ctx, recordID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to get borrow record by ID: %w", err)
+	}
+	return record, nil
+}
+
+// giveBack 在事务中归还图书，lookup查找要归还的记录并检查调用方能否归还；staffID不为nil时记录经办的馆员
+func (s *BorrowService) giveBack(ctx context.Context, name string, staffID *int, lookup func(repos repositories.Repositories) (*models.BorrowRecord, error)) (*models.BorrowRecord, error) {
+	// 事务处理
+	var overdue bool
+	var returned *models.BorrowRecord
+	var stock int
+	err := s.transactor.WithinTransaction(ctx, name, func(repos repositories.Repositories) error {
+		// 事务内的仓库
+		txBookRepo := repos.Books
+		txRecordRepo := repos.BorrowRecords
+
+		// 查找记录
+		record, err := lookup(repos)
+		if err != nil {
+			return err
+		}
+
+		// 检查记录是否已经归还
+		if record.ReturnedAt != nil {
+			return ErrAlreadyReturned
+		}
+
+		// 更新图书库存
+		book, err := txBookRepo.GetByID(ctx, record.BookID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBookNotFound
+			}
+			return fmt.Errorf("failed to get book by ID: %w", err)
+		}
+
+		book.Stock++
+		if err := txBookRepo.Update(ctx, book); err != nil {
+			return fmt.Errorf("failed to update book stock: %w", err)
+		}
+		stock = book.Stock
+
+		// 更新借阅记录
+		currentTime := time.Now()
+		record.ReturnedAt = &currentTime
+		record.CheckedInBy = staffID
+		overdue = currentTime.After(record.DueDate)
+		if err := txRecordRepo.Update(ctx, record); err != nil {
+			return fmt.Errorf("failed to update borrow record: %w", err)
+		}
+		returned = record
+
+		return recordEvent(ctx, repos.Outbox, models.EventLoanReturned, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loansReturned.WithLabelValues(strconv.FormatBool(overdue)).Inc()
+	if s.notifications != nil {
+		s.notifications.LoanReturned(ctx, returned)
+	}
+	s.publish(models.EventLoanReturned, returned, stock)
+	return returned, nil
+}
+
+// publish 提交后发布借阅事件和借还后的库存
+func (s *BorrowService) publish(eventType string, record *models.BorrowRecord, stock int) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(eventType, record.BookID, record)
+	s.events.Publish(BusEventAvailability, record.BookID, Availability{BookID: record.BookID, Stock: stock})
+}
+
+// GetUserBorrowRecords
+func (s *BorrowService) GetUserBorrowRecords(ctx context.Context, userID int) (_ []*models.BorrowRecord, err error) {
+	ctx, span := startSpan(ctx, "BorrowService.GetUserBorrowRecords", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	records, err := s.recordRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return []*models.BorrowRecord{}, ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to get borrow records by user ID: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"library-system/models"
+	"testing"
+	"time"
+)
+
+func TestBorrowService_BorrowBook(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup 返回借书的用户ID和图书ID
+		setup     func(t *testing.T, env *testEnv) (userID, bookID int)
+		wantErr   error
+		wantStock int
+	}{
+		{
+			name: "借书成功",
+			setup: func(t *testing.T, env *testEnv) (int, int) {
+				return env.createUser(t, "lemon", models.RoleUser).ID, env.createBook(t, "Go", 2).ID
+			},
+			wantStock: 1,
+		},
+		{
+			name:    "无效的参数",
+			setup:   func(t *testing.T, env *testEnv) (int, int) { return 0, 1 },
+			wantErr: ErrInvalidInput,
+		},
+		{
+			name: "图书不存在",
+			setup: func(t *testing.T, env *testEnv) (int, int) {
+				return env.createUser(t, "lemon", models.RoleUser).ID, 404
+			},
+			wantErr: ErrBookNotFound,
+		},
+		{
+			name: "库存不足",
+			setup: func(t *testing.T, env *testEnv) (int, int) {
+				return env.createUser(t, "lemon", models.RoleUser).ID, env.createBook(t, "Go", 0).ID
+			},
+			wantErr:   ErrStockNotEnough,
+			wantStock: 0,
+		},
+		{
+			name: "借书数量已达上限",
+			setup: func(t *testing.T, env *testEnv) (int, int) {
+				user := env.createUser(t, "lemon", models.RoleUser)
+				other := env.createBook(t, "Rust", 5)
+				for range DefaultLoanPolicy().MaxActiveBorrows {
+					env.createRecord(t, user.ID, other.ID, time.Hour)
+				}
+				return user.ID, env.createBook(t, "Go", 2).ID
+			},
+			wantErr:   ErrBorrowLimit,
+			wantStock: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := NewBorrowService(env.store, env.repos.BorrowRecords, DefaultLoanPolicy(), nil, nil)
+			userID, bookID := tt.setup(t, env)
+
+			err := service.BorrowBook(context.Background(), userID, bookID)
+			checkErr(t, err, tt.wantErr)
+
+			if userID <= 0 || tt.wantErr == ErrBookNotFound {
+				return
+			}
+			if stock := env.getBook(t, bookID).Stock; stock != tt.wantStock {
+				t.Errorf("stock = %d, want %d", stock, tt.wantStock)
+			}
+
+			active, err := env.repos.BorrowRecords.GetActiveByUserID(context.Background(), userID)
+			checkErr(t, err, nil)
+			borrowed := 0
+			for _, record := range active {
+				if record.BookID == bookID {
+					borrowed++
+					if days := record.DueDate.Sub(record.BorrowedAt).Hours() / 24; int(days+0.5) != DefaultLoanPolicy().LoanDays {
+						t.Errorf("loan days = %.1f, want %d", days, DefaultLoanPolicy().LoanDays)
+					}
+				}
+			}
+			want := 0
+			if tt.wantErr == nil {
+				want = 1
+			}
+			if borrowed != want {
+				t.Errorf("borrowed = %d, want %d", borrowed, want)
+			}
+		})
+	}
+}
+
+func TestBorrowService_ReturnBook(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup 返回记录ID和还书的用户ID
+		setup     func(t *testing.T, env *testEnv, book *models.Book) (recordID, userID int)
+		wantErr   error
+		wantStock int
+	}{
+		{
+			name: "还书成功",
+			setup: func(t *testing.T, env *testEnv, book *models.Book) (int, int) {
+				user := env.createUser(t, "lemon", models.RoleUser)
+				return env.createRecord(t, user.ID, book.ID, time.Hour).ID, user.ID
+			},
+			wantStock: 2,
+		},
+		{
+			name: "逾期还书",
+			setup: func(t *testing.T, env *testEnv, book *models.Book) (int, int) {
+				user := env.createUser(t, "lemon", models.RoleUser)
+				return env.createRecord(t, user.ID, book.ID, -time.Hour).ID, user.ID
+			},
+			wantStock: 2,
+		},
+		{
+			name:      "无效的参数",
+			setup:     func(t *testing.T, env *testEnv, book *models.Book) (int, int) { return 1, 0 },
+			wantErr:   ErrInvalidInput,
+			wantStock: 1,
+		},
+		{
+			name: "记录不存在",
+			setup: func(t *testing.T, env *testEnv, book *models.Book) (int, int) {
+				return 404, env.createUser(t, "lemon", models.RoleUser).ID
+			},
+			wantErr:   ErrRecordNotFound,
+			wantStock: 1,
+		},
+		{
+			name: "不能归还他人的借阅",
+			setup: func(t *testing.T, env *testEnv, book *models.Book) (int, int) {
+				owner := env.createUser(t, "lemon", models.RoleUser)
+				other := env.createUser(t, "lime", models.RoleUser)
+				return env.createRecord(t, owner.ID, book.ID, time.Hour).ID, other.ID
+			},
+			wantErr:   ErrPermissionDenied,
+			wantStock: 1,
+		},
+		{
+			name: "已经归还",
+			setup: func(t *testing.T, env *testEnv, book *models.Book) (int, int) {
+				user := env.createUser(t, "lemon", models.RoleUser)
+				record := env.createRecord(t, user.ID, book.ID, time.Hour)
+				returnedAt := time.Now()
+				record.ReturnedAt = &returnedAt
+				checkErr(t, env.repos.BorrowRecords.Update(context.Background(), record), nil)
+				return record.ID, user.ID
+			},
+			wantErr:   ErrAlreadyReturned,
+			wantStock: 1,
+		},
+		{
+			name: "图书已被删除",
+			setup: func(t *testing.T, env *testEnv, book *models.Book) (int, int) {
+				user := env.createUser(t, "lemon", models.RoleUser)
+				return env.createRecord(t, user.ID, 404, time.Hour).ID, user.ID
+			},
+			wantErr:   ErrBookNotFound,
+			wantStock: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := NewBorrowService(env.store, env.repos.BorrowRecords, DefaultLoanPolicy(), nil, nil)
+			book := env.createBook(t, "Go", 1)
+			recordID, userID := tt.setup(t, env, book)
+
+			err := service.ReturnBook(context.Background(), recordID, userID)
+			checkErr(t, err, tt.wantErr)
+
+			if stock := env.getBook(t, book.ID).Stock; stock != tt.wantStock {
+				t.Errorf("stock = %d, want %d", stock, tt.wantStock)
+			}
+			if tt.wantErr == nil {
+				record, err := env.repos.BorrowRecords.GetByID(context.Background(), recordID)
+				checkErr(t, err, nil)
+				if record.ReturnedAt == nil {
+					t.Error("ReturnedAt is nil after return")
+				}
+			}
+		})
+	}
+}
+
+func TestBorrowService_CheckOut(t *testing.T) {
+	tests := []struct {
+		name       string
+		patronID   int
+		cardNumber string
+		bookID     int
+		wantErr    error
+	}{
+		{name: "按用户ID借书", patronID: 1, bookID: 1},
+		{name: "按借书证号借书", cardNumber: " L0001 ", bookID: 1},
+		{name: "缺少读者", bookID: 1, wantErr: ErrInvalidInput},
+		{name: "同时指定用户ID和证号", patronID: 1, cardNumber: "L0001", bookID: 1, wantErr: ErrInvalidInput},
+		{name: "读者不存在", patronID: 404, bookID: 1, wantErr: ErrUserNotFound},
+		{name: "证号不存在", cardNumber: "L404", bookID: 1, wantErr: ErrUserNotFound},
+		{name: "读者已停用", patronID: 3, bookID: 1, wantErr: ErrUserDisabled},
+		{name: "图书不存在", patronID: 1, bookID: 404, wantErr: ErrBookNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := NewBorrowService(env.store, env.repos.BorrowRecords, DefaultLoanPolicy(), nil, nil)
+			ctx := context.Background()
+
+			patron := env.createUser(t, "lemon", models.RoleUser)
+			card := "L0001"
+			patron.CardNumber = &card
+			checkErr(t, env.repos.Users.Update(ctx, patron), nil)
+			staff := env.createUser(t, "admin", models.RoleAdmin)
+			disabled := env.createUser(t, "lime", models.RoleUser)
+			disabled.Active = false
+			checkErr(t, env.repos.Users.Update(ctx, disabled), nil)
+			book := env.createBook(t, "Go", 1)
+
+			record, err := service.CheckOut(ctx, staff.ID, tt.patronID, tt.cardNumber, tt.bookID)
+			checkErr(t, err, tt.wantErr)
+
+			wantStock := 1
+			if tt.wantErr == nil {
+				wantStock = 0
+				if record.UserID != patron.ID || record.BookID != book.ID {
+					t.Errorf("record = %+v, want user %d and book %d", record, patron.ID, book.ID)
+				}
+				if record.CheckedOutBy == nil || *record.CheckedOutBy != staff.ID {
+					t.Errorf("CheckedOutBy = %v, want %d", record.CheckedOutBy, staff.ID)
+				}
+			}
+			if stock := env.getBook(t, book.ID).Stock; stock != wantStock {
+				t.Errorf("stock = %d, want %d", stock, wantStock)
+			}
+		})
+	}
+}
+
+func TestBorrowService_CheckIn(t *testing.T) {
+	env := newTestEnv(t)
+	service := NewBorrowService(env.store, env.repos.BorrowRecords, DefaultLoanPolicy(), nil, nil)
+	ctx := context.Background()
+	patron := env.createUser(t, "lemon", models.RoleUser)
+	staff := env.createUser(t, "admin", models.RoleAdmin)
+	book := env.createBook(t, "Go", 0)
+
+	// 自助借出的图书也可以由馆员归还
+	borrowed := env.createRecord(t, patron.ID, book.ID, time.Hour)
+	record, err := service.CheckIn(ctx, staff.ID, borrowed.ID, "", 0)
+	checkErr(t, err, nil)
+	if record.ReturnedAt == nil || record.CheckedInBy == nil || *record.CheckedInBy != staff.ID {
+		t.Errorf("record = %+v, want returned by staff %d", record, staff.ID)
+	}
+	if record.CheckedOutBy != nil {
+		t.Errorf("CheckedOutBy = %v, want nil for a self-service loan", *record.CheckedOutBy)
+	}
+	if stock := env.getBook(t, book.ID).Stock; stock != 1 {
+		t.Errorf("stock = %d, want 1", stock)
+	}
+
+	_, err = service.CheckIn(ctx, staff.ID, borrowed.ID, "", 0)
+	checkErr(t, err, ErrAlreadyReturned)
+	_, err = service.CheckIn(ctx, staff.ID, 404, "", 0)
+	checkErr(t, err, ErrRecordNotFound)
+	_, err = service.CheckIn(ctx, 0, borrowed.ID, "", 0)
+	checkErr(t, err, ErrInvalidInput)
+
+	// 读者自助还书时不记录经办人
+	selfService := env.createRecord(t, patron.ID, book.ID, time.Hour)
+	checkErr(t, service.ReturnBook(ctx, selfService.ID, patron.ID), nil)
+	returned, err := env.repos.BorrowRecords.GetByID(ctx, selfService.ID)
+	checkErr(t, err, nil)
+	if returned.CheckedInBy != nil {
+		t.Errorf("CheckedInBy = %v, want nil", *returned.CheckedInBy)
+	}
+}
+
+func TestBorrowService_CheckInByCardNumber(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordID   int
+		cardNumber string
+		bookID     int
+		// 为true时按借书证号查到的是另一本书，没有未还的借阅
+		otherBook bool
+		wantErr   error
+	}{
+		{name: "按借书证号和图书还书", cardNumber: " L0001 ", bookID: 1},
+		{name: "缺少借阅记录", wantErr: ErrInvalidInput},
+		{name: "同时指定记录和证号", recordID: 1, cardNumber: "L0001", bookID: 1, wantErr: ErrInvalidInput},
+		{name: "缺少证号", bookID: 1, wantErr: ErrInvalidInput},
+		{name: "缺少图书", cardNumber: "L0001", wantErr: ErrInvalidInput},
+		{name: "证号不存在", cardNumber: "L404", bookID: 1, wantErr: ErrUserNotFound},
+		{name: "没有未还的借阅", cardNumber: "L0001", otherBook: true, wantErr: ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := NewBorrowService(env.store, env.repos.BorrowRecords, DefaultLoanPolicy(), nil, nil)
+			ctx := context.Background()
+
+			// 停用的读者也可以还书
+			patron := env.createUser(t, "lemon", models.RoleUser)
+			card := "L0001"
+			patron.CardNumber = &card
+			patron.Active = false
+			checkErr(t, env.repos.Users.Update(ctx, patron), nil)
+			staff := env.createUser(t, "admin", models.RoleAdmin)
+			book := env.createBook(t, "Go", 0)
+			other := env.createBook(t, "Rust", 0)
+			// 同一本书借了两册
+			later := env.createRecord(t, patron.ID, book.ID, 2*time.Hour)
+			earlier := env.createRecord(t, patron.ID, book.ID, time.Hour)
+			bookID := tt.bookID
+			if tt.otherBook {
+				bookID = other.ID
+			}
+
+			record, err := service.CheckIn(ctx, staff.ID, tt.recordID, tt.cardNumber, bookID)
+			checkErr(t, err, tt.wantErr)
+
+			wantStock := 0
+			if tt.wantErr == nil {
+				wantStock = 1
+				if record.ID != earlier.ID || record.CheckedInBy == nil || *record.CheckedInBy != staff.ID {
+					t.Errorf("record = %+v, want record %d returned by staff %d", record, earlier.ID, staff.ID)
+				}
+			}
+			if stock := env.getBook(t, book.ID).Stock; stock != wantStock {
+				t.Errorf("stock = %d, want %d", stock, wantStock)
+			}
+			got, err := env.repos.BorrowRecords.GetByID(ctx, later.ID)
+			checkErr(t, err, nil)
+			if got.ReturnedAt != nil {
+				t.Error("the later loan was returned")
+			}
+		})
+	}
+}
+
+func TestBorrowService_GetUserBorrowRecords(t *testing.T) {
+	env := newTestEnv(t)
+	service := NewBorrowService(env.store, env.repos.BorrowRecords, DefaultLoanPolicy(), nil, nil)
+	lemon := env.createUser(t, "lemon", models.RoleUser)
+	lime := env.createUser(t, "lime", models.RoleUser)
+	book := env.createBook(t, "Go", 5)
+	env.createRecord(t, lemon.ID, book.ID, time.Hour)
+	env.createRecord(t, lemon.ID, book.ID, time.Hour)
+	env.createRecord(t, lime.ID, book.ID, time.Hour)
+
+	tests := []struct {
+		name    string
+		userID  int
+		want    int
+		wantErr error
+	}{
+		{name: "只返回本人的记录", userID: lemon.ID, want: 2},
+		{name: "没有记录", userID: 404, want: 0},
+		{name: "无效的参数", userID: 0, wantErr: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := service.GetUserBorrowRecords(context.Background(), tt.userID)
+			checkErr(t, err, tt.wantErr)
+			if len(records) != tt.want {
+				t.Errorf("len(records) = %d, want %d", len(records), tt.want)
+			}
+			for _, record := range records {
+				if record.UserID != tt.userID {
+					t.Errorf("record.UserID = %d, want %d", record.UserID, tt.userID)
+				}
+			}
+		})
+	}
+}
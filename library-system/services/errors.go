@@ -1,17 +1,67 @@
-package services
-
-import "errors"
-
-var (
-	ErrUserNotFound     = errors.New("用户不存在")
-	ErrUserExists       = errors.New("用户已存在")
-	ErrInvalidPassword  = errors.New("密码错误")
-	ErrBookNotFound     = errors.New("图书不存在")
-	ErrBookExists       = errors.New("图书已存在")
-	ErrStockNotEnough   = errors.New("库存不足")
-	ErrBorrowLimit      = errors.New("借书数量已达上限")
-	ErrRecordNotFound   = errors.New("借阅记录不存在")
-	ErrAlreadyReturned  = errors.New("图书已归还")
-	ErrPermissionDenied = errors.New("权限不足")
-	ErrInvalidInput     = errors.New("无效的输入参数")
-)
+package services
+
+import "errors"
+
+// Error 业务错误
+// Code是稳定的机器可读错误码，客户端应据此判断错误类型；Message是默认语言的说明，用于日志，
+// 返回给客户端的提示取消息目录中的 error.<小写错误码>
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code, message string) error {
+	return &Error{Code: code, Message: message}
+}
+
+var (
+	ErrUserNotFound      = newError("USER_NOT_FOUND", "用户不存在")
+	ErrUserExists        = newError("USER_EXISTS", "用户已存在")
+	ErrInvalidPassword   = newError("INVALID_PASSWORD", "密码错误")
+	ErrBookNotFound      = newError("BOOK_NOT_FOUND", "图书不存在")
+	ErrBookExists        = newError("BOOK_EXISTS", "图书已存在")
+	ErrStockNotEnough    = newError("OUT_OF_STOCK", "库存不足")
+	ErrBorrowLimit       = newError("BORROW_LIMIT_REACHED", "借书数量已达上限")
+	ErrRecordNotFound    = newError("RECORD_NOT_FOUND", "借阅记录不存在")
+	ErrAlreadyReturned   = newError("ALREADY_RETURNED", "图书已归还")
+	ErrPermissionDenied  = newError("PERMISSION_DENIED", "权限不足")
+	ErrInvalidInput      = newError("VALIDATION_FAILED", "无效的输入参数")
+	ErrEmailExists       = newError("EMAIL_EXISTS", "邮箱已被使用")
+	ErrCardNumberExists  = newError("CARD_NUMBER_EXISTS", "借书证号已被使用")
+	ErrInvalidResetToken = newError("INVALID_RESET_TOKEN", "重置链接无效或已过期")
+	ErrTooManyAttempts   = newError("TOO_MANY_ATTEMPTS", "登录尝试次数过多，请稍后再试")
+	ErrLockoutNotFound   = newError("LOCKOUT_NOT_FOUND", "锁定记录不存在")
+
+	ErrTwoFactorEnabled     = newError("TWO_FACTOR_ALREADY_ENABLED", "两步验证已启用")
+	ErrTwoFactorNotEnabled  = newError("TWO_FACTOR_NOT_ENABLED", "两步验证未启用")
+	ErrTwoFactorNotSetup    = newError("TWO_FACTOR_NOT_SETUP", "请先生成两步验证密钥")
+	ErrInvalidTwoFactorCode = newError("INVALID_TWO_FACTOR_CODE", "验证码错误")
+
+	ErrOIDCLoginFailed  = newError("OIDC_LOGIN_FAILED", "单点登录失败")
+	ErrUserDisabled     = newError("USER_DISABLED", "账号已停用")
+	ErrUsernameConflict = newError("USERNAME_CONFLICT", "用户名已被其他账号占用，请联系管理员")
+
+	ErrNotificationNotFound = newError("NOTIFICATION_NOT_FOUND", "通知不存在")
+
+	ErrJobNotFound = newError("JOB_NOT_FOUND", "任务不存在")
+	ErrJobRunning  = newError("JOB_RUNNING", "任务正在运行或刚刚运行过")
+
+	ErrWebhookNotFound = newError("WEBHOOK_NOT_FOUND", "Webhook不存在")
+
+	// 以下错误不由服务返回，供接口层对外统一报告，避免暴露用户是否存在等细节
+	ErrInvalidCredentials = newError("INVALID_CREDENTIALS", "用户名或密码错误")
+	ErrLoginExpired       = newError("LOGIN_EXPIRED", "登录已过期，请重新输入用户名和密码")
+)
+
+// ErrorCode 返回错误链上第一个业务错误的错误码，非业务错误返回空字符串
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
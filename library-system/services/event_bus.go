@@ -0,0 +1,154 @@
+package services
+
+import (
+	"library-system/metrics"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 事件总线上的事件类型，除库存变化外与models中的领域事件类型一致
+const BusEventAvailability = "availability"
+
+// 每个订阅者缓冲的事件数，写满时断开该订阅者，客户端重连后从历史中补发
+const subscriberBuffer = 64
+
+var streamSubscribers = promauto.With(metrics.Registry).NewGauge(prometheus.GaugeOpts{
+	Name: "library_event_stream_subscribers",
+	Help: "当前订阅事件流的连接数",
+})
+
+// BusEvent 事件总线上的事件
+type BusEvent struct {
+	// 递增的序号，用作SSE的事件ID，客户端断线重连时据此补发
+	ID   uint64
+	Type string
+	// 涉及的图书，用于按图书过滤
+	BookID int
+	// 编码为JSON后发送
+	Data any
+}
+
+// Availability 图书库存变化
+type Availability struct {
+	BookID int `json:"book_id" example:"1"`
+	Stock  int `json:"stock" example:"4"`
+}
+
+// EventBus 进程内的事件广播，保留最近的事件供断线重连的订阅者补发
+// 只包含本实例发布的事件，多实例部署时订阅者只能收到所连接实例上的借还
+type EventBus struct {
+	mu sync.Mutex
+	// 下一个事件的序号，从启动时间开始，重启后不会与之前的序号重复
+	nextID uint64
+	// 最近的事件，最多historySize条
+	history     []BusEvent
+	historySize int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewEventBus historySize为断线重连时最多可补发的事件数
+func NewEventBus(historySize int) *EventBus {
+	return &EventBus{
+		nextID:      uint64(time.Now().UnixMicro()),
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription 一个订阅者，C关闭表示订阅已结束（总线关闭或订阅者处理过慢）
+type Subscription struct {
+	C      <-chan BusEvent
+	c      chan BusEvent
+	filter func(*BusEvent) bool
+	bus    *EventBus
+}
+
+// Publish 广播事件，不会阻塞
+func (b *EventBus) Publish(eventType string, bookID int, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	event := BusEvent{ID: b.nextID, Type: eventType, BookID: bookID, Data: data}
+	b.nextID++
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter(&event) {
+			continue
+		}
+		select {
+		case sub.c <- event:
+		default:
+			// 处理过慢，断开后由客户端带上最后的事件ID重连
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe 订阅满足filter的事件，lastEventID不为0时先返回历史中在它之后的事件
+// complete为false表示lastEventID之后的事件已不在历史中（或lastEventID为0），补发的事件不完整
+// 总线已关闭时返回的订阅C已关闭
+func (b *EventBus) Subscribe(lastEventID uint64, filter func(*BusEvent) bool) (sub *Subscription, replay []BusEvent, complete bool) {
+	c := make(chan BusEvent, subscriberBuffer)
+	sub = &Subscription{C: c, c: c, filter: filter, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(c)
+		return sub, nil, false
+	}
+
+	if lastEventID != 0 {
+		oldest := b.nextID
+		if len(b.history) > 0 {
+			oldest = b.history[0].ID
+		}
+		complete = lastEventID+1 >= oldest
+		for _, event := range b.history {
+			if event.ID > lastEventID && filter(&event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	streamSubscribers.Inc()
+	return sub, replay, complete
+}
+
+// Close 取消订阅，可以重复调用
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// remove 调用方持有锁
+func (b *EventBus) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.c)
+	streamSubscribers.Dec()
+}
+
+// Close 结束全部订阅，之后发布的事件被丢弃；关闭服务时在等待请求完成之前调用
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import "testing"
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus(2)
+	all := func(*BusEvent) bool { return true }
+
+	sub, replay, complete := bus.Subscribe(0, all)
+	if len(replay) != 0 || complete {
+		t.Fatalf("new subscription replay = %v, complete = %v", replay, complete)
+	}
+	bus.Publish(BusEventAvailability, 1, Availability{BookID: 1, Stock: 1})
+	first := <-sub.C
+	bus.Publish(BusEventAvailability, 2, Availability{BookID: 2, Stock: 1})
+	bus.Publish(BusEventAvailability, 1, Availability{BookID: 1, Stock: 0})
+	sub.Close()
+	sub.Close()
+
+	// 补发最后事件之后的事件，只保留最近的2条
+	_, replay, complete = bus.Subscribe(first.ID, func(e *BusEvent) bool { return e.BookID == 1 })
+	if !complete || len(replay) != 1 || replay[0].Data.(Availability).Stock != 0 {
+		t.Errorf("replay = %+v, complete = %v, want the last event of book 1", replay, complete)
+	}
+	_, replay, complete = bus.Subscribe(first.ID-1, all)
+	if complete || len(replay) != 2 {
+		t.Errorf("replay = %+v, complete = %v, want 2 events and incomplete", replay, complete)
+	}
+
+	// 处理过慢的订阅者被断开
+	slow, _, _ := bus.Subscribe(0, all)
+	for range subscriberBuffer + 1 {
+		bus.Publish(BusEventAvailability, 1, Availability{BookID: 1})
+	}
+	count := 0
+	for range slow.C {
+		count++
+	}
+	if count != subscriberBuffer {
+		t.Errorf("slow subscriber received %d events before being dropped, want %d", count, subscriberBuffer)
+	}
+
+	// 关闭后结束全部订阅
+	open, _, _ := bus.Subscribe(0, all)
+	bus.Close()
+	if _, ok := <-open.C; ok {
+		t.Error("subscription still open after Close")
+	}
+	closed, _, _ := bus.Subscribe(0, all)
+	if _, ok := <-closed.C; ok {
+		t.Error("subscription after Close is open")
+	}
+}
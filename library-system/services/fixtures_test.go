@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library-system/models"
+	"library-system/repositories"
+	"library-system/repositories/memory"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试用户的密码，满足默认密码策略
+const testPassword = "lemon2024tree"
+
+// testEnv 基于内存仓库的测试环境
+type testEnv struct {
+	store *memory.Store
+	repos repositories.Repositories
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := memory.NewStore()
+	return &testEnv{store: store, repos: store.Repositories()}
+}
+
+// loginGuard 使用默认策略的登录限制
+func (e *testEnv) loginGuard() *LoginGuard {
+	return NewLoginGuard(e.repos.LoginThrottles, DefaultLoginGuardPolicy())
+}
+
+// createUser 创建本地用户，密码为testPassword
+func (e *testEnv) createUser(t *testing.T, name, role string) *models.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	user := &models.User{
+		Name:         name,
+		Password:     string(hash),
+		Role:         role,
+		AuthProvider: models.AuthProviderLocal,
+		Active:       true,
+	}
+	if err := e.repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %q: %v", name, err)
+	}
+	return user
+}
+
+// createBook
+func (e *testEnv) createBook(t *testing.T, title string, stock int) *models.Book {
+	t.Helper()
+	book := &models.Book{Title: title, Author: "Lemon", Stock: stock}
+	if err := e.repos.Books.Create(context.Background(), book); err != nil {
+		t.Fatalf("create book %q: %v", title, err)
+	}
+	return book
+}
+
+// createRecord 创建借阅记录，dueIn为负数时表示已逾期
+func (e *testEnv) createRecord(t *testing.T, userID, bookID int, dueIn time.Duration) *models.BorrowRecord {
+	t.Helper()
+	now := time.Now()
+	record := &models.BorrowRecord{UserID: userID, BookID: bookID, BorrowedAt: now, DueDate: now.Add(dueIn)}
+	if err := e.repos.BorrowRecords.Create(context.Background(), record); err != nil {
+		t.Fatalf("create borrow record: %v", err)
+	}
+	return record
+}
+
+// getBook
+func (e *testEnv) getBook(t *testing.T, id int) *models.Book {
+	t.Helper()
+	book, err := e.repos.Books.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get book %d: %v", id, err)
+	}
+	return book
+}
+
+// getUser
+func (e *testEnv) getUser(t *testing.T, id int) *models.User {
+	t.Helper()
+	user, err := e.repos.Users.GetByUserID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get user %d: %v", id, err)
+	}
+	return user
+}
+
+// checkErr want为nil时要求没有错误，否则要求errors.Is(err, want)
+func checkErr(t *testing.T, err, want error) {
+	t.Helper()
+	if want == nil {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if !errors.Is(err, want) {
+		t.Fatalf("error = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"library-system/repositories"
+	"time"
+)
+
+// HousekeepingService 清理过期数据，由定时任务调用
+type HousekeepingService struct {
+	sessionRepo repositories.SessionRepository
+}
+
+func NewHousekeepingService(sessionRepo repositories.SessionRepository) *HousekeepingService {
+	return &HousekeepingService{sessionRepo: sessionRepo}
+}
+
+// PurgeExpiredSessions 删除已过期的Session，返回删除的数量
+// 读取时已经拒绝过期的Session，这里只回收存储空间
+func (s *HousekeepingService) PurgeExpiredSessions(ctx context.Context) (deleted int64, err error) {
+	ctx, span := startSpan(ctx, "HousekeepingService.PurgeExpiredSessions")
+	defer endSpan(span, &err)
+
+	deleted, err = s.sessionRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+// LDAPConfig 目录服务配置
+type LDAPConfig struct {
+	// ldap://host:389 或 ldaps://host:636
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	// 用于搜索的服务账号，为空时匿名搜索
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// 登录时查找用户的过滤器，%s会被替换为转义后的用户名
+	UserFilter string
+	// 同步时列出所有用户的过滤器
+	SyncFilter        string
+	UsernameAttribute string
+	EmailAttribute    string
+	GroupAttribute    string
+	// 属于其中任意一个组（DN）的用户映射为管理员
+	AdminGroups []string
+}
+
+// ldapEntry 目录中的一个用户
+type ldapEntry struct {
+	DN       string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// LDAPDialer 建立到目录服务的网络连接，*net.Dialer满足该接口
+type LDAPDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// LDAPDirectory 封装目录服务的连接和搜索
+type LDAPDirectory struct {
+	config LDAPConfig
+	dialer LDAPDialer
+}
+
+// NewLDAPDirectory dialer为nil时使用net.Dialer，测试时可传入连接本地桩服务的实现
+func NewLDAPDirectory(config LDAPConfig, dialer LDAPDialer) *LDAPDirectory {
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: ldap.DefaultTimeout}
+	}
+	if config.UsernameAttribute == "" {
+		config.UsernameAttribute = "uid"
+	}
+	if config.EmailAttribute == "" {
+		config.EmailAttribute = "mail"
+	}
+	if config.GroupAttribute == "" {
+		config.GroupAttribute = "memberOf"
+	}
+	if config.UserFilter == "" {
+		config.UserFilter = "(&(objectClass=person)(" + config.UsernameAttribute + "=%s))"
+	}
+	if config.SyncFilter == "" {
+		config.SyncFilter = "(objectClass=person)"
+	}
+	return &LDAPDirectory{config: config, dialer: dialer}
+}
+
+// ldapConn 绑定到ctx的连接，ctx结束时关闭连接以中断进行中的请求
+type ldapConn struct {
+	*ldap.Conn
+	ctx  context.Context
+	stop func() bool
+}
+
+// Close 解除与ctx的关联并关闭连接
+func (c *ldapConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
+// cause ctx结束导致的失败返回ctx的错误，而不是连接已关闭
+func (c *ldapConn) cause(err error) error {
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// dialAddr 根据URL确定网络类型和地址，未指定端口时使用协议的默认端口
+func dialAddr(u *url.URL) (network, addr string, err error) {
+	switch u.Scheme {
+	case "ldapi":
+		if u.Path == "" || u.Path == "/" {
+			return "unix", "/var/run/slapd/ldapi", nil
+		}
+		return "unix", u.Path, nil
+	case "ldap", "ldaps":
+		port := u.Port()
+		if port == "" {
+			port = ldap.DefaultLdapPort
+			if u.Scheme == "ldaps" {
+				port = ldap.DefaultLdapsPort
+			}
+		}
+		return "tcp", net.JoinHostPort(u.Hostname(), port), nil
+	default:
+		return "", "", fmt.Errorf("unsupported ldap url scheme %q", u.Scheme)
+	}
+}
+
+// connect 建立连接并以服务账号绑定
+func (d *LDAPDirectory) connect(ctx context.Context) (*ldapConn, error) {
+	u, err := url.Parse(d.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ldap url: %w", err)
+	}
+	network, addr, err := dialAddr(u)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: d.config.InsecureSkipVerify, ServerName: u.Hostname()}
+
+	netConn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap server: %w", err)
+	}
+
+	if u.Scheme == "ldaps" {
+		tlsConn := tls.Client(netConn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("failed to connect to ldap server: %w", err)
+		}
+		netConn = tlsConn
+	}
+
+	conn := ldap.NewConn(netConn, u.Scheme == "ldaps")
+	conn.Start()
+	c := &ldapConn{Conn: conn, ctx: ctx, stop: context.AfterFunc(ctx, func() { conn.Close() })}
+
+	if d.config.StartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to start tls: %w", c.cause(err))
+		}
+	}
+
+	if d.config.BindDN != "" {
+		if err := c.Bind(d.config.BindDN, d.config.BindPassword); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to bind service account: %w", c.cause(err))
+		}
+	}
+
+	return c, nil
+}
+
+// findUser 按用户名查找唯一的目录条目
+func (d *LDAPDirectory) findUser(conn *ldapConn, username string) (*ldapEntry, error) {
+	filter := fmt.Sprintf(d.config.UserFilter, ldap.EscapeFilter(username))
+	result, err := conn.Search(d.searchRequest(filter, 2))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search ldap user: %w", conn.cause(err))
+	}
+
+	if len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("ldap filter matched %d entries for %q", len(result.Entries), username)
+	}
+
+	return d.toEntry(result.Entries[0]), nil
+}
+
+// searchUsers 分页列出所有用户
+func (d *LDAPDirectory) searchUsers(ctx context.Context) ([]*ldapEntry, error) {
+	conn, err := d.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result, err := conn.SearchWithPaging(d.searchRequest(d.config.SyncFilter, 0), 500)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search ldap users: %w", conn.cause(err))
+	}
+
+	entries := make([]*ldapEntry, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		entry := d.toEntry(e)
+		if entry.Username != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// searchRequest
+func (d *LDAPDirectory) searchRequest(filter string, sizeLimit int) *ldap.SearchRequest {
+	return ldap.NewSearchRequest(
+		d.config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, sizeLimit, 0, false,
+		filter,
+		[]string{d.config.UsernameAttribute, d.config.EmailAttribute, d.config.GroupAttribute},
+		nil,
+	)
+}
+
+// toEntry
+func (d *LDAPDirectory) toEntry(e *ldap.Entry) *ldapEntry {
+	return &ldapEntry{
+		DN:       e.DN,
+		Username: e.GetAttributeValue(d.config.UsernameAttribute),
+		Email:    e.GetAttributeValue(d.config.EmailAttribute),
+		Groups:   e.GetAttributeValues(d.config.GroupAttribute),
+	}
+}
+
+// role 根据用户组映射角色，组DN比较时忽略大小写
+func (d *LDAPDirectory) role(entry *ldapEntry) string {
+	for _, group := range entry.Groups {
+		if slices.ContainsFunc(d.config.AdminGroups, func(admin string) bool { return strings.EqualFold(admin, group) }) {
+			return models.RoleAdmin
+		}
+	}
+	return models.RoleUser
+}
+
+// syncLDAPUser 根据目录条目创建或更新本地用户，返回用户以及是否为新建
+func syncLDAPUser(ctx context.Context, userRepo repositories.UserRepository, directory *LDAPDirectory, entry *ldapEntry) (*models.User, bool, error) {
+	externalID := strings.ToLower(entry.Username)
+	role := directory.role(entry)
+
+	user, err := userRepo.GetByExternalID(ctx, models.AuthProviderLDAP, externalID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to get user by external ID: %w", err)
+	}
+
+	// 已存在: 同步角色、邮箱并重新启用
+	if err == nil {
+		user.Role = role
+		user.Active = true
+		if entry.Email != "" && emailFree(ctx, userRepo, entry.Email, user.ID) {
+			user.Email = entry.Email
+		}
+		if err := userRepo.Update(ctx, user); err != nil {
+			return nil, false, fmt.Errorf("failed to update user: %w", err)
+		}
+		return user, false, nil
+	}
+
+	// 用户名被其他账号占用时不自动改名: 改名后的账号无法用目录中的用户名登录，由管理员处理冲突
+	if _, err := userRepo.GetByUsername(ctx, entry.Username); err == nil {
+		return nil, false, ErrUsernameConflict
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to check username existence: %w", err)
+	}
+
+	user = &models.User{
+		Name:         entry.Username,
+		Role:         role,
+		AuthProvider: models.AuthProviderLDAP,
+		ExternalID:   &externalID,
+		Active:       true,
+	}
+	if entry.Email != "" && emailFree(ctx, userRepo, entry.Email, 0) {
+		user.Email = entry.Email
+	}
+	if err := userRepo.Create(ctx, user); err != nil {
+		return nil, false, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, true, nil
+}
+
+// emailFree 邮箱未被其他用户使用
+func emailFree(ctx context.Context, userRepo repositories.UserRepository, email string, userID int) bool {
+	existing, err := userRepo.GetByEmail(ctx, email)
+	return errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && existing.ID == userID)
+}
+
+// LDAPAuthenticator 通过目录服务绑定验证密码，首次登录时创建本地用户
+type LDAPAuthenticator struct {
+	directory *LDAPDirectory
+	userRepo  repositories.UserRepository
+}
+
+func NewLDAPAuthenticator(directory *LDAPDirectory, userRepo repositories.UserRepository) *LDAPAuthenticator {
+	return &LDAPAuthenticator{directory: directory, userRepo: userRepo}
+}
+
+// Authenticate
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	// 空密码会被目录服务当作匿名绑定
+	if password == "" {
+		return nil, ErrInvalidPassword
+	}
+
+	conn, err := a.directory.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := a.directory.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// 以用户身份绑定验证密码
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidPassword
+		}
+		return nil, fmt.Errorf("failed to bind ldap user: %w", conn.cause(err))
+	}
+
+	user, _, err := syncLDAPUser(ctx, a.userRepo, a.directory, entry)
+	return user, err
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library-system/models"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	testBaseDN      = "dc=example,dc=com"
+	testBindDN      = "cn=reader,dc=example,dc=com"
+	testBindPass    = "reader-secret"
+	testAdminsGroup = "cn=admins,ou=groups,dc=example,dc=com"
+)
+
+// stubLDAP 本地的目录服务，支持简单绑定、分页搜索以及与/或/非、等值和存在过滤器
+type stubLDAP struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu sync.Mutex
+	// DN对应的密码
+	passwords map[string]string
+	entries   []*ldap.Entry
+	// 服务端每页最多返回的条目数，0表示按客户端请求的大小
+	pageSize int
+	// 非nil时搜索请求等待该通道关闭后才响应
+	hold chan struct{}
+	// 拨号时传入的地址
+	dialed []string
+	// 非nil时拨号失败
+	dialErr error
+	// 收到的搜索请求数
+	searches int
+}
+
+func newStubLDAP(t *testing.T) *stubLDAP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	s := &stubLDAP{t: t, listener: listener, passwords: map[string]string{testBindDN: testBindPass}}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// addUser 添加一个目录用户，groups为其所属组的DN
+func (s *stubLDAP) addUser(uid, email, password string, groups ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dn := "uid=" + uid + ",ou=people," + testBaseDN
+	s.entries = append(s.entries, ldap.NewEntry(dn, map[string][]string{
+		"objectClass": {"person"},
+		"uid":         {uid},
+		"mail":        {email},
+		"memberOf":    groups,
+	}))
+	s.passwords[dn] = password
+}
+
+// directory 连接到本服务的目录，URL中的主机名只用于记录拨号地址
+func (s *stubLDAP) directory(config LDAPConfig) *LDAPDirectory {
+	if config.URL == "" {
+		config.URL = "ldap://directory.test"
+	}
+	if config.BaseDN == "" {
+		config.BaseDN = testBaseDN
+	}
+	if config.BindDN == "" {
+		config.BindDN = testBindDN
+		config.BindPassword = testBindPass
+	}
+	return NewLDAPDirectory(config, s)
+}
+
+// DialContext 实现LDAPDialer，总是连接到本地监听的地址
+func (s *stubLDAP) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	s.mu.Lock()
+	s.dialed = append(s.dialed, addr)
+	err := s.dialErr
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", s.listener.Addr().String())
+}
+
+// serve 处理一个连接上的请求，直到客户端解除绑定或断开
+func (s *stubLDAP) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses [][]byte
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			responses = [][]byte{ldapMessage(id, ldapResult(ldap.ApplicationBindResponse, s.bind(op)))}
+		case ldap.ApplicationSearchRequest:
+			var controls []*ber.Packet
+			if len(packet.Children) > 2 {
+				controls = packet.Children[2].Children
+			}
+			responses = s.search(id, op, controls)
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			responses = [][]byte{ldapMessage(id, ldapResult(ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError))}
+		}
+
+		for _, response := range responses {
+			if _, err := conn.Write(response); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind 简单绑定，空DN为匿名绑定
+func (s *stubLDAP) bind(op *ber.Packet) uint16 {
+	dn := op.Children[1].Data.String()
+	password := op.Children[2].Data.String()
+	if dn == "" {
+		return ldap.LDAPResultSuccess
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if want, ok := s.passwords[dn]; !ok || password == "" || want != password {
+		return ldap.LDAPResultInvalidCredentials
+	}
+	return ldap.LDAPResultSuccess
+}
+
+// search 返回匹配过滤器的条目，超出sizeLimit时返回SizeLimitExceeded
+func (s *stubLDAP) search(id int64, op *ber.Packet, controls []*ber.Packet) [][]byte {
+	s.mu.Lock()
+	s.searches++
+	hold := s.hold
+	s.mu.Unlock()
+	if hold != nil {
+		<-hold
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizeLimit := int(op.Children[3].Value.(int64))
+	filter := op.Children[6]
+	var matched []*ldap.Entry
+	for _, entry := range s.entries {
+		if matchFilter(filter, entry) {
+			matched = append(matched, entry)
+		}
+	}
+
+	code := uint16(ldap.LDAPResultSuccess)
+	if sizeLimit > 0 && len(matched) > sizeLimit {
+		matched, code = matched[:sizeLimit], ldap.LDAPResultSizeLimitExceeded
+	}
+
+	// 分页: cookie为下一页的起始位置
+	var doneControls []*ber.Packet
+	for _, packet := range controls {
+		control, err := ldap.DecodeControl(packet)
+		if err != nil {
+			s.t.Errorf("decode control: %v", err)
+			continue
+		}
+		paging, ok := control.(*ldap.ControlPaging)
+		if !ok {
+			continue
+		}
+		offset, _ := strconv.Atoi(string(paging.Cookie))
+		size := int(paging.PagingSize)
+		if s.pageSize > 0 && size > s.pageSize {
+			size = s.pageSize
+		}
+		// 页大小为0表示放弃分页
+		end := min(offset+size, len(matched))
+		if offset > end {
+			offset = end
+		}
+		next := ldap.NewControlPaging(paging.PagingSize)
+		if end < len(matched) {
+			next.SetCookie([]byte(strconv.Itoa(end)))
+		}
+		matched = matched[offset:end]
+		doneControls = append(doneControls, next.Encode())
+	}
+
+	responses := make([][]byte, 0, len(matched)+1)
+	for _, entry := range matched {
+		responses = append(responses, ldapMessage(id, ldapEntryPacket(entry)))
+	}
+	return append(responses, ldapMessage(id, ldapResult(ldap.ApplicationSearchResultDone, code), doneControls...))
+}
+
+// matchFilter 属性名和值的比较都忽略大小写
+func matchFilter(filter *ber.Packet, entry *ldap.Entry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(filter.Children[0], entry)
+	case ldap.FilterEqualityMatch:
+		value := filter.Children[1].Data.String()
+		for _, v := range entry.GetEqualFoldAttributeValues(filter.Children[0].Data.String()) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(entry.GetEqualFoldAttributeValues(filter.Data.String())) > 0
+	default:
+		return false
+	}
+}
+
+// ldapMessage 编码一条响应消息
+func ldapMessage(id int64, op *ber.Packet, controls ...*ber.Packet) []byte {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	packet.AppendChild(op)
+	if len(controls) > 0 {
+		wrapper := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		for _, control := range controls {
+			wrapper.AppendChild(control)
+		}
+		packet.AppendChild(wrapper)
+	}
+	return packet.Bytes()
+}
+
+// ldapResult 编码LDAPResult
+func ldapResult(tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return op
+}
+
+// ldapEntryPacket 编码SearchResultEntry
+func ldapEntryPacket(entry *ldap.Entry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "DN"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, attribute := range entry.Attributes {
+		seq := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		seq.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attribute.Name, "Type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range attribute.Values {
+			values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		seq.AppendChild(values)
+		attributes.AppendChild(seq)
+	}
+	op.AppendChild(attributes)
+	return op
+}
+
+func TestLDAPAuthenticator_Authenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   LDAPConfig
+		username string
+		password string
+		wantErr  error
+		// wantErr为nil时按错误信息匹配
+		wantErrText string
+		wantName    string
+		wantRole    string
+	}{
+		{name: "首次登录创建用户", username: "alice", password: "alice-pass", wantName: "alice", wantRole: models.RoleUser},
+		{name: "管理员组映射为管理员", username: "bob", password: "bob-pass", wantName: "bob", wantRole: models.RoleAdmin},
+		{name: "用户名被本地用户占用", username: "carol", password: "carol-pass", wantErr: ErrUsernameConflict},
+		{name: "密码错误", username: "alice", password: "wrong", wantErr: ErrInvalidPassword},
+		{name: "空密码不会匿名绑定", username: "alice", password: "", wantErr: ErrInvalidPassword},
+		{name: "用户不存在", username: "nobody", password: "x", wantErr: ErrUserNotFound},
+		{name: "用户名中的通配符被转义", username: "*", password: "alice-pass", wantErr: ErrUserNotFound},
+		{name: "过滤器匹配多个条目", username: "dup", password: "dup-pass", wantErrText: "matched 2 entries"},
+		{
+			name:     "服务账号绑定失败",
+			config:   LDAPConfig{BindDN: testBindDN, BindPassword: "wrong"},
+			username: "alice", password: "alice-pass",
+			wantErrText: "failed to bind service account",
+		},
+		{
+			name:     "不支持的URL",
+			config:   LDAPConfig{URL: "http://directory.test"},
+			username: "alice", password: "alice-pass",
+			wantErrText: "unsupported ldap url scheme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.createUser(t, "carol", models.RoleUser)
+
+			stub := newStubLDAP(t)
+			stub.addUser("alice", "alice@example.com", "alice-pass")
+			stub.addUser("bob", "bob@example.com", "bob-pass", "CN=Admins,OU=Groups,DC=example,DC=com")
+			stub.addUser("carol", "carol@example.com", "carol-pass")
+			stub.addUser("dup", "dup1@example.com", "dup-pass")
+			stub.addUser("dup", "dup2@example.com", "dup-pass")
+
+			tt.config.AdminGroups = []string{testAdminsGroup}
+			authenticator := NewLDAPAuthenticator(stub.directory(tt.config), env.repos.Users)
+			user, err := authenticator.Authenticate(context.Background(), tt.username, tt.password)
+
+			switch {
+			case tt.wantErr != nil:
+				checkErr(t, err, tt.wantErr)
+				return
+			case tt.wantErrText != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrText) {
+					t.Fatalf("Authenticate() error = %v, want error containing %q", err, tt.wantErrText)
+				}
+				return
+			case err != nil:
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+
+			if user.Name != tt.wantName || user.Role != tt.wantRole || user.AuthProvider != models.AuthProviderLDAP || !user.Active {
+				t.Errorf("user = %q/%q/%q/active=%v, want %q/%q/ldap/active", user.Name, user.Role, user.AuthProvider, user.Active, tt.wantName, tt.wantRole)
+			}
+			if user.ExternalID == nil || *user.ExternalID != tt.username {
+				t.Errorf("ExternalID = %v, want %q", user.ExternalID, tt.username)
+			}
+			if user.Email != tt.username+"@example.com" {
+				t.Errorf("Email = %q", user.Email)
+			}
+			// 再次登录复用同一个本地用户
+			again, err := authenticator.Authenticate(context.Background(), tt.username, tt.password)
+			if err != nil || again.ID != user.ID {
+				t.Errorf("second Authenticate() = %v, %v, want user %d", again, err, user.ID)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticator_UsernameConflict(t *testing.T) {
+	env := newTestEnv(t)
+	// 使用同一用户名的单点登录用户，本地认证不处理
+	oidc := &models.User{Name: "carol", Role: models.RoleUser, AuthProvider: models.AuthProviderOIDC, Active: true}
+	checkErr(t, env.repos.Users.Create(context.Background(), oidc), nil)
+
+	stub := newStubLDAP(t)
+	stub.addUser("carol", "carol@example.com", "carol-pass")
+	service := NewAuthService(env.repos.Users, []Authenticator{
+		NewLocalAuthenticator(env.repos.Users),
+		NewLDAPAuthenticator(stub.directory(LDAPConfig{}), env.repos.Users),
+	}, env.loginGuard(), DefaultPasswordPolicy())
+
+	// 冲突报告给用户，由管理员处理，不创建无法登录的改名账号
+	_, err := service.Login(context.Background(), "carol", "carol-pass", "192.0.2.1")
+	checkErr(t, err, ErrUsernameConflict)
+	users, err := env.repos.Users.GetByAuthProvider(context.Background(), models.AuthProviderLDAP)
+	checkErr(t, err, nil)
+	if len(users) != 0 {
+		t.Errorf("ldap users = %+v, want none", users)
+	}
+}
+
+func TestLDAPDirectory_Dial(t *testing.T) {
+	tests := []struct {
+		url      string
+		wantAddr string
+	}{
+		{url: "ldap://directory.test", wantAddr: "directory.test:389"},
+		{url: "ldap://directory.test:1389", wantAddr: "directory.test:1389"},
+		{url: "ldaps://directory.test", wantAddr: "directory.test:636"},
+		{url: "ldap://[::1]", wantAddr: "[::1]:389"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			stub := newStubLDAP(t)
+			stub.dialErr = errors.New("connection refused")
+
+			_, err := stub.directory(LDAPConfig{URL: tt.url}).connect(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "connection refused") {
+				t.Fatalf("connect() error = %v, want dial error", err)
+			}
+			if len(stub.dialed) != 1 || stub.dialed[0] != tt.wantAddr {
+				t.Errorf("dialed = %q, want %q", stub.dialed, tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticator_ContextCanceled(t *testing.T) {
+	env := newTestEnv(t)
+	stub := newStubLDAP(t)
+	stub.addUser("alice", "alice@example.com", "alice-pass")
+	// 目录服务不响应搜索请求
+	hold := make(chan struct{})
+	stub.hold = hold
+	defer close(hold)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewLDAPAuthenticator(stub.directory(LDAPConfig{}), env.repos.Users).Authenticate(ctx, "alice", "alice-pass")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// 不等待客户端自身的请求超时
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Authenticate() returned after %s", elapsed)
+	}
+}
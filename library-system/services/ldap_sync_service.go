@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+	"log/slog"
+	"strings"
+)
+
+// LDAPSyncResult 一次同步的统计
+type LDAPSyncResult struct {
+	Created     int `json:"created"`
+	Updated     int `json:"updated"`
+	Deactivated int `json:"deactivated"`
+	// 用户名被其他账号占用而跳过的目录用户
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// LDAPSyncService 按目录搜索结果创建、更新或停用本地用户
+type LDAPSyncService struct {
+	transactor repositories.Transactor
+	directory  *LDAPDirectory
+}
+
+func NewLDAPSyncService(transactor repositories.Transactor, directory *LDAPDirectory) *LDAPSyncService {
+	return &LDAPSyncService{transactor: transactor, directory: directory}
+}
+
+// Sync 目录中不存在的LDAP用户会被停用并注销所有会话
+func (s *LDAPSyncService) Sync(ctx context.Context) (_ *LDAPSyncResult, err error) {
+	ctx, span := startSpan(ctx, "LDAPSyncService.Sync")
+	defer endSpan(span, &err)
+
+	entries, err := s.directory.searchUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 搜索结果为空多半是配置错误，不能因此停用所有用户
+	if len(entries) == 0 {
+		return nil, errors.New("ldap sync search returned no users")
+	}
+
+	result := &LDAPSyncResult{}
+
+	// 事务处理
+	err = s.transactor.WithinTransaction(ctx, "ldap_sync", func(repos repositories.Repositories) error {
+		// 事务重试时重新统计
+		*result = LDAPSyncResult{}
+
+		// 事务内的仓库
+		txUserRepo := repos.Users
+		txSessionRepo := repos.Sessions
+
+		seen := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			externalID := strings.ToLower(entry.Username)
+			if seen[externalID] {
+				continue
+			}
+			seen[externalID] = true
+
+			_, created, err := syncLDAPUser(ctx, txUserRepo, s.directory, entry)
+			if errors.Is(err, ErrUsernameConflict) {
+				result.Conflicts = append(result.Conflicts, entry.Username)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if created {
+				result.Created++
+			} else {
+				result.Updated++
+			}
+		}
+
+		users, err := txUserRepo.GetByAuthProvider(ctx, models.AuthProviderLDAP)
+		if err != nil {
+			return fmt.Errorf("failed to get ldap users: %w", err)
+		}
+
+		// 停用目录中已不存在的用户
+		for _, user := range users {
+			if !user.Active || user.ExternalID == nil || seen[*user.ExternalID] {
+				continue
+			}
+
+			user.Active = false
+			if err := txUserRepo.Update(ctx, user); err != nil {
+				return fmt.Errorf("failed to deactivate user: %w", err)
+			}
+			if err := txSessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+				return fmt.Errorf("failed to delete user sessions: %w", err)
+			}
+			result.Deactivated++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 冲突需要管理员改名或删除本地账号后，下次同步才会创建
+	for _, username := range result.Conflicts {
+		slog.WarnContext(ctx, "LDAP用户名已被其他账号占用，跳过同步", slog.String("username", username))
+	}
+
+	return result, nil
+}
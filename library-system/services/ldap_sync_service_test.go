@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library-system/models"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// createLDAPUser 创建一个已同步过的LDAP用户，并为其保存一个会话
+func (e *testEnv) createLDAPUser(t *testing.T, name, role string) *models.User {
+	t.Helper()
+	externalID := name
+	user := &models.User{Name: name, Role: role, AuthProvider: models.AuthProviderLDAP, ExternalID: &externalID, Active: true}
+	if err := e.repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %q: %v", name, err)
+	}
+	session := &models.Session{ID: "session-" + name, UserID: user.ID, Data: "{}", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := e.repos.Sessions.Save(context.Background(), session); err != nil {
+		t.Fatalf("save session for %q: %v", name, err)
+	}
+	return user
+}
+
+// hasSession 用户的会话是否仍然存在
+func (e *testEnv) hasSession(t *testing.T, user *models.User) bool {
+	t.Helper()
+	_, err := e.repos.Sessions.GetByID(context.Background(), "session-"+user.Name)
+	return err == nil
+}
+
+func TestLDAPSyncService_Sync(t *testing.T) {
+	env := newTestEnv(t)
+	local := env.createUser(t, "frank", models.RoleUser)
+	// 目录中仍存在，但被降级且此前被停用
+	dave := env.createLDAPUser(t, "dave", models.RoleAdmin)
+	dave.Active = false
+	if err := env.repos.Users.Update(context.Background(), dave); err != nil {
+		t.Fatalf("update dave: %v", err)
+	}
+	// 目录中已不存在
+	erin := env.createLDAPUser(t, "erin", models.RoleUser)
+
+	stub := newStubLDAP(t)
+	stub.pageSize = 2
+	stub.addUser("alice", "alice@example.com", "x")
+	stub.addUser("bob", "bob@example.com", "x", testAdminsGroup)
+	stub.addUser("dave", "dave@example.com", "x")
+	stub.addUser("Alice", "alice2@example.com", "x")
+	stub.addUser("", "nameless@example.com", "x")
+	// 与本地用户同名
+	stub.addUser("frank", "frank@example.com", "x")
+
+	service := NewLDAPSyncService(env.store, stub.directory(LDAPConfig{AdminGroups: []string{testAdminsGroup}}))
+	result, err := service.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// 大小写不同的重复用户名只处理一次，没有用户名的条目被忽略
+	if result.Created != 2 || result.Updated != 1 || result.Deactivated != 1 || !slices.Equal(result.Conflicts, []string{"frank"}) {
+		t.Errorf("result = %+v", *result)
+	}
+	// 6个条目按每页2个分3页返回
+	if stub.searches != 3 {
+		t.Errorf("searches = %d, want 3 pages", stub.searches)
+	}
+
+	if got := env.getUser(t, dave.ID); !got.Active || got.Role != models.RoleUser || got.Email != "dave@example.com" {
+		t.Errorf("dave = active %v, role %q, email %q", got.Active, got.Role, got.Email)
+	}
+	if !env.hasSession(t, dave) {
+		t.Error("dave's session was deleted")
+	}
+	if got := env.getUser(t, erin.ID); got.Active || env.hasSession(t, erin) {
+		t.Errorf("erin = active %v, session kept %v, want deactivated and logged out", got.Active, env.hasSession(t, erin))
+	}
+	// 同名的本地用户保持不变，也不会创建改名后的目录用户
+	if got := env.getUser(t, local.ID); !got.Active || got.AuthProvider != models.AuthProviderLocal {
+		t.Errorf("local user = active %v, provider %q", got.Active, got.AuthProvider)
+	}
+	if _, err := env.repos.Users.GetByExternalID(context.Background(), models.AuthProviderLDAP, "frank"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetByExternalID(frank) error = %v, want not found", err)
+	}
+	if _, err := env.repos.Users.GetByUsername(context.Background(), "ldap_frank"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetByUsername(ldap_frank) error = %v, want not found", err)
+	}
+	bob, err := env.repos.Users.GetByExternalID(context.Background(), models.AuthProviderLDAP, "bob")
+	if err != nil || bob.Role != models.RoleAdmin {
+		t.Errorf("bob = %+v, %v, want admin", bob, err)
+	}
+}
+
+func TestLDAPSyncService_SyncFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(stub *stubLDAP)
+		wantErr string
+	}{
+		{
+			// 过滤器配置错误时目录返回空结果，不能因此停用所有用户
+			name:    "搜索结果为空时拒绝停用",
+			prepare: func(stub *stubLDAP) {},
+			wantErr: "returned no users",
+		},
+		{
+			name: "只有缺少用户名的条目",
+			prepare: func(stub *stubLDAP) {
+				stub.addUser("", "nameless@example.com", "x")
+			},
+			wantErr: "returned no users",
+		},
+		{
+			name: "目录服务不可用",
+			prepare: func(stub *stubLDAP) {
+				stub.dialErr = errors.New("connection refused")
+			},
+			wantErr: "failed to connect to ldap server",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			erin := env.createLDAPUser(t, "erin", models.RoleUser)
+
+			stub := newStubLDAP(t)
+			tt.prepare(stub)
+
+			result, err := NewLDAPSyncService(env.store, stub.directory(LDAPConfig{})).Sync(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Sync() = %+v, %v, want error containing %q", result, err, tt.wantErr)
+			}
+			if got := env.getUser(t, erin.ID); !got.Active || !env.hasSession(t, erin) {
+				t.Errorf("erin = active %v, session kept %v, want untouched", got.Active, env.hasSession(t, erin))
+			}
+		})
+	}
+}
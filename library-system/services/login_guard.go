@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"library-system/repositories"
+	"strings"
+	"time"
+)
+
+// LoginGuardPolicy 登录失败限制策略
+// 失败次数达到阈值后开始锁定，锁定时长从BaseDelay起每次失败翻倍，最长MaxDelay
+type LoginGuardPolicy struct {
+	AccountThreshold int
+	IPThreshold      int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	// 超过该时长没有失败记录时重新计数
+	ResetAfter time.Duration
+}
+
+// DefaultLoginGuardPolicy
+func DefaultLoginGuardPolicy() LoginGuardPolicy {
+	return LoginGuardPolicy{
+		AccountThreshold: 5,
+		IPThreshold:      20,
+		BaseDelay:        time.Minute,
+		MaxDelay:         time.Hour,
+		ResetAfter:       24 * time.Hour,
+	}
+}
+
+// LoginLockedError 账号或IP处于锁定状态，包装ErrTooManyAttempts
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("%s (retry after %s)", ErrTooManyAttempts.Error(), e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+type LoginGuard struct {
+	repo   repositories.LoginThrottleRepository
+	policy LoginGuardPolicy
+}
+
+func NewLoginGuard(repo repositories.LoginThrottleRepository, policy LoginGuardPolicy) *LoginGuard {
+	return &LoginGuard{repo: repo, policy: policy}
+}
+
+// AccountKey
+func AccountKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// IPKey
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check 任意一个key处于锁定状态时返回LoginLockedError
+func (g *LoginGuard) Check(ctx context.Context, keys ...string) error {
+	throttles, err := g.repo.GetByKeys(ctx, keys)
+	if err != nil {
+		return fmt.Errorf("failed to get login throttles: %w", err)
+	}
+
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, t := range throttles {
+		if t.LockedUntil != nil && t.LockedUntil.After(now) {
+			retryAfter = max(retryAfter, t.LockedUntil.Sub(now))
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// RecordFailure 记录一次失败，达到阈值后按指数退避锁定
+// 计数在数据库中原子递增，并发的失败登录同样会累计并触发锁定
+func (g *LoginGuard) RecordFailure(ctx context.Context, accountKey, ipKey string) error {
+	now := time.Now()
+	for _, key := range []string{accountKey, ipKey} {
+		threshold := g.policy.AccountThreshold
+		if key == ipKey {
+			threshold = g.policy.IPThreshold
+		}
+
+		t, err := g.repo.Increment(ctx, key, now, now.Add(-g.policy.ResetAfter))
+		if err != nil {
+			return fmt.Errorf("failed to increment login failures: %w", err)
+		}
+		if t.Failures < threshold {
+			continue
+		}
+		if err := g.repo.ExtendLock(ctx, key, now.Add(g.lockDuration(t.Failures-threshold))); err != nil {
+			return fmt.Errorf("failed to lock login: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess 登录成功后清除账号的失败记录，IP的记录保留
+func (g *LoginGuard) RecordSuccess(ctx context.Context, accountKey string) error {
+	if _, err := g.repo.Delete(ctx, accountKey); err != nil {
+		return fmt.Errorf("failed to clear login throttle: %w", err)
+	}
+	return nil
+}
+
+// lockDuration
+func (g *LoginGuard) lockDuration(exceeded int) time.Duration {
+	d := g.policy.BaseDelay
+	for i := 0; i < exceeded && d < g.policy.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, g.policy.MaxDelay)
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/i18n"
+	"library-system/metrics"
+	"library-system/models"
+	"library-system/repositories"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// NotificationPolicy 通知规则
+type NotificationPolicy struct {
+	// 应还日期前多久发送即将到期提醒
+	DueSoonWindow time.Duration
+}
+
+// DefaultNotificationPolicy 默认到期前3天提醒
+func DefaultNotificationPolicy() NotificationPolicy {
+	return NotificationPolicy{
+		DueSoonWindow: 72 * time.Hour,
+	}
+}
+
+// 站内信列表默认和最多返回的条数
+const (
+	DefaultNotificationLimit = 50
+	MaxNotificationLimit     = 100
+)
+
+var notificationsDelivered = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "library_notifications_delivered_total",
+	Help: "投递的通知数量，result为success或failure",
+}, []string{"type", "channel", "result"})
+
+// NotificationInbox 站内信列表
+type NotificationInbox struct {
+	Notifications []*models.Notification `json:"notifications"`
+	UnreadCount   int64                  `json:"unread_count" example:"3"`
+}
+
+// NotificationScanResult 一次到期扫描发送的提醒数量
+type NotificationScanResult struct {
+	DueSoon int
+	Overdue int
+}
+
+type NotificationService struct {
+	transactor       repositories.Transactor
+	userRepo         repositories.UserRepository
+	bookRepo         repositories.BookRepository
+	recordRepo       repositories.BorrowRecordRepository
+	notificationRepo repositories.NotificationRepository
+	preferenceRepo   repositories.NotificationPreferenceRepository
+	// 渠道名称到投递方式，见 models.ChannelEmail 等
+	notifiers map[string]Notifier
+	policy    NotificationPolicy
+
+	// 正在异步发送的通知
+	sending sync.WaitGroup
+}
+
+func NewNotificationService(transactor repositories.Transactor, userRepo repositories.UserRepository, bookRepo repositories.BookRepository, recordRepo repositories.BorrowRecordRepository, notificationRepo repositories.NotificationRepository, preferenceRepo repositories.NotificationPreferenceRepository, notifiers map[string]Notifier, policy NotificationPolicy) *NotificationService {
+	return &NotificationService{
+		transactor:       transactor,
+		userRepo:         userRepo,
+		bookRepo:         bookRepo,
+		recordRepo:       recordRepo,
+		notificationRepo: notificationRepo,
+		preferenceRepo:   preferenceRepo,
+		notifiers:        notifiers,
+		policy:           policy,
+	}
+}
+
+// LoanCreated 借书成功后通知借阅者
+func (s *NotificationService) LoanCreated(ctx context.Context, record *models.BorrowRecord) {
+	s.notifyAsync(ctx, models.NotificationLoanCreated, record)
+}
+
+// LoanReturned 还书成功后通知借阅者
+func (s *NotificationService) LoanReturned(ctx context.Context, record *models.BorrowRecord) {
+	s.notifyAsync(ctx, models.NotificationLoanReturned, record)
+}
+
+// notifyAsync 异步发送，发送失败只记录日志，不影响借还书的结果
+// 请求结束后继续发送，保留ctx中的语言和日志属性
+func (s *NotificationService) notifyAsync(ctx context.Context, kind string, record *models.BorrowRecord) {
+	ctx = context.WithoutCancel(ctx)
+	s.sending.Go(func() {
+		if err := s.notify(ctx, kind, record); err != nil {
+			slog.ErrorContext(ctx, "通知发送失败", slog.String("type", kind), slog.Int("borrow_record_id", record.ID), slog.Any("error", err))
+		}
+	})
+}
+
+// Wait 等待已提交的通知发送完成，关闭服务时调用
+func (s *NotificationService) Wait() {
+	s.sending.Wait()
+}
+
+// ScanDueDates 为即将到期和逾期未还的借阅发送提醒，每条借阅每种提醒只发送一次
+// 先标记再发送，多个实例同时扫描时只有标记成功的实例发送；发送失败不会重试
+// 标记逾期的同时写入loan.overdue事件
+func (s *NotificationService) ScanDueDates(ctx context.Context) (result NotificationScanResult, err error) {
+	ctx, span := startSpan(ctx, "NotificationService.ScanDueDates")
+	defer endSpan(span, &err)
+
+	now := time.Now()
+
+	// 即将到期
+	dueSoon, err := s.recordRepo.GetUnnotifiedDueSoon(ctx, now, now.Add(s.policy.DueSoonWindow))
+	if err != nil {
+		return result, fmt.Errorf("failed to get due soon borrow records: %w", err)
+	}
+	for _, record := range dueSoon {
+		marked, err := s.recordRepo.MarkDueSoonNotified(ctx, record.ID, now)
+		if err != nil {
+			return result, fmt.Errorf("failed to mark due soon notified: %w", err)
+		}
+		if marked && s.notifyScanned(ctx, models.NotificationDueSoon, record) {
+			result.DueSoon++
+		}
+	}
+
+	// 逾期
+	overdue, err := s.recordRepo.GetUnnotifiedOverdue(ctx, now)
+	if err != nil {
+		return result, fmt.Errorf("failed to get overdue borrow records: %w", err)
+	}
+	for _, record := range overdue {
+		marked, err := s.markOverdue(ctx, record, now)
+		if err != nil {
+			return result, err
+		}
+		if marked && s.notifyScanned(ctx, models.NotificationOverdue, record) {
+			result.Overdue++
+		}
+	}
+
+	return result, nil
+}
+
+// markOverdue 标记成功时在同一事务中写入loan.overdue事件
+func (s *NotificationService) markOverdue(ctx context.Context, record *models.BorrowRecord, now time.Time) (marked bool, err error) {
+	err = s.transactor.WithinTransaction(ctx, "mark_overdue", func(repos repositories.Repositories) error {
+		marked, err = repos.BorrowRecords.MarkOverdueNotified(ctx, record.ID, now)
+		if err != nil {
+			return fmt.Errorf("failed to mark overdue notified: %w", err)
+		}
+		if !marked {
+			return nil
+		}
+		return recordEvent(ctx, repos.Outbox, models.EventLoanOverdue, record)
+	})
+	return marked, err
+}
+
+// notifyScanned 单条提醒发送失败时记录日志，继续处理其他借阅
+func (s *NotificationService) notifyScanned(ctx context.Context, kind string, record *models.BorrowRecord) bool {
+	if err := s.notify(ctx, kind, record); err != nil {
+		slog.ErrorContext(ctx, "通知发送失败", slog.String("type", kind), slog.Int("borrow_record_id", record.ID), slog.Any("error", err))
+		return false
+	}
+	return true
+}
+
+// notify 按借阅者的渠道偏好投递通知，一个渠道失败不影响其他渠道
+// 标题和正文取消息目录中的 notification.<类型>.title/body，使用用户的语言偏好，没有时使用ctx中的语言
+func (s *NotificationService) notify(ctx context.Context, kind string, record *models.BorrowRecord) error {
+	user, err := s.userRepo.GetByUserID(ctx, record.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user by ID: %w", err)
+	}
+	// 停用的账号不再通知
+	if !user.Active {
+		return nil
+	}
+	book, err := s.bookRepo.GetByID(ctx, record.BookID)
+	if err != nil {
+		return fmt.Errorf("failed to get book by ID: %w", err)
+	}
+	preference, err := s.getPreference(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	locale := user.Locale
+	if locale == "" {
+		locale = i18n.FromContext(ctx)
+	}
+	recordID := record.ID
+	notification := &models.Notification{
+		Type:     kind,
+		Title:    i18n.T(locale, "notification."+kind+".title"),
+		Body:     i18n.T(locale, "notification."+kind+".body", user.Name, book.Title, record.DueDate.Format(time.DateOnly)),
+		RecordID: &recordID,
+	}
+
+	var errs []error
+	for _, channel := range slices.Sorted(maps.Keys(s.notifiers)) {
+		if !preference.Enabled(channel) {
+			continue
+		}
+		if err := s.notifiers[channel].Notify(ctx, user, notification); err != nil {
+			notificationsDelivered.WithLabelValues(kind, channel, "failure").Inc()
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+			continue
+		}
+		notificationsDelivered.WithLabelValues(kind, channel, "success").Inc()
+	}
+	return errors.Join(errs...)
+}
+
+// getPreference 没有记录时全部渠道开启
+func (s *NotificationService) getPreference(ctx context.Context, userID int) (*models.NotificationPreference, error) {
+	preference, err := s.preferenceRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.NotificationPreference{UserID: userID, Email: true, InApp: true}, nil
+		}
+		return nil, fmt.Errorf("failed to get notification preference: %w", err)
+	}
+	return preference, nil
+}
+
+// GetInbox 最新的在前，limit不大于0时使用默认值，最多返回MaxNotificationLimit条
+func (s *NotificationService) GetInbox(ctx context.Context, userID int, unreadOnly bool, limit int) (_ *NotificationInbox, err error) {
+	ctx, span := startSpan(ctx, "NotificationService.GetInbox", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+	if limit <= 0 {
+		limit = DefaultNotificationLimit
+	}
+	limit = min(limit, MaxNotificationLimit)
+
+	notifications, err := s.notificationRepo.GetByUserID(ctx, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications by user ID: %w", err)
+	}
+	unread, err := s.notificationRepo.CountUnreadByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	return &NotificationInbox{Notifications: notifications, UnreadCount: unread}, nil
+}
+
+// SetRead 将通知标记为已读或未读，其他用户的通知按不存在处理
+func (s *NotificationService) SetRead(ctx context.Context, userID, notificationID int, read bool) (err error) {
+	ctx, span := startSpan(ctx, "NotificationService.SetRead", attribute.Int("user.id", userID), attribute.Int("notification.id", notificationID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 || notificationID <= 0 {
+		return ErrInvalidInput
+	}
+
+	notification, err := s.notificationRepo.GetByID(ctx, notificationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotificationNotFound
+		}
+		return fmt.Errorf("failed to get notification by ID: %w", err)
+	}
+	if notification.UserID != userID {
+		return ErrNotificationNotFound
+	}
+
+	// 已经是目标状态时不修改，保留首次阅读的时间
+	if read == (notification.ReadAt != nil) {
+		return nil
+	}
+	notification.ReadAt = nil
+	if read {
+		now := time.Now()
+		notification.ReadAt = &now
+	}
+	if err := s.notificationRepo.Update(ctx, notification); err != nil {
+		return fmt.Errorf("failed to update notification: %w", err)
+	}
+	return nil
+}
+
+// MarkAllRead
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int) (err error) {
+	ctx, span := startSpan(ctx, "NotificationService.MarkAllRead", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 {
+		return ErrInvalidInput
+	}
+
+	if err := s.notificationRepo.MarkAllRead(ctx, userID, time.Now()); err != nil {
+		return fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return nil
+}
+
+// GetPreference
+func (s *NotificationService) GetPreference(ctx context.Context, userID int) (_ *models.NotificationPreference, err error) {
+	ctx, span := startSpan(ctx, "NotificationService.GetPreference", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	return s.getPreference(ctx, userID)
+}
+
+// UpdatePreference
+func (s *NotificationService) UpdatePreference(ctx context.Context, userID int, email, inApp bool) (_ *models.NotificationPreference, err error) {
+	ctx, span := startSpan(ctx, "NotificationService.UpdatePreference", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	preference := &models.NotificationPreference{UserID: userID, Email: email, InApp: inApp}
+	if err := s.preferenceRepo.Save(ctx, preference); err != nil {
+		return nil, fmt.Errorf("failed to save notification preference: %w", err)
+	}
+	return preference, nil
+}
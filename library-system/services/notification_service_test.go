@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"library-system/mailer"
+	"library-system/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestNotificationService(env *testEnv, m mailer.Mailer) *NotificationService {
+	return NewNotificationService(env.store, env.repos.Users, env.repos.Books, env.repos.BorrowRecords, env.repos.Notifications, env.repos.NotificationPreferences, map[string]Notifier{
+		models.ChannelEmail: NewMailNotifier(m),
+		models.ChannelInApp: NewInboxNotifier(env.repos.Notifications),
+	}, DefaultNotificationPolicy())
+}
+
+// inbox 返回用户的全部站内信
+func inbox(t *testing.T, service *NotificationService, userID int) *NotificationInbox {
+	t.Helper()
+	result, err := service.GetInbox(context.Background(), userID, false, 0)
+	checkErr(t, err, nil)
+	return result
+}
+
+func TestNotificationService_ScanDueDates(t *testing.T) {
+	env := newTestEnv(t)
+	m := mailer.NewMemoryMailer()
+	service := newTestNotificationService(env, m)
+	ctx := context.Background()
+
+	lemon := env.createUserWithEmail(t, "lemon", "lemon@example.com", models.AuthProviderLocal)
+	book := env.createBook(t, "Go", 5)
+	env.createRecord(t, lemon.ID, book.ID, 24*time.Hour)
+	env.createRecord(t, lemon.ID, book.ID, -24*time.Hour)
+	// 还未进入提醒窗口
+	env.createRecord(t, lemon.ID, book.ID, 10*24*time.Hour)
+	// 已归还的借阅不提醒
+	returned := env.createRecord(t, lemon.ID, book.ID, -24*time.Hour)
+	now := time.Now()
+	returned.ReturnedAt = &now
+	checkErr(t, env.repos.BorrowRecords.Update(ctx, returned), nil)
+
+	result, err := service.ScanDueDates(ctx)
+	checkErr(t, err, nil)
+	if result.DueSoon != 1 || result.Overdue != 1 {
+		t.Fatalf("result = %+v, want 1 due soon and 1 overdue", result)
+	}
+	if got := len(m.Messages()); got != 2 {
+		t.Errorf("sent %d emails, want 2", got)
+	}
+	if got := inbox(t, service, lemon.ID); got.UnreadCount != 2 || len(got.Notifications) != 2 {
+		t.Errorf("inbox = %d unread, %d notifications, want 2, 2", got.UnreadCount, len(got.Notifications))
+	}
+
+	// 再次扫描不重复提醒
+	result, err = service.ScanDueDates(ctx)
+	checkErr(t, err, nil)
+	if result.DueSoon != 0 || result.Overdue != 0 {
+		t.Errorf("second scan result = %+v, want none", result)
+	}
+	if got := len(m.Messages()); got != 2 {
+		t.Errorf("sent %d emails after second scan, want 2", got)
+	}
+}
+
+func TestNotificationService_Preference(t *testing.T) {
+	env := newTestEnv(t)
+	m := mailer.NewMemoryMailer()
+	service := newTestNotificationService(env, m)
+	ctx := context.Background()
+
+	lemon := env.createUserWithEmail(t, "lemon", "lemon@example.com", models.AuthProviderLocal)
+	lemon.Locale = "en-US"
+	checkErr(t, env.repos.Users.Update(ctx, lemon), nil)
+	book := env.createBook(t, "Go", 5)
+
+	// 未设置时全部渠道开启
+	preference, err := service.GetPreference(ctx, lemon.ID)
+	checkErr(t, err, nil)
+	if !preference.Email || !preference.InApp {
+		t.Fatalf("default preference = %+v, want all enabled", preference)
+	}
+
+	// 关闭邮件后只保存站内信，内容使用用户的语言
+	_, err = service.UpdatePreference(ctx, lemon.ID, false, true)
+	checkErr(t, err, nil)
+	service.LoanCreated(ctx, env.createRecord(t, lemon.ID, book.ID, 24*time.Hour))
+	service.Wait()
+
+	if got := len(m.Messages()); got != 0 {
+		t.Errorf("sent %d emails with email disabled, want 0", got)
+	}
+	notifications := inbox(t, service, lemon.ID).Notifications
+	if len(notifications) != 1 {
+		t.Fatalf("len(notifications) = %d, want 1", len(notifications))
+	}
+	if n := notifications[0]; n.Type != models.NotificationLoanCreated || n.Title != "Book borrowed" || !strings.Contains(n.Body, `"Go"`) {
+		t.Errorf("notification = %+v", n)
+	}
+
+	// 全部关闭后不再通知
+	_, err = service.UpdatePreference(ctx, lemon.ID, false, false)
+	checkErr(t, err, nil)
+	service.LoanReturned(ctx, env.createRecord(t, lemon.ID, book.ID, 24*time.Hour))
+	service.Wait()
+	if got := len(inbox(t, service, lemon.ID).Notifications); got != 1 {
+		t.Errorf("len(notifications) = %d after disabling all channels, want 1", got)
+	}
+}
+
+func TestNotificationService_SetRead(t *testing.T) {
+	env := newTestEnv(t)
+	service := newTestNotificationService(env, mailer.NewMemoryMailer())
+	ctx := context.Background()
+
+	lemon := env.createUser(t, "lemon", models.RoleUser)
+	lime := env.createUser(t, "lime", models.RoleUser)
+	book := env.createBook(t, "Go", 5)
+	service.LoanCreated(ctx, env.createRecord(t, lemon.ID, book.ID, 24*time.Hour))
+	service.LoanCreated(ctx, env.createRecord(t, lemon.ID, book.ID, 24*time.Hour))
+	service.Wait()
+	notificationID := inbox(t, service, lemon.ID).Notifications[0].ID
+
+	// 不能修改他人的通知
+	checkErr(t, service.SetRead(ctx, lime.ID, notificationID, true), ErrNotificationNotFound)
+	checkErr(t, service.SetRead(ctx, lemon.ID, 404, true), ErrNotificationNotFound)
+	checkErr(t, service.SetRead(ctx, lemon.ID, 0, true), ErrInvalidInput)
+
+	checkErr(t, service.SetRead(ctx, lemon.ID, notificationID, true), nil)
+	if got := inbox(t, service, lemon.ID).UnreadCount; got != 1 {
+		t.Errorf("unread = %d after marking one read, want 1", got)
+	}
+	unread, err := service.GetInbox(ctx, lemon.ID, true, 0)
+	checkErr(t, err, nil)
+	if len(unread.Notifications) != 1 || unread.Notifications[0].ID == notificationID {
+		t.Errorf("unread notifications = %+v", unread.Notifications)
+	}
+
+	checkErr(t, service.SetRead(ctx, lemon.ID, notificationID, false), nil)
+	if got := inbox(t, service, lemon.ID).UnreadCount; got != 2 {
+		t.Errorf("unread = %d after marking unread, want 2", got)
+	}
+
+	checkErr(t, service.MarkAllRead(ctx, lemon.ID), nil)
+	if got := inbox(t, service, lemon.ID).UnreadCount; got != 0 {
+		t.Errorf("unread = %d after marking all read, want 0", got)
+	}
+}
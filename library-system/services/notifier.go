@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"library-system/mailer"
+	"library-system/models"
+	"library-system/repositories"
+)
+
+// Notifier 通知的投递渠道
+type Notifier interface {
+	Notify(ctx context.Context, user *models.User, notification *models.Notification) error
+}
+
+// MailNotifier 通过邮件投递，SMTP、本地目录或内存由mailer决定；没有邮箱的用户跳过
+type MailNotifier struct {
+	mailer mailer.Mailer
+}
+
+func NewMailNotifier(m mailer.Mailer) *MailNotifier {
+	return &MailNotifier{mailer: m}
+}
+
+// Notify
+func (n *MailNotifier) Notify(ctx context.Context, user *models.User, notification *models.Notification) error {
+	if user.Email == "" {
+		return nil
+	}
+	msg := &mailer.Message{To: user.Email, Subject: notification.Title, Body: notification.Body}
+	if err := n.mailer.Send(msg); err != nil {
+		return fmt.Errorf("failed to send notification mail: %w", err)
+	}
+	return nil
+}
+
+// InboxNotifier 保存为站内信
+type InboxNotifier struct {
+	notificationRepo repositories.NotificationRepository
+}
+
+func NewInboxNotifier(notificationRepo repositories.NotificationRepository) *InboxNotifier {
+	return &InboxNotifier{notificationRepo: notificationRepo}
+}
+
+// Notify
+func (n *InboxNotifier) Notify(ctx context.Context, user *models.User, notification *models.Notification) error {
+	copied := *notification
+	copied.UserID = user.ID
+	if err := n.notificationRepo.Create(ctx, &copied); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// OIDCConfig OpenID Connect单点登录配置
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// ID Token中用户组的声明名称，组内包含AdminGroups任意一个时映射为管理员
+	GroupsClaim string
+	AdminGroups []string
+}
+
+// OIDCAuthRequest 发起授权请求时生成的参数，State、Nonce、Verifier需保存到回调时校验
+type OIDCAuthRequest struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+type OIDCService struct {
+	userRepo   repositories.UserRepository
+	config     OIDCConfig
+	httpClient *http.Client
+
+	// 首次使用时才进行服务发现，身份提供方暂时不可用不影响启动
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// NewOIDCService httpClient为nil时使用http.DefaultClient，测试时可传入指向本地桩服务的客户端
+func NewOIDCService(userRepo repositories.UserRepository, config OIDCConfig, httpClient *http.Client) *OIDCService {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	return &OIDCService{
+		userRepo:   userRepo,
+		config:     config,
+		httpClient: httpClient,
+	}
+}
+
+// AuthCodeURL 生成带state、nonce和PKCE challenge的授权地址
+func (s *OIDCService) AuthCodeURL(ctx context.Context) (_ *OIDCAuthRequest, err error) {
+	ctx, span := startSpan(ctx, "OIDCService.AuthCodeURL")
+	defer endSpan(span, &err)
+
+	oauthConfig, _, err := s.oauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	return &OIDCAuthRequest{
+		URL:      oauthConfig.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+	}, nil
+}
+
+// Exchange 用授权码换取ID Token，校验签名和nonce后返回对应的本地用户，首次登录时自动创建
+func (s *OIDCService) Exchange(ctx context.Context, code, verifier, nonce string) (user *models.User, err error) {
+	ctx, span := startSpan(ctx, "OIDCService.Exchange")
+	defer endSpan(span, &err)
+
+	defer func() { recordLoginAttempt("oidc", err) }()
+
+	// 参数基础校验
+	if code == "" || verifier == "" || nonce == "" {
+		return nil, ErrInvalidInput
+	}
+
+	oauthConfig, provider, err := s.oauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = oidc.ClientContext(ctx, s.httpClient)
+
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to exchange authorization code: %v", ErrOIDCLoginFailed, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrOIDCLoginFailed)
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to verify id token: %v", ErrOIDCLoginFailed, err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("%w: id token nonce mismatch", ErrOIDCLoginFailed)
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode id token claims: %v", ErrOIDCLoginFailed, err)
+	}
+
+	return s.provisionUser(ctx, idToken.Subject, claims)
+}
+
+// oauthConfig 获取（必要时发现）身份提供方并生成OAuth2配置
+func (s *OIDCService) oauthConfig(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.provider == nil {
+		provider, err := oidc.NewProvider(oidc.ClientContext(ctx, s.httpClient), s.config.IssuerURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+		}
+		s.provider = provider
+	}
+
+	return &oauth2.Config{
+		ClientID:     s.config.ClientID,
+		ClientSecret: s.config.ClientSecret,
+		RedirectURL:  s.config.RedirectURL,
+		Endpoint:     s.provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+	}, s.provider, nil
+}
+
+// provisionUser 根据声明查找或创建本地用户，并按用户组同步角色
+func (s *OIDCService) provisionUser(ctx context.Context, subject string, claims map[string]any) (*models.User, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("%w: id token has no subject", ErrOIDCLoginFailed)
+	}
+
+	role := models.RoleUser
+	if s.isAdmin(claims) {
+		role = models.RoleAdmin
+	}
+	email := verifiedEmail(claims)
+
+	user, err := s.userRepo.GetByExternalID(ctx, models.AuthProviderOIDC, subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get user by external ID: %w", err)
+	}
+
+	// 已关联的用户: 同步角色和邮箱
+	if err == nil {
+		if !user.Active {
+			return nil, ErrUserDisabled
+		}
+		user.Role = role
+		if email != "" && s.emailAvailable(ctx, email, user.ID) {
+			user.Email = email
+		}
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+		return user, nil
+	}
+
+	// 首次登录: 自动创建用户
+	username, err := s.availableUsername(ctx, claims, subject)
+	if err != nil {
+		return nil, err
+	}
+	externalID := subject
+	user = &models.User{
+		Name:         username,
+		Role:         role,
+		AuthProvider: models.AuthProviderOIDC,
+		ExternalID:   &externalID,
+		Active:       true,
+	}
+	if email != "" && s.emailAvailable(ctx, email, 0) {
+		user.Email = email
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// isAdmin
+func (s *OIDCService) isAdmin(claims map[string]any) bool {
+	groups, _ := claims[s.config.GroupsClaim].([]any)
+	for _, g := range groups {
+		if name, ok := g.(string); ok && slices.Contains(s.config.AdminGroups, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// availableUsername 优先使用preferred_username，不合法或已被占用时根据subject生成
+func (s *OIDCService) availableUsername(ctx context.Context, claims map[string]any, subject string) (string, error) {
+	if preferred, _ := claims["preferred_username"].(string); preferred != "" && len(ValidateUsername(preferred)) == 0 {
+		_, err := s.userRepo.GetByUsername(ctx, preferred)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return preferred, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check username existence: %w", err)
+		}
+	}
+
+	sum := sha256.Sum256([]byte(s.config.IssuerURL + "|" + subject))
+	return "oidc_" + hex.EncodeToString(sum[:])[:16], nil
+}
+
+// emailAvailable
+func (s *OIDCService) emailAvailable(ctx context.Context, email string, userID int) bool {
+	existing, err := s.userRepo.GetByEmail(ctx, email)
+	return errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && existing.ID == userID)
+}
+
+// verifiedEmail 只使用身份提供方已验证的邮箱
+func verifiedEmail(claims map[string]any) string {
+	email, _ := claims["email"].(string)
+	verified, _ := claims["email_verified"].(bool)
+	if !verified {
+		return ""
+	}
+	return strings.TrimSpace(email)
+}
+
+// randomToken
+func randomToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"library-system/models"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubIssuer 本地的OpenID Connect身份提供方，支持服务发现、JWKS和授权码换取ID Token
+type stubIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu sync.Mutex
+	// 授权码对应的ID Token声明
+	codes map[string]map[string]any
+	// 非nil时用该密钥签名，模拟签名无效
+	signingKey *rsa.PrivateKey
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+
+	s := &stubIssuer{t: t, key: key, codes: make(map[string]map[string]any)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                s.server.URL,
+			"authorization_endpoint":                s.server.URL + "/authorize",
+			"token_endpoint":                        s.server.URL + "/token",
+			"jwks_uri":                              s.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]any{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", s.handleToken)
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// issue 登记一个授权码，换取时返回包含claims的ID Token
+func (s *stubIssuer) issue(claims map[string]any) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := fmt.Sprintf("code-%d", len(s.codes)+1)
+	s.codes[code] = claims
+	return code
+}
+
+// handleToken 授权码只能使用一次，要求携带PKCE verifier
+func (s *stubIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	claims, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	signingKey := s.signingKey
+	s.mu.Unlock()
+
+	if !ok || r.PostForm.Get("code_verifier") == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	if signingKey == nil {
+		signingKey = s.key
+	}
+
+	now := time.Now()
+	payload := map[string]any{
+		"iss": s.server.URL,
+		"aud": "library",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		payload[k] = v
+	}
+
+	writeJSON(w, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     s.sign(signingKey, payload),
+	})
+}
+
+// sign 生成RS256签名的JWT
+func (s *stubIssuer) sign(key *rsa.PrivateKey, payload map[string]any) string {
+	encode := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			s.t.Fatalf("marshal jwt: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signingInput := encode(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"}) + "." + encode(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		s.t.Fatalf("sign jwt: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestOIDCService(env *testEnv, issuer *stubIssuer) *OIDCService {
+	return NewOIDCService(env.repos.Users, OIDCConfig{
+		IssuerURL:    issuer.server.URL,
+		ClientID:     "library",
+		ClientSecret: "secret",
+		RedirectURL:  "https://library.example.com/auth/oidc/callback",
+		AdminGroups:  []string{"library-admins"},
+	}, issuer.server.Client())
+}
+
+func TestOIDCService_AuthCodeURL(t *testing.T) {
+	issuer := newStubIssuer(t)
+	service := newTestOIDCService(newTestEnv(t), issuer)
+
+	req, err := service.AuthCodeURL(context.Background())
+	checkErr(t, err, nil)
+
+	u, err := url.Parse(req.URL)
+	checkErr(t, err, nil)
+	query := u.Query()
+	if !strings.HasPrefix(req.URL, issuer.server.URL+"/authorize?") {
+		t.Errorf("URL = %q, want the authorization endpoint", req.URL)
+	}
+	if query.Get("state") != req.State || query.Get("nonce") != req.Nonce || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("query = %v, want state, nonce and S256 challenge", query)
+	}
+	if req.Verifier == "" || query.Get("code_challenge") == req.Verifier {
+		t.Error("verifier must be kept secret and differ from the challenge")
+	}
+}
+
+func TestOIDCService_Exchange(t *testing.T) {
+	const nonce = "nonce-1"
+
+	tests := []struct {
+		name string
+		// setup 准备已有用户，返回换取使用的授权码和nonce
+		setup     func(t *testing.T, env *testEnv, issuer *stubIssuer) (code, nonce string)
+		wantErr   error
+		wantName  string
+		wantRole  string
+		wantEmail string
+	}{
+		{
+			name: "首次登录创建管理员",
+			setup: func(t *testing.T, env *testEnv, issuer *stubIssuer) (string, string) {
+				return issuer.issue(map[string]any{
+					"sub": "alice-sub", "nonce": nonce, "preferred_username": "alice",
+					"email": "alice@example.com", "email_verified": true, "groups": []string{"library-admins"},
+				}), nonce
+			},
+			wantName:  "alice",
+			wantRole:  models.RoleAdmin,
+			wantEmail: "alice@example.com",
+		},
+		{
+			name: "用户名被占用且邮箱未验证",
+			setup: func(t *testing.T, env *testEnv, issuer *stubIssuer) (string, string) {
+				env.createUser(t, "alice", models.RoleUser)
+				return issuer.issue(map[string]any{
+					"sub": "alice-sub", "nonce": nonce, "preferred_username": "alice",
+					"email": "alice@example.com", "email_verified": false,
+				}), nonce
+			},
+			wantName: "oidc_",
+			wantRole: models.RoleUser,
+		},
+		{
+			name: "已关联用户同步角色",
+			setup: func(t *testing.T, env *testEnv, issuer *stubIssuer) (string, string) {
+				subject := "alice-sub"
+				existing := &models.User{Name: "alice", Role: models.RoleAdmin, AuthProvider: models.AuthProviderOIDC, ExternalID: &subject, Active: true}
+				checkErr(t, env.repos.Users.Create(context.Background(), existing), nil)
+				return issuer.issue(map[string]any{"sub": subject, "nonce": nonce, "preferred_username": "renamed"}), nonce
+			},
+			wantName: "alice",
+			wantRole: models.RoleUser,
+		},
+		{
+			name: "已关联用户被停用",
+			setup: func(t *testing.T, env *testEnv, issuer *stubIssuer) (string, string) {
+				subject := "alice-sub"
+				existing := &models.User{Name: "alice", Role: models.RoleUser, AuthProvider: models.AuthProviderOIDC, ExternalID: &subject, Active: true}
+				checkErr(t, env.repos.Users.Create(context.Background(), existing), nil)
+				existing.Active = false
+				checkErr(t, env.repos.Users.Update(context.Background(), existing), nil)
+				return issuer.issue(map[string]any{"sub": subject, "nonce": nonce}), nonce
+			},
+			wantErr: ErrUserDisabled,
+		},
+		{
+			name: "nonce不匹配",
+			setup: func(t *testing.T, env *testEnv, issuer *stubIssuer) (string, string) {
+				return issuer.issue(map[string]any{"sub": "alice-sub", "nonce": "other"}), nonce
+			},
+			wantErr: ErrOIDCLoginFailed,
+		},
+		{
+			name: "签名无效",
+			setup: func(t *testing.T, env *testEnv, issuer *stubIssuer) (string, string) {
+				other, err := rsa.GenerateKey(rand.Reader, 2048)
+				checkErr(t, err, nil)
+				issuer.signingKey = other
+				return issuer.issue(map[string]any{"sub": "alice-sub", "nonce": nonce}), nonce
+			},
+			wantErr: ErrOIDCLoginFailed,
+		},
+		{
+			name: "授权码无效",
+			setup: func(t *testing.T, env *testEnv, issuer *stubIssuer) (string, string) {
+				return "unknown-code", nonce
+			},
+			wantErr: ErrOIDCLoginFailed,
+		},
+		{
+			name: "参数为空",
+			setup: func(t *testing.T, env *testEnv, issuer *stubIssuer) (string, string) {
+				return "", nonce
+			},
+			wantErr: ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			issuer := newStubIssuer(t)
+			service := newTestOIDCService(env, issuer)
+			code, nonce := tt.setup(t, env, issuer)
+
+			user, err := service.Exchange(context.Background(), code, "verifier", nonce)
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+
+			if !strings.HasPrefix(user.Name, tt.wantName) || user.Role != tt.wantRole || user.Email != tt.wantEmail {
+				t.Errorf("user = %+v, want name %q role %q email %q", user, tt.wantName, tt.wantRole, tt.wantEmail)
+			}
+			linked, err := env.repos.Users.GetByExternalID(context.Background(), models.AuthProviderOIDC, "alice-sub")
+			checkErr(t, err, nil)
+			if linked.ID != user.ID || linked.Role != tt.wantRole {
+				t.Errorf("stored user = %+v, want ID %d role %q", linked, user.ID, tt.wantRole)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+	"time"
+)
+
+// recordEvent 写入领域事件，outbox应为事务内的仓库，事件与业务数据一起提交或回滚
+func recordEvent(ctx context.Context, outbox repositories.OutboxRepository, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	event := &models.OutboxEvent{
+		Type:      eventType,
+		Payload:   string(payload),
+		CreatedAt: time.Now(),
+	}
+	if err := outbox.Create(ctx, event); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// 常见弱密码集合，全部为小写
+var commonPasswords = loadCommonPasswords(commonPasswordsFile)
+
+// 用户名: 以字母开头，只包含字母、数字、下划线、点和连字符
+var usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+const (
+	usernameMinLength = 3
+	usernameMaxLength = 32
+	// bcrypt只使用前72个字节
+	passwordMaxBytes = 72
+)
+
+// PasswordPolicy 密码强度策略
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	RejectCommon  bool
+}
+
+// DefaultPasswordPolicy 默认策略: 至少8位，包含小写字母和数字，不能是常见密码
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    8,
+		RequireLower: true,
+		RequireDigit: true,
+		RejectCommon: true,
+	}
+}
+
+// Validate 校验密码，返回所有未满足的规则
+func (p PasswordPolicy) Validate(field, username, password string) []FieldError {
+	var errs []FieldError
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		errs = append(errs, NewFieldError(field, "min_length", p.MinLength))
+	}
+	if len(password) > passwordMaxBytes {
+		errs = append(errs, NewFieldError(field, "max_length", passwordMaxBytes))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		errs = append(errs, NewFieldError(field, "uppercase"))
+	}
+	if p.RequireLower && !hasLower {
+		errs = append(errs, NewFieldError(field, "lowercase"))
+	}
+	if p.RequireDigit && !hasDigit {
+		errs = append(errs, NewFieldError(field, "digit"))
+	}
+	if p.RequireSymbol && !hasSymbol {
+		errs = append(errs, NewFieldError(field, "symbol"))
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		errs = append(errs, NewFieldError(field, "not_username"))
+	}
+	if p.RejectCommon {
+		if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+			errs = append(errs, NewFieldError(field, "common"))
+		}
+	}
+
+	return errs
+}
+
+// ValidateUsername 校验用户名格式
+func ValidateUsername(username string) []FieldError {
+	var errs []FieldError
+
+	length := utf8.RuneCountInString(username)
+	if length < usernameMinLength || length > usernameMaxLength {
+		errs = append(errs, NewFieldError("username", "length", usernameMinLength, usernameMaxLength))
+	}
+	if !usernamePattern.MatchString(username) {
+		errs = append(errs, NewFieldError("username", "format"))
+	}
+
+	return errs
+}
+
+// loadCommonPasswords
+func loadCommonPasswords(content string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = struct{}{}
+	}
+	return passwords
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"library-system/i18n"
+	"library-system/mailer"
+	"library-system/models"
+	"library-system/repositories"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type PasswordResetService struct {
+	transactor     repositories.Transactor
+	userRepo       repositories.UserRepository
+	tokenRepo      repositories.PasswordResetTokenRepository
+	mailer         mailer.Mailer
+	passwordPolicy PasswordPolicy
+	resetURL       string
+	tokenTTL       time.Duration
+
+	// 正在异步发送的邮件
+	sending sync.WaitGroup
+}
+
+func NewPasswordResetService(transactor repositories.Transactor, userRepo repositories.UserRepository, tokenRepo repositories.PasswordResetTokenRepository, m mailer.Mailer, passwordPolicy PasswordPolicy, resetURL string, tokenTTL time.Duration) *PasswordResetService {
+	return &PasswordResetService{
+		transactor:     transactor,
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		mailer:         m,
+		passwordPolicy: passwordPolicy,
+		resetURL:       resetURL,
+		tokenTTL:       tokenTTL,
+	}
+}
+
+// RequestReset 为邮箱对应的用户生成重置令牌并发送邮件
+// 邮箱不存在时同样返回nil，调用方无法据此判断用户是否存在
+func (s *PasswordResetService) RequestReset(ctx context.Context, email string) (err error) {
+	ctx, span := startSpan(ctx, "PasswordResetService.RequestReset")
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return ErrInvalidInput
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	// 外部身份提供方的用户没有本地密码
+	if user.AuthProvider != models.AuthProviderLocal {
+		return nil
+	}
+
+	// 生成令牌，数据库中只保存哈希
+	token, err := newResetToken()
+	if err != nil {
+		return err
+	}
+	record := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(s.tokenTTL),
+	}
+	if err := s.tokenRepo.Create(ctx, record); err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	// 邮件使用用户的语言偏好，没有时使用请求的语言
+	locale := user.Locale
+	if locale == "" {
+		locale = i18n.FromContext(ctx)
+	}
+
+	// 异步发送邮件，避免响应时间暴露用户是否存在
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: i18n.T(locale, "mail.password_reset.subject"),
+		Body:    i18n.T(locale, "mail.password_reset.body", user.Name, int(s.tokenTTL.Minutes()), s.resetURL, token),
+	}
+	s.sending.Go(func() {
+		if err := s.mailer.Send(msg); err != nil {
+			slog.Error("重置密码邮件发送失败", slog.Int("user_id", user.ID), slog.Any("error", err))
+		}
+	})
+
+	return nil
+}
+
+// Wait 等待已提交的邮件发送完成，关闭服务时调用
+func (s *PasswordResetService) Wait() {
+	s.sending.Wait()
+}
+
+// ConfirmReset 校验令牌并设置新密码，同时吊销该用户的所有Session
+func (s *PasswordResetService) ConfirmReset(ctx context.Context, token, newPassword string) (err error) {
+	ctx, span := startSpan(ctx, "PasswordResetService.ConfirmReset")
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if token == "" || newPassword == "" {
+		return ErrInvalidInput
+	}
+
+	// 事务处理
+	return s.transactor.WithinTransaction(ctx, "password_reset_confirm", func(repos repositories.Repositories) error {
+		// 事务内的仓库
+		txUserRepo := repos.Users
+		txTokenRepo := repos.PasswordResetTokens
+		txSessionRepo := repos.Sessions
+
+		// 查找令牌
+		record, err := txTokenRepo.GetByTokenHash(ctx, hashResetToken(token))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return fmt.Errorf("failed to get password reset token: %w", err)
+		}
+
+		// 检查令牌是否已使用或过期
+		now := time.Now()
+		if record.UsedAt != nil || record.ExpiresAt.Before(now) {
+			return ErrInvalidResetToken
+		}
+
+		user, err := txUserRepo.GetByUserID(ctx, record.UserID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return fmt.Errorf("failed to get user by ID: %w", err)
+		}
+
+		// 校验新密码强度
+		if err := newValidationError(s.passwordPolicy.Validate("new_password", user.Name, newPassword)); err != nil {
+			return err
+		}
+
+		// 加密新密码
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		user.Password = string(hashedPassword)
+		if err := txUserRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		// 令牌只能使用一次，同时作废该用户的其他令牌
+		if err := txTokenRepo.InvalidateByUserID(ctx, user.ID, now); err != nil {
+			return fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+		}
+
+		// 吊销已有Session
+		if err := txSessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// newResetToken 生成URL安全的随机令牌
+func newResetToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashResetToken
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
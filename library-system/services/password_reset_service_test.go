@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"library-system/mailer"
+	"library-system/models"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var resetTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func newTestPasswordResetService(env *testEnv, m mailer.Mailer) *PasswordResetService {
+	return NewPasswordResetService(env.store, env.repos.Users, env.repos.PasswordResetTokens, m, DefaultPasswordPolicy(), "https://library.example.com/reset", 30*time.Minute)
+}
+
+// createUserWithEmail
+func (e *testEnv) createUserWithEmail(t *testing.T, name, email, provider string) *models.User {
+	t.Helper()
+	user := e.createUser(t, name, models.RoleUser)
+	user.Email = email
+	user.AuthProvider = provider
+	if err := e.repos.Users.Update(context.Background(), user); err != nil {
+		t.Fatalf("update user %q: %v", name, err)
+	}
+	return user
+}
+
+// requestResetToken 申请重置并从邮件中取出令牌
+func requestResetToken(t *testing.T, service *PasswordResetService, m *mailer.MemoryMailer, email string) string {
+	t.Helper()
+	checkErr(t, service.RequestReset(context.Background(), email), nil)
+	service.Wait()
+
+	messages := m.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no reset email sent")
+	}
+	match := resetTokenPattern.FindStringSubmatch(messages[len(messages)-1].Body)
+	if match == nil {
+		t.Fatalf("no token in email body: %q", messages[len(messages)-1].Body)
+	}
+	return match[1]
+}
+
+func TestPasswordResetService_RequestReset(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantErr  error
+		wantSent bool
+	}{
+		{name: "发送重置邮件", email: "lemon@example.com", wantSent: true},
+		{name: "邮箱不区分大小写", email: " LEMON@example.com ", wantSent: true},
+		{name: "邮箱不存在时不暴露", email: "nobody@example.com", wantSent: false},
+		{name: "外部用户没有本地密码", email: "lime@example.com", wantSent: false},
+		{name: "邮箱为空", email: " ", wantErr: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			m := mailer.NewMemoryMailer()
+			service := newTestPasswordResetService(env, m)
+			env.createUserWithEmail(t, "lemon", "lemon@example.com", models.AuthProviderLocal)
+			env.createUserWithEmail(t, "lime", "lime@example.com", models.AuthProviderOIDC)
+
+			checkErr(t, service.RequestReset(context.Background(), tt.email), tt.wantErr)
+			service.Wait()
+
+			messages := m.Messages()
+			if sent := len(messages) > 0; sent != tt.wantSent {
+				t.Fatalf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			if tt.wantSent && messages[0].To != "lemon@example.com" {
+				t.Errorf("To = %q, want lemon@example.com", messages[0].To)
+			}
+		})
+	}
+}
+
+func TestPasswordResetService_ConfirmReset(t *testing.T) {
+	const newPassword = "orange2025juice"
+
+	tests := []struct {
+		name string
+		// prepare 返回提交的令牌，可在确认之前修改令牌数据
+		prepare     func(t *testing.T, env *testEnv, token string) string
+		newPassword string
+		wantErr     error
+	}{
+		{
+			name:        "重置成功",
+			prepare:     func(t *testing.T, env *testEnv, token string) string { return token },
+			newPassword: newPassword,
+		},
+		{
+			name:        "令牌错误",
+			prepare:     func(t *testing.T, env *testEnv, token string) string { return "not-a-token" },
+			newPassword: newPassword,
+			wantErr:     ErrInvalidResetToken,
+		},
+		{
+			name: "令牌已过期",
+			prepare: func(t *testing.T, env *testEnv, token string) string {
+				expired := &models.PasswordResetToken{UserID: 1, TokenHash: hashResetToken("expired-token"), ExpiresAt: time.Now().Add(-time.Minute)}
+				checkErr(t, env.repos.PasswordResetTokens.Create(context.Background(), expired), nil)
+				return "expired-token"
+			},
+			newPassword: newPassword,
+			wantErr:     ErrInvalidResetToken,
+		},
+		{
+			name: "令牌已使用",
+			prepare: func(t *testing.T, env *testEnv, token string) string {
+				checkErr(t, env.repos.PasswordResetTokens.InvalidateByUserID(context.Background(), 1, time.Now()), nil)
+				return token
+			},
+			newPassword: newPassword,
+			wantErr:     ErrInvalidResetToken,
+		},
+		{
+			name:        "新密码太弱",
+			prepare:     func(t *testing.T, env *testEnv, token string) string { return token },
+			newPassword: "short",
+			wantErr:     ErrInvalidInput,
+		},
+		{
+			name:        "参数为空",
+			prepare:     func(t *testing.T, env *testEnv, token string) string { return "" },
+			newPassword: newPassword,
+			wantErr:     ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			m := mailer.NewMemoryMailer()
+			service := newTestPasswordResetService(env, m)
+			user := env.createUserWithEmail(t, "lemon", "lemon@example.com", models.AuthProviderLocal)
+			ctx := context.Background()
+			session := &models.Session{ID: "session-1", UserID: user.ID, Data: "{}", ExpiresAt: time.Now().Add(time.Hour)}
+			checkErr(t, env.repos.Sessions.Save(ctx, session), nil)
+
+			token := tt.prepare(t, env, requestResetToken(t, service, m, "lemon@example.com"))
+			err := service.ConfirmReset(ctx, token, tt.newPassword)
+			checkErr(t, err, tt.wantErr)
+
+			want := testPassword
+			if tt.wantErr == nil {
+				want = tt.newPassword
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(env.getUser(t, user.ID).Password), []byte(want)); err != nil {
+				t.Errorf("stored password does not match %q", want)
+			}
+
+			_, err = env.repos.Sessions.GetByID(ctx, session.ID)
+			if tt.wantErr == nil {
+				// 重置后吊销已有Session，令牌不能再次使用
+				checkErr(t, err, gorm.ErrRecordNotFound)
+				checkErr(t, service.ConfirmReset(ctx, token, "another2025pass"), ErrInvalidResetToken)
+			} else {
+				checkErr(t, err, nil)
+			}
+		})
+	}
+}
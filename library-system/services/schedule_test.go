@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// 2024-01-15是周一
+	from := time.Date(2024, 1, 15, 10, 30, 20, 0, time.UTC)
+
+	tests := []struct {
+		spec    string
+		want    time.Time
+		wantErr bool
+	}{
+		{spec: "* * * * *", want: time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{spec: "0 * * * *", want: time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", want: time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{spec: "5/20 * * * *", want: time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{spec: "30 3 * * *", want: time.Date(2024, 1, 16, 3, 30, 0, 0, time.UTC)},
+		{spec: "0 9-17/4 * * *", want: time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 * *", want: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 8 * * 6,7", want: time.Date(2024, 1, 20, 8, 0, 0, 0, time.UTC)},
+		// 日和周都指定时满足其一即可
+		{spec: "0 0 20 * 3", want: time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{spec: "@every 1h", want: time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{spec: "@every 20m", want: time.Date(2024, 1, 15, 10, 40, 0, 0, time.UTC)},
+		{spec: "@every 10s", wantErr: true},
+		{spec: "@every soon", wantErr: true},
+		{spec: "* * * *", wantErr: true},
+		{spec: "60 * * * *", wantErr: true},
+		{spec: "*/0 * * * *", wantErr: true},
+		{spec: "0 5-3 * * *", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSchedule(%q) error = nil, want error", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) error = %v", tt.spec, err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/i18n"
+	"library-system/metrics"
+	"library-system/models"
+	"library-system/repositories"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 运行历史默认和最多返回的条数
+const (
+	DefaultJobRunLimit = 20
+	MaxJobRunLimit     = 100
+)
+
+// schedulerTick 调度循环最长的休眠时间，心跳据此更新
+const schedulerTick = time.Minute
+
+var (
+	jobRuns = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "library_job_runs_total",
+		Help: "定时任务运行次数，status为succeeded或failed",
+	}, []string{"job", "status"})
+	jobDuration = promauto.With(metrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "library_job_duration_seconds",
+		Help:    "定时任务运行耗时",
+		Buckets: []float64{0.1, 0.5, 1, 5, 15, 60, 300},
+	}, []string{"job"})
+)
+
+// Job 定时任务
+// 任务说明取消息目录中的 job.<任务名>
+type Job struct {
+	Name string
+	// 为nil时只能手动触发
+	Schedule Schedule
+	// Run 返回结果摘要，记录在运行历史中
+	Run func(ctx context.Context) (string, error)
+}
+
+// JobInfo 任务及其最近一次运行
+type JobInfo struct {
+	Name        string `json:"name" example:"session_purge"`
+	Description string `json:"description" example:"清理已过期的登录会话"`
+	// 为空时只能手动触发
+	Schedule  string         `json:"schedule,omitempty" example:"30 3 * * *"`
+	NextRunAt *time.Time     `json:"next_run_at,omitempty" example:"2024-01-16T03:30:00Z"`
+	LastRun   *models.JobRun `json:"last_run,omitempty"`
+}
+
+// Scheduler 进程内的定时任务调度
+// 每个任务运行前取得数据库中的任务锁，多个实例中同一调度时间只有一个实例运行；
+// 运行中定期续期，实例异常退出后锁在lockTTL后失效
+type Scheduler struct {
+	lockRepo repositories.JobLockRepository
+	runRepo  repositories.JobRunRepository
+	// 本实例的标识，记录在锁和运行历史中
+	instance string
+	lockTTL  time.Duration
+
+	jobs  []*Job
+	byKey map[string]*Job
+
+	mu sync.Mutex
+	// 本实例正在运行的任务
+	running map[string]bool
+	// Stop时取消正在运行的任务
+	ctx    context.Context
+	cancel context.CancelFunc
+	runs   sync.WaitGroup
+}
+
+func NewScheduler(lockRepo repositories.JobLockRepository, runRepo repositories.JobRunRepository, instance string, lockTTL time.Duration) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		lockRepo: lockRepo,
+		runRepo:  runRepo,
+		instance: instance,
+		lockTTL:  lockTTL,
+		byKey:    make(map[string]*Job),
+		running:  make(map[string]bool),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register 在Run之前注册任务，名称重复时panic
+func (s *Scheduler) Register(job Job) {
+	if _, ok := s.byKey[job.Name]; ok {
+		panic(fmt.Sprintf("job %q registered twice", job.Name))
+	}
+	s.jobs = append(s.jobs, &job)
+	s.byKey[job.Name] = &job
+}
+
+// Run 按调度时间启动任务，ctx取消后返回，已启动的任务由Stop取消
+// beat在每轮循环后调用，至少每分钟一次，用于就绪检查
+func (s *Scheduler) Run(ctx context.Context, beat func()) {
+	now := time.Now()
+	next := make(map[string]time.Time)
+	for _, job := range s.jobs {
+		if job.Schedule != nil {
+			next[job.Name] = job.Schedule.Next(now)
+		}
+	}
+
+	for {
+		now = time.Now()
+		wake := now.Add(schedulerTick)
+		for _, job := range s.jobs {
+			at, ok := next[job.Name]
+			if !ok {
+				continue
+			}
+			if !at.After(now) {
+				s.startScheduled(job, at)
+				at = job.Schedule.Next(now)
+				next[job.Name] = at
+			}
+			if at.Before(wake) {
+				wake = at
+			}
+		}
+		beat()
+
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// startScheduled 其他实例已经运行或正在运行时跳过
+func (s *Scheduler) startScheduled(job *Job, scheduledAt time.Time) {
+	if _, err := s.start(job, models.JobTriggerSchedule, scheduledAt); err != nil {
+		if errors.Is(err, ErrJobRunning) {
+			slog.Debug("任务已在其他实例运行，跳过", slog.String("job", job.Name), slog.Time("scheduled_at", scheduledAt))
+			return
+		}
+		slog.Error("任务启动失败", slog.String("job", job.Name), slog.Any("error", err))
+	}
+}
+
+// Stop 取消正在运行的任务并等待退出，关闭服务时调用
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.runs.Wait()
+}
+
+// Trigger 立即运行任务，返回已创建的运行记录，任务在后台继续运行
+func (s *Scheduler) Trigger(ctx context.Context, name string) (_ *models.JobRun, err error) {
+	_, span := startSpan(ctx, "Scheduler.Trigger", attribute.String("job.name", name))
+	defer endSpan(span, &err)
+
+	job, ok := s.byKey[name]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return s.start(job, models.JobTriggerManual, time.Now())
+}
+
+// start 取得任务锁并创建运行记录，之后在后台运行任务
+// 本实例或其他实例正在运行，或该调度时间已经运行过时返回ErrJobRunning
+func (s *Scheduler) start(job *Job, trigger string, scheduledAt time.Time) (*models.JobRun, error) {
+	s.mu.Lock()
+	if s.running[job.Name] {
+		s.mu.Unlock()
+		return nil, ErrJobRunning
+	}
+	s.running[job.Name] = true
+	s.mu.Unlock()
+
+	run, err := s.acquire(job, trigger, scheduledAt)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.running, job.Name)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	// 返回副本，避免与后台更新竞争
+	started := *run
+	s.runs.Go(func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, job.Name)
+			s.mu.Unlock()
+		}()
+		s.execute(job, run)
+	})
+	return &started, nil
+}
+
+// acquire
+func (s *Scheduler) acquire(job *Job, trigger string, scheduledAt time.Time) (*models.JobRun, error) {
+	now := time.Now()
+	acquired, err := s.lockRepo.TryAcquire(s.ctx, &models.JobLock{
+		Name:        job.Name,
+		Owner:       s.instance,
+		LockedUntil: now.Add(s.lockTTL),
+		ScheduledAt: scheduledAt,
+	}, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+	if !acquired {
+		return nil, ErrJobRunning
+	}
+
+	run := &models.JobRun{
+		Job:       job.Name,
+		Trigger:   trigger,
+		Status:    models.JobRunRunning,
+		Instance:  s.instance,
+		StartedAt: now,
+	}
+	if err := s.runRepo.Create(s.ctx, run); err != nil {
+		s.release(job.Name)
+		return nil, fmt.Errorf("failed to create job run: %w", err)
+	}
+	return run, nil
+}
+
+// execute 运行任务并记录结果，运行期间定期续期任务锁
+func (s *Scheduler) execute(job *Job, run *models.JobRun) {
+	ctx, span := startSpan(s.ctx, "Scheduler.execute", attribute.String("job.name", job.Name))
+	var err error
+	defer endSpan(span, &err)
+	defer s.release(job.Name)
+
+	renewCtx, stopRenew := context.WithCancel(ctx)
+	defer stopRenew()
+	go s.renew(renewCtx, job.Name)
+
+	run.Result, err = s.call(ctx, job)
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.DurationMs = finished.Sub(run.StartedAt).Milliseconds()
+	run.Status = models.JobRunSucceeded
+	if err != nil {
+		run.Status = models.JobRunFailed
+		run.Error = err.Error()
+		slog.ErrorContext(ctx, "任务运行失败", slog.String("job", job.Name), slog.Any("error", err))
+	} else {
+		slog.InfoContext(ctx, "任务运行完成", slog.String("job", job.Name), slog.String("result", run.Result), slog.Int64("duration_ms", run.DurationMs))
+	}
+	jobRuns.WithLabelValues(job.Name, run.Status).Inc()
+	jobDuration.WithLabelValues(job.Name).Observe(finished.Sub(run.StartedAt).Seconds())
+
+	// 关闭服务时任务被取消，仍然记录结果
+	if updateErr := s.runRepo.Update(context.WithoutCancel(ctx), run); updateErr != nil {
+		slog.ErrorContext(ctx, "任务运行记录保存失败", slog.String("job", job.Name), slog.Any("error", updateErr))
+	}
+}
+
+// call 任务panic时按失败处理，不影响其他任务
+func (s *Scheduler) call(ctx context.Context, job *Job) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// renew 每隔lockTTL的三分之一续期一次，直到ctx取消
+func (s *Scheduler) renew(ctx context.Context, name string) {
+	ticker := time.NewTicker(s.lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		extended, err := s.lockRepo.Extend(ctx, name, s.instance, time.Now().Add(s.lockTTL))
+		if err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "任务锁续期失败", slog.String("job", name), slog.Any("error", err))
+		} else if err == nil && !extended {
+			slog.WarnContext(ctx, "任务锁已被其他实例取得", slog.String("job", name))
+		}
+	}
+}
+
+// release 释放任务锁，保留调度时间，同一调度时间不会再次运行
+func (s *Scheduler) release(name string) {
+	if err := s.lockRepo.Release(context.WithoutCancel(s.ctx), name, s.instance, time.Now()); err != nil {
+		slog.Error("任务锁释放失败", slog.String("job", name), slog.Any("error", err))
+	}
+}
+
+// Jobs 全部任务，按注册顺序
+func (s *Scheduler) Jobs(ctx context.Context) (_ []*JobInfo, err error) {
+	ctx, span := startSpan(ctx, "Scheduler.Jobs")
+	defer endSpan(span, &err)
+
+	locale := i18n.FromContext(ctx)
+	now := time.Now()
+	jobs := make([]*JobInfo, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		info := &JobInfo{Name: job.Name, Description: i18n.T(locale, "job."+job.Name)}
+		if job.Schedule != nil {
+			next := job.Schedule.Next(now)
+			info.Schedule = job.Schedule.String()
+			info.NextRunAt = &next
+		}
+
+		runs, err := s.runRepo.GetByJob(ctx, job.Name, 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get job runs: %w", err)
+		}
+		if len(runs) > 0 {
+			info.LastRun = runs[0]
+		}
+		jobs = append(jobs, info)
+	}
+	return jobs, nil
+}
+
+// Runs 任务的运行历史，最新的在前，limit不大于0时使用默认值，最多返回MaxJobRunLimit条
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) (_ []*models.JobRun, err error) {
+	ctx, span := startSpan(ctx, "Scheduler.Runs", attribute.String("job.name", name))
+	defer endSpan(span, &err)
+
+	if _, ok := s.byKey[name]; !ok {
+		return nil, ErrJobNotFound
+	}
+	if limit <= 0 {
+		limit = DefaultJobRunLimit
+	}
+	limit = min(limit, MaxJobRunLimit)
+
+	runs, err := s.runRepo.GetByJob(ctx, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job runs: %w", err)
+	}
+	return runs, nil
+}
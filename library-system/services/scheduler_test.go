@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library-system/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestScheduler(env *testEnv, instance string) *Scheduler {
+	return NewScheduler(env.repos.JobLocks, env.repos.JobRuns, instance, time.Minute)
+}
+
+// lastRun 返回任务最近一次运行
+func lastRun(t *testing.T, env *testEnv, job string) *models.JobRun {
+	t.Helper()
+	runs, err := env.repos.JobRuns.GetByJob(context.Background(), job, 1)
+	checkErr(t, err, nil)
+	if len(runs) == 0 {
+		t.Fatalf("no runs for job %q", job)
+	}
+	return runs[0]
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        func(ctx context.Context) (string, error)
+		wantStatus string
+		wantResult string
+		wantError  string
+	}{
+		{
+			name:       "运行成功",
+			run:        func(ctx context.Context) (string, error) { return "deleted=3", nil },
+			wantStatus: models.JobRunSucceeded,
+			wantResult: "deleted=3",
+		},
+		{
+			name:       "运行失败",
+			run:        func(ctx context.Context) (string, error) { return "", errors.New("boom") },
+			wantStatus: models.JobRunFailed,
+			wantError:  "boom",
+		},
+		{
+			name:       "panic按失败处理",
+			run:        func(ctx context.Context) (string, error) { panic("boom") },
+			wantStatus: models.JobRunFailed,
+			wantError:  "job panicked: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			scheduler := newTestScheduler(env, "a")
+			scheduler.Register(Job{Name: "test", Run: tt.run})
+
+			run, err := scheduler.Trigger(context.Background(), "test")
+			checkErr(t, err, nil)
+			if run.Status != models.JobRunRunning || run.Trigger != models.JobTriggerManual {
+				t.Errorf("started run = %+v", run)
+			}
+			scheduler.Stop()
+
+			got := lastRun(t, env, "test")
+			if got.ID != run.ID || got.Status != tt.wantStatus || got.Result != tt.wantResult || got.Error != tt.wantError {
+				t.Errorf("run = %+v, want status %q result %q error %q", got, tt.wantStatus, tt.wantResult, tt.wantError)
+			}
+			if got.FinishedAt == nil {
+				t.Error("FinishedAt is nil")
+			}
+		})
+	}
+
+	t.Run("任务不存在", func(t *testing.T) {
+		scheduler := newTestScheduler(newTestEnv(t), "a")
+		_, err := scheduler.Trigger(context.Background(), "missing")
+		checkErr(t, err, ErrJobNotFound)
+		_, err = scheduler.Runs(context.Background(), "missing", 0)
+		checkErr(t, err, ErrJobNotFound)
+	})
+}
+
+func TestScheduler_Lock(t *testing.T) {
+	env := newTestEnv(t)
+	var count atomic.Int32
+	release := make(chan struct{})
+	job := Job{Name: "test", Run: func(ctx context.Context) (string, error) {
+		count.Add(1)
+		<-release
+		return "", nil
+	}}
+	// 两个实例共用同一个数据库
+	a := newTestScheduler(env, "a")
+	a.Register(job)
+	b := newTestScheduler(env, "b")
+	b.Register(job)
+
+	slot := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	_, err := a.start(a.byKey["test"], models.JobTriggerSchedule, slot)
+	checkErr(t, err, nil)
+
+	// 运行中其他实例和本实例都不能再运行
+	_, err = b.start(b.byKey["test"], models.JobTriggerSchedule, slot)
+	checkErr(t, err, ErrJobRunning)
+	_, err = a.Trigger(context.Background(), "test")
+	checkErr(t, err, ErrJobRunning)
+
+	close(release)
+	a.runs.Wait()
+
+	// 同一调度时间只运行一次，下一个调度时间可以由其他实例运行
+	_, err = b.start(b.byKey["test"], models.JobTriggerSchedule, slot)
+	checkErr(t, err, ErrJobRunning)
+	_, err = b.start(b.byKey["test"], models.JobTriggerSchedule, slot.Add(time.Hour))
+	checkErr(t, err, nil)
+	b.runs.Wait()
+
+	if got := count.Load(); got != 2 {
+		t.Errorf("job ran %d times, want 2", got)
+	}
+	if run := lastRun(t, env, "test"); run.Instance != "b" || run.Trigger != models.JobTriggerSchedule {
+		t.Errorf("last run = %+v, want scheduled run on b", run)
+	}
+}
+
+func TestScheduler_Jobs(t *testing.T) {
+	env := newTestEnv(t)
+	scheduler := newTestScheduler(env, "a")
+	hourly, err := ParseSchedule("@hourly")
+	checkErr(t, err, nil)
+	scheduler.Register(Job{Name: "session_purge", Schedule: hourly, Run: func(ctx context.Context) (string, error) { return "deleted=0", nil }})
+	scheduler.Register(Job{Name: "manual", Run: func(ctx context.Context) (string, error) { return "", nil }})
+
+	_, err = scheduler.Trigger(context.Background(), "session_purge")
+	checkErr(t, err, nil)
+	scheduler.Stop()
+
+	jobs, err := scheduler.Jobs(context.Background())
+	checkErr(t, err, nil)
+	if len(jobs) != 2 || jobs[0].Name != "session_purge" || jobs[1].Name != "manual" {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if jobs[0].Schedule != "0 * * * *" || jobs[0].NextRunAt == nil || jobs[0].LastRun == nil || jobs[0].LastRun.Result != "deleted=0" {
+		t.Errorf("scheduled job = %+v", jobs[0])
+	}
+	if jobs[1].Schedule != "" || jobs[1].NextRunAt != nil || jobs[1].LastRun != nil {
+		t.Errorf("manual job = %+v", jobs[1])
+	}
+	if jobs[0].Description == "" || jobs[0].Description == "job.session_purge" {
+		t.Errorf("description = %q, want catalog entry", jobs[0].Description)
+	}
+}
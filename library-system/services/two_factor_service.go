@@ -0,0 +1,440 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"library-system/models"
+	"library-system/repositories"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// 恢复码字符集，去掉了容易混淆的字符
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// TOTPSetup 两步验证密钥和供验证器应用扫描的URI
+type TOTPSetup struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"otpauth_uri" example:"otpauth://totp/LibrarySystem:lemon?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=LibrarySystem"`
+}
+
+type TwoFactorService struct {
+	transactor repositories.Transactor
+	userRepo   repositories.UserRepository
+	loginGuard *LoginGuard
+	issuer     string
+}
+
+func NewTwoFactorService(transactor repositories.Transactor, userRepo repositories.UserRepository, loginGuard *LoginGuard, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		transactor: transactor,
+		userRepo:   userRepo,
+		loginGuard: loginGuard,
+		issuer:     issuer,
+	}
+}
+
+// Setup 生成新的TOTP密钥，在Enable确认之前不会生效
+func (s *TwoFactorService) Setup(ctx context.Context, userID int) (_ *TOTPSetup, err error) {
+	ctx, span := startSpan(ctx, "TwoFactorService.Setup", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	user, err := s.getUser(ctx, s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save totp secret: %w", err)
+	}
+
+	return &TOTPSetup{
+		Secret: secret,
+		URI:    totpURI(s.issuer, user.Name, secret),
+	}, nil
+}
+
+// QRCode 生成待确认密钥的二维码PNG，启用后不再提供
+func (s *TwoFactorService) QRCode(ctx context.Context, userID int) (_ []byte, err error) {
+	ctx, span := startSpan(ctx, "TwoFactorService.QRCode", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	user, err := s.getUser(ctx, s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetup
+	}
+
+	png, err := qrcode.Encode(totpURI(s.issuer, user.Name, user.TOTPSecret), qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	return png, nil
+}
+
+// Enable 校验验证码后启用两步验证，返回新生成的恢复码
+func (s *TwoFactorService) Enable(ctx context.Context, userID int, code string) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "TwoFactorService.Enable", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if code == "" {
+		return nil, ErrInvalidInput
+	}
+
+	var recoveryCodes []string
+
+	// 事务处理
+	err = s.transactor.WithinTransaction(ctx, "two_factor_enable", func(repos repositories.Repositories) error {
+		// 事务内的仓库
+		txUserRepo := repos.Users
+		txCodeRepo := repos.RecoveryCodes
+
+		user, err := s.getUser(ctx, txUserRepo, userID)
+		if err != nil {
+			return err
+		}
+		if user.TOTPEnabled {
+			return ErrTwoFactorEnabled
+		}
+		if user.TOTPSecret == "" {
+			return ErrTwoFactorNotSetup
+		}
+
+		step, ok := verifyTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		user.TOTPEnabled = true
+		user.TOTPLastStep = step
+		if err := txUserRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to enable two factor: %w", err)
+		}
+
+		recoveryCodes, err = s.replaceRecoveryCodes(ctx, txCodeRepo, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// Disable 校验密码和验证码后关闭两步验证，失败次数计入登录限制
+func (s *TwoFactorService) Disable(ctx context.Context, userID int, password, code, clientIP string) (err error) {
+	ctx, span := startSpan(ctx, "TwoFactorService.Disable", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if password == "" || code == "" {
+		return ErrInvalidInput
+	}
+
+	user, err := s.getUser(ctx, s.userRepo, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	// 检查账号或IP是否被锁定
+	accountKey := AccountKey(user.Name)
+	ipKey := IPKey(clientIP)
+	if err := s.loginGuard.Check(ctx, accountKey, ipKey); err != nil {
+		return err
+	}
+
+	// 验证密码
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		if err := s.loginGuard.RecordFailure(ctx, accountKey, ipKey); err != nil {
+			return err
+		}
+		return ErrInvalidPassword
+	}
+
+	// 事务处理
+	err = s.transactor.WithinTransaction(ctx, "two_factor_disable", func(repos repositories.Repositories) error {
+		// 事务内的仓库
+		txUserRepo := repos.Users
+		txCodeRepo := repos.RecoveryCodes
+
+		// 重新读取，验证时需要最新的已使用时间步
+		user, err := s.getUser(ctx, txUserRepo, userID)
+		if err != nil {
+			return err
+		}
+		if !user.TOTPEnabled {
+			return ErrTwoFactorNotEnabled
+		}
+		if err := s.verifyCode(ctx, txUserRepo, txCodeRepo, user, code); err != nil {
+			return err
+		}
+
+		user.TOTPEnabled = false
+		user.TOTPSecret = ""
+		user.TOTPLastStep = 0
+		if err := txUserRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to disable two factor: %w", err)
+		}
+
+		if err := txCodeRepo.DeleteByUserID(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		return nil
+	})
+	return s.recordCodeAttempt(ctx, accountKey, ipKey, err)
+}
+
+// RegenerateRecoveryCodes 校验验证码后作废旧恢复码并生成新的一组，失败次数计入登录限制
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code, clientIP string) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "TwoFactorService.RegenerateRecoveryCodes", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if code == "" {
+		return nil, ErrInvalidInput
+	}
+
+	user, err := s.getUser(ctx, s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	// 检查账号或IP是否被锁定
+	accountKey := AccountKey(user.Name)
+	ipKey := IPKey(clientIP)
+	if err := s.loginGuard.Check(ctx, accountKey, ipKey); err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+
+	// 事务处理
+	err = s.transactor.WithinTransaction(ctx, "two_factor_regenerate_codes", func(repos repositories.Repositories) error {
+		// 事务内的仓库
+		txUserRepo := repos.Users
+		txCodeRepo := repos.RecoveryCodes
+
+		user, err := s.getUser(ctx, txUserRepo, userID)
+		if err != nil {
+			return err
+		}
+		if !user.TOTPEnabled {
+			return ErrTwoFactorNotEnabled
+		}
+		if err := s.verifyCode(ctx, txUserRepo, txCodeRepo, user, code); err != nil {
+			return err
+		}
+
+		recoveryCodes, err = s.replaceRecoveryCodes(ctx, txCodeRepo, user.ID)
+		return err
+	})
+	if err := s.recordCodeAttempt(ctx, accountKey, ipKey, err); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// VerifyLogin 登录第二步: 校验TOTP验证码或恢复码，失败次数计入登录限制
+func (s *TwoFactorService) VerifyLogin(ctx context.Context, userID int, code, clientIP string) (user *models.User, err error) {
+	ctx, span := startSpan(ctx, "TwoFactorService.VerifyLogin", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	defer func() { recordLoginAttempt("totp", err) }()
+
+	// 参数基础校验
+	if userID <= 0 || code == "" {
+		return nil, ErrInvalidInput
+	}
+
+	user, err = s.getUser(ctx, s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrUserDisabled
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	// 检查账号或IP是否被锁定
+	// 失败限制使用自己的仓库，在事务外读写，避免事务重试时重复计数
+	accountKey := AccountKey(user.Name)
+	ipKey := IPKey(clientIP)
+	if err := s.loginGuard.Check(ctx, accountKey, ipKey); err != nil {
+		return nil, err
+	}
+
+	var verified *models.User
+
+	// 事务处理
+	err = s.transactor.WithinTransaction(ctx, "two_factor_verify_login", func(repos repositories.Repositories) error {
+		// 事务内的仓库
+		txUserRepo := repos.Users
+		txCodeRepo := repos.RecoveryCodes
+
+		// 重新读取，验证时需要最新的已使用时间步
+		user, err := s.getUser(ctx, txUserRepo, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.verifyCode(ctx, txUserRepo, txCodeRepo, user, code); err != nil {
+			return err
+		}
+
+		verified = user
+		return nil
+	})
+	if err := s.recordCodeAttempt(ctx, accountKey, ipKey, err); err != nil {
+		return nil, err
+	}
+
+	return verified, nil
+}
+
+// recordCodeAttempt 在事务外记录验证结果，避免事务重试时重复计数
+// 验证码错误时计入失败次数，验证通过时清除账号的失败记录，返回原错误
+func (s *TwoFactorService) recordCodeAttempt(ctx context.Context, accountKey, ipKey string, err error) error {
+	if err == nil {
+		return s.loginGuard.RecordSuccess(ctx, accountKey)
+	}
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if err := s.loginGuard.RecordFailure(ctx, accountKey, ipKey); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// getUser
+func (s *TwoFactorService) getUser(ctx context.Context, userRepo repositories.UserRepository, userID int) (*models.User, error) {
+	// 参数基础校验
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	user, err := userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by ID: %w", err)
+	}
+
+	return user, nil
+}
+
+// verifyCode 依次尝试TOTP验证码和恢复码，成功后记录已使用的时间步或恢复码
+func (s *TwoFactorService) verifyCode(ctx context.Context, userRepo repositories.UserRepository, codeRepo repositories.RecoveryCodeRepository, user *models.User, code string) error {
+	if step, ok := verifyTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep); ok {
+		user.TOTPLastStep = step
+		if err := userRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to update totp step: %w", err)
+		}
+		return nil
+	}
+
+	codes, err := codeRepo.GetUnusedByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get recovery codes: %w", err)
+	}
+
+	hash := hashRecoveryCode(code)
+	for _, rc := range codes {
+		if subtle.ConstantTimeCompare([]byte(rc.CodeHash), []byte(hash)) == 1 {
+			now := time.Now()
+			rc.UsedAt = &now
+			if err := codeRepo.Update(ctx, rc); err != nil {
+				return fmt.Errorf("failed to mark recovery code used: %w", err)
+			}
+			return nil
+		}
+	}
+
+	return ErrInvalidTwoFactorCode
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的一组，返回明文
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, codeRepo repositories.RecoveryCodeRepository, userID int) ([]string, error) {
+	if err := codeRepo.DeleteByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	plain := make([]string, 0, recoveryCodeCount)
+	records := make([]*models.RecoveryCode, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		plain = append(plain, code)
+		records = append(records, &models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	if err := codeRepo.CreateBatch(ctx, records); err != nil {
+		return nil, fmt.Errorf("failed to create recovery codes: %w", err)
+	}
+
+	return plain, nil
+}
+
+// newRecoveryCode 生成形如 abcde-23456 的恢复码
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+
+	var sb strings.Builder
+	for i, b := range buf {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+// hashRecoveryCode 忽略大小写和首尾空白
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"library-system/models"
+	"library-system/repositories"
+	"testing"
+	"time"
+)
+
+// currentTOTP 计算当前时间往后offset个时间步的验证码
+// 只使用0和1，跨过时间步边界时仍在允许的偏差内
+func currentTOTP(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode totp secret: %v", err)
+	}
+	return totpCode(key, time.Now().Unix()/totpPeriod+offset)
+}
+
+func newTestTwoFactorService(env *testEnv) *TwoFactorService {
+	return NewTwoFactorService(env.store, env.repos.Users, env.loginGuard(), "LibrarySystem")
+}
+
+// enableTwoFactor 为用户启用两步验证，返回密钥和恢复码，已使用当前时间步
+func enableTwoFactor(t *testing.T, service *TwoFactorService, userID int) (string, []string) {
+	t.Helper()
+	setup, err := service.Setup(context.Background(), userID)
+	checkErr(t, err, nil)
+	codes, err := service.Enable(context.Background(), userID, currentTOTP(t, setup.Secret, 0))
+	checkErr(t, err, nil)
+	return setup.Secret, codes
+}
+
+func TestTwoFactorService_SetupAndQRCode(t *testing.T) {
+	env := newTestEnv(t)
+	service := newTestTwoFactorService(env)
+	user := env.createUser(t, "lemon", models.RoleUser)
+	ctx := context.Background()
+
+	_, err := service.QRCode(ctx, user.ID)
+	checkErr(t, err, ErrTwoFactorNotSetup)
+
+	setup, err := service.Setup(ctx, user.ID)
+	checkErr(t, err, nil)
+	if setup.Secret == "" || env.getUser(t, user.ID).TOTPSecret != setup.Secret {
+		t.Fatalf("secret not saved: %+v", setup)
+	}
+	if env.getUser(t, user.ID).TOTPEnabled {
+		t.Error("two factor enabled before confirmation")
+	}
+
+	png, err := service.QRCode(ctx, user.ID)
+	checkErr(t, err, nil)
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Error("QRCode() did not return a PNG")
+	}
+
+	// 重新生成会替换未确认的密钥
+	again, err := service.Setup(ctx, user.ID)
+	checkErr(t, err, nil)
+	if again.Secret == setup.Secret {
+		t.Error("Setup() returned the same secret twice")
+	}
+
+	_, err = service.Setup(ctx, 404)
+	checkErr(t, err, ErrUserNotFound)
+
+	_, err = service.Enable(ctx, user.ID, currentTOTP(t, again.Secret, 0))
+	checkErr(t, err, nil)
+	_, err = service.Setup(ctx, user.ID)
+	checkErr(t, err, ErrTwoFactorEnabled)
+	_, err = service.QRCode(ctx, user.ID)
+	checkErr(t, err, ErrTwoFactorEnabled)
+}
+
+func TestTwoFactorService_Enable(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup 是否先生成密钥，code 根据密钥生成提交的验证码
+		setup   bool
+		code    func(t *testing.T, secret string) string
+		wantErr error
+	}{
+		{name: "启用成功", setup: true, code: func(t *testing.T, s string) string { return currentTOTP(t, s, 0) }},
+		{name: "验证码错误", setup: true, code: func(t *testing.T, s string) string { return "000000" }, wantErr: ErrInvalidTwoFactorCode},
+		{name: "验证码为空", setup: true, code: func(t *testing.T, s string) string { return "" }, wantErr: ErrInvalidInput},
+		{name: "未生成密钥", setup: false, code: func(t *testing.T, s string) string { return "123456" }, wantErr: ErrTwoFactorNotSetup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := newTestTwoFactorService(env)
+			user := env.createUser(t, "lemon", models.RoleUser)
+
+			var secret string
+			if tt.setup {
+				setup, err := service.Setup(context.Background(), user.ID)
+				checkErr(t, err, nil)
+				secret = setup.Secret
+			}
+			codes, err := service.Enable(context.Background(), user.ID, tt.code(t, secret))
+			checkErr(t, err, tt.wantErr)
+
+			enabled := env.getUser(t, user.ID).TOTPEnabled
+			if enabled != (tt.wantErr == nil) {
+				t.Errorf("TOTPEnabled = %v", enabled)
+			}
+			if tt.wantErr == nil && len(codes) != recoveryCodeCount {
+				t.Errorf("len(codes) = %d, want %d", len(codes), recoveryCodeCount)
+			}
+		})
+	}
+
+	t.Run("重复启用", func(t *testing.T) {
+		env := newTestEnv(t)
+		service := newTestTwoFactorService(env)
+		user := env.createUser(t, "lemon", models.RoleUser)
+		secret, _ := enableTwoFactor(t, service, user.ID)
+
+		_, err := service.Enable(context.Background(), user.ID, currentTOTP(t, secret, 1))
+		checkErr(t, err, ErrTwoFactorEnabled)
+	})
+}
+
+func TestTwoFactorService_VerifyLogin(t *testing.T) {
+	env := newTestEnv(t)
+	service := newTestTwoFactorService(env)
+	user := env.createUser(t, "lemon", models.RoleUser)
+	plain := env.createUser(t, "lime", models.RoleUser)
+	secret, codes := enableTwoFactor(t, service, user.ID)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		userID  int
+		code    string
+		wantErr error
+	}{
+		{name: "已使用的验证码不能重复使用", userID: user.ID, code: currentTOTP(t, secret, 0), wantErr: ErrInvalidTwoFactorCode},
+		{name: "下一个时间步的验证码", userID: user.ID, code: currentTOTP(t, secret, 1)},
+		{name: "恢复码", userID: user.ID, code: codes[0]},
+		{name: "恢复码只能使用一次", userID: user.ID, code: codes[0], wantErr: ErrInvalidTwoFactorCode},
+		{name: "恢复码忽略大小写", userID: user.ID, code: " " + string(bytes.ToUpper([]byte(codes[1]))) + " "},
+		{name: "未启用两步验证", userID: plain.ID, code: "123456", wantErr: ErrTwoFactorNotEnabled},
+		{name: "用户不存在", userID: 404, code: "123456", wantErr: ErrUserNotFound},
+		{name: "验证码为空", userID: user.ID, code: "", wantErr: ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.VerifyLogin(ctx, tt.userID, tt.code, "192.0.2.1")
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr == nil && got.ID != tt.userID {
+				t.Errorf("user.ID = %d, want %d", got.ID, tt.userID)
+			}
+		})
+	}
+
+	t.Run("失败次数过多后锁定", func(t *testing.T) {
+		// 前面最后一次成功的验证已清除账号的失败记录
+		for range DefaultLoginGuardPolicy().AccountThreshold {
+			_, err := service.VerifyLogin(ctx, user.ID, "000000", "192.0.2.1")
+			checkErr(t, err, ErrInvalidTwoFactorCode)
+		}
+		_, err := service.VerifyLogin(ctx, user.ID, codes[2], "192.0.2.1")
+		checkErr(t, err, ErrTooManyAttempts)
+	})
+
+	t.Run("账号已停用", func(t *testing.T) {
+		disabled := env.getUser(t, user.ID)
+		disabled.Active = false
+		checkErr(t, env.repos.Users.Update(ctx, disabled), nil)
+
+		_, err := service.VerifyLogin(ctx, user.ID, codes[3], "192.0.2.2")
+		checkErr(t, err, ErrUserDisabled)
+	})
+}
+
+// retryingTransactor 每个事务先完整执行一次后回滚，再执行一次，模拟遇到死锁后的重试
+type retryingTransactor struct {
+	repositories.Transactor
+}
+
+func (r retryingTransactor) WithinTransaction(ctx context.Context, operation string, fn func(repos repositories.Repositories) error) error {
+	errDeadlock := errors.New("deadlock")
+	_ = r.Transactor.WithinTransaction(ctx, operation, func(repos repositories.Repositories) error {
+		_ = fn(repos)
+		return errDeadlock
+	})
+	return r.Transactor.WithinTransaction(ctx, operation, fn)
+}
+
+func TestTwoFactorService_VerifyLoginRetry(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "lemon", models.RoleUser)
+	enableTwoFactor(t, newTestTwoFactorService(env), user.ID)
+	service := NewTwoFactorService(retryingTransactor{env.store}, env.repos.Users, env.loginGuard(), "LibrarySystem")
+	ctx := context.Background()
+
+	// 事务重试时一次错误的验证码只计一次失败
+	_, err := service.VerifyLogin(ctx, user.ID, "000000", "192.0.2.1")
+	checkErr(t, err, ErrInvalidTwoFactorCode)
+
+	throttles, err := env.repos.LoginThrottles.GetByKeys(ctx, []string{AccountKey("lemon")})
+	checkErr(t, err, nil)
+	if len(throttles) != 1 || throttles[0].Failures != 1 {
+		t.Errorf("throttles = %+v, want 1 failure", throttles)
+	}
+}
+
+func TestTwoFactorService_RegenerateRecoveryCodes(t *testing.T) {
+	env := newTestEnv(t)
+	service := newTestTwoFactorService(env)
+	user := env.createUser(t, "lemon", models.RoleUser)
+	plain := env.createUser(t, "lime", models.RoleUser)
+	_, oldCodes := enableTwoFactor(t, service, user.ID)
+	ctx := context.Background()
+
+	_, err := service.RegenerateRecoveryCodes(ctx, user.ID, "000000", "192.0.2.1")
+	checkErr(t, err, ErrInvalidTwoFactorCode)
+	_, err = service.RegenerateRecoveryCodes(ctx, plain.ID, "123456", "192.0.2.1")
+	checkErr(t, err, ErrTwoFactorNotEnabled)
+	_, err = service.RegenerateRecoveryCodes(ctx, user.ID, "", "192.0.2.1")
+	checkErr(t, err, ErrInvalidInput)
+
+	newCodes, err := service.RegenerateRecoveryCodes(ctx, user.ID, oldCodes[0], "192.0.2.1")
+	checkErr(t, err, nil)
+	if len(newCodes) != recoveryCodeCount {
+		t.Fatalf("len(codes) = %d, want %d", len(newCodes), recoveryCodeCount)
+	}
+
+	// 旧恢复码全部作废
+	_, err = service.VerifyLogin(ctx, user.ID, oldCodes[1], "192.0.2.1")
+	checkErr(t, err, ErrInvalidTwoFactorCode)
+	_, err = service.VerifyLogin(ctx, user.ID, newCodes[0], "192.0.2.1")
+	checkErr(t, err, nil)
+}
+
+func TestTwoFactorService_Disable(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		code     func(codes []string) string
+		wantErr  error
+	}{
+		{name: "关闭成功", password: testPassword, code: func(codes []string) string { return codes[0] }},
+		{name: "密码错误", password: "wrong-password1", code: func(codes []string) string { return codes[0] }, wantErr: ErrInvalidPassword},
+		{name: "验证码错误", password: testPassword, code: func(codes []string) string { return "000000" }, wantErr: ErrInvalidTwoFactorCode},
+		{name: "参数为空", password: "", code: func(codes []string) string { return codes[0] }, wantErr: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := newTestTwoFactorService(env)
+			user := env.createUser(t, "lemon", models.RoleUser)
+			_, codes := enableTwoFactor(t, service, user.ID)
+
+			err := service.Disable(context.Background(), user.ID, tt.password, tt.code(codes), "192.0.2.1")
+			checkErr(t, err, tt.wantErr)
+
+			got := env.getUser(t, user.ID)
+			if got.TOTPEnabled != (tt.wantErr != nil) {
+				t.Errorf("TOTPEnabled = %v", got.TOTPEnabled)
+			}
+			if tt.wantErr == nil {
+				if got.TOTPSecret != "" {
+					t.Error("secret not cleared")
+				}
+				err := service.Disable(context.Background(), user.ID, tt.password, codes[1], "192.0.2.1")
+				checkErr(t, err, ErrTwoFactorNotEnabled)
+			}
+		})
+	}
+}
+
+func TestTwoFactorService_SensitiveActionsThrottled(t *testing.T) {
+	disable := func(password, code string) func(service *TwoFactorService, userID int) error {
+		return func(service *TwoFactorService, userID int) error {
+			return service.Disable(context.Background(), userID, password, code, "192.0.2.1")
+		}
+	}
+	regenerate := func(code string) func(service *TwoFactorService, userID int) error {
+		return func(service *TwoFactorService, userID int) error {
+			_, err := service.RegenerateRecoveryCodes(context.Background(), userID, code, "192.0.2.1")
+			return err
+		}
+	}
+
+	tests := []struct {
+		name string
+		// 使用错误的密码或验证码尝试
+		attempt func(service *TwoFactorService, userID int) error
+		wantErr error
+		// 使用正确的密码和验证码（恢复码）尝试
+		succeed func(codes []string) func(service *TwoFactorService, userID int) error
+	}{
+		{
+			name:    "关闭两步验证时验证码错误",
+			attempt: disable(testPassword, "000000"),
+			wantErr: ErrInvalidTwoFactorCode,
+			succeed: func(codes []string) func(*TwoFactorService, int) error { return disable(testPassword, codes[0]) },
+		},
+		{
+			name:    "关闭两步验证时密码错误",
+			attempt: disable("wrong-password1", "000000"),
+			wantErr: ErrInvalidPassword,
+			succeed: func(codes []string) func(*TwoFactorService, int) error { return disable(testPassword, codes[0]) },
+		},
+		{
+			name:    "重新生成恢复码时验证码错误",
+			attempt: regenerate("000000"),
+			wantErr: ErrInvalidTwoFactorCode,
+			succeed: func(codes []string) func(*TwoFactorService, int) error { return regenerate(codes[0]) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := newTestTwoFactorService(env)
+			user := env.createUser(t, "lemon", models.RoleUser)
+			_, codes := enableTwoFactor(t, service, user.ID)
+
+			for range DefaultLoginGuardPolicy().AccountThreshold {
+				checkErr(t, tt.attempt(service, user.ID), tt.wantErr)
+			}
+
+			// 锁定后正确的密码和验证码也被拒绝，两步验证保持启用
+			checkErr(t, tt.succeed(codes)(service, user.ID), ErrTooManyAttempts)
+			if !env.getUser(t, user.ID).TOTPEnabled {
+				t.Error("two factor disabled while locked")
+			}
+		})
+	}
+}
@@ -1,13 +1,268 @@
-package services
-
-import (
-	"library-system/repositories"
-)
-
-type UserService struct {
-	userRepo repositories.UserRepository
-}
-
-func NewUserService(userRepo repositories.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
-}
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"library-system/i18n"
+	"library-system/models"
+	"library-system/repositories"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 手机号: 可选的国际区号前缀，数字与连字符
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9-]{4,19}$`)
+
+// 借书证号: 字母、数字与连字符，与条码打印的内容一致
+var cardNumberPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,31}$`)
+
+type UserService struct {
+	transactor repositories.Transactor
+	userRepo   repositories.UserRepository
+	recordRepo repositories.BorrowRecordRepository
+	// 修改密码时校验当前密码的失败次数与登录共用限制
+	loginGuard     *LoginGuard
+	passwordPolicy PasswordPolicy
+	loanPolicy     LoanPolicy
+}
+
+func NewUserService(transactor repositories.Transactor, userRepo repositories.UserRepository, recordRepo repositories.BorrowRecordRepository, loginGuard *LoginGuard, passwordPolicy PasswordPolicy, loanPolicy LoanPolicy) *UserService {
+	return &UserService{
+		transactor:     transactor,
+		userRepo:       userRepo,
+		recordRepo:     recordRepo,
+		loginGuard:     loginGuard,
+		passwordPolicy: passwordPolicy,
+		loanPolicy:     loanPolicy,
+	}
+}
+
+// AccountSummary 用户账户概览
+type AccountSummary struct {
+	ActiveLoans  []*models.BorrowRecord `json:"active_loans"`
+	ActiveCount  int                    `json:"active_count" example:"2"`
+	OverdueCount int                    `json:"overdue_count" example:"1"`
+	BorrowLimit  int                    `json:"borrow_limit" example:"5"`
+}
+
+// GetProfile
+func (s *UserService) GetProfile(ctx context.Context, userID int) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetProfile", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	user, err := s.userRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by ID: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateProfile
+func (s *UserService) UpdateProfile(ctx context.Context, userID int, email, phone, locale string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.UpdateProfile", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	email = strings.TrimSpace(email)
+	phone = strings.TrimSpace(phone)
+
+	// 参数基础校验，空值表示清除，格式错误的字段全部报告
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+	var fields []FieldError
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email {
+			fields = append(fields, NewFieldError("email", "invalid", "email"))
+		}
+	}
+	if phone != "" && !phonePattern.MatchString(phone) {
+		fields = append(fields, NewFieldError("phone", "invalid", "phone"))
+	}
+	if locale != "" {
+		matched, ok := i18n.Match(locale)
+		if !ok {
+			fields = append(fields, NewFieldError("locale", "invalid", "locale"))
+		}
+		locale = matched
+	}
+	if err := newValidationError(fields); err != nil {
+		return nil, err
+	}
+
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查邮箱是否已被其他用户使用
+	if email != "" && !strings.EqualFold(email, user.Email) {
+		existing, err := s.userRepo.GetByEmail(ctx, email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to check email existence: %w", err)
+		}
+		if err == nil && existing.ID != user.ID {
+			return nil, ErrEmailExists
+		}
+	}
+
+	user.Email = email
+	user.Phone = phone
+	user.Locale = locale
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetCardNumber 管理员登记或更换读者的借书证号，空值表示注销
+func (s *UserService) SetCardNumber(ctx context.Context, userID int, cardNumber string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.SetCardNumber", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	cardNumber = strings.TrimSpace(cardNumber)
+
+	// 参数基础校验
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+	if cardNumber != "" && !cardNumberPattern.MatchString(cardNumber) {
+		return nil, newValidationError([]FieldError{NewFieldError("card_number", "invalid", "card_number")})
+	}
+
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查借书证号是否已被其他用户使用
+	user.CardNumber = nil
+	if cardNumber != "" {
+		existing, err := s.userRepo.GetByCardNumber(ctx, cardNumber)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to check card number existence: %w", err)
+		}
+		if err == nil && existing.ID != user.ID {
+			return nil, ErrCardNumberExists
+		}
+		user.CardNumber = &cardNumber
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ChangePassword 修改密码并吊销该用户的所有Session，调用方需要为当前请求重新建立Session
+// 当前密码错误计入登录失败次数，被盗用的Session同样无法无限次尝试
+func (s *UserService) ChangePassword(ctx context.Context, userID int, currentPassword, newPassword, clientIP string) (err error) {
+	ctx, span := startSpan(ctx, "UserService.ChangePassword", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 || currentPassword == "" || newPassword == "" {
+		return ErrInvalidInput
+	}
+
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// 检查账号或IP是否被锁定
+	accountKey := AccountKey(user.Name)
+	ipKey := IPKey(clientIP)
+	if err := s.loginGuard.Check(ctx, accountKey, ipKey); err != nil {
+		return err
+	}
+
+	// 验证当前密码
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		if err := s.loginGuard.RecordFailure(ctx, accountKey, ipKey); err != nil {
+			return err
+		}
+		return ErrInvalidPassword
+	}
+	if err := s.loginGuard.RecordSuccess(ctx, accountKey); err != nil {
+		return err
+	}
+
+	// 校验新密码强度
+	if err := newValidationError(s.passwordPolicy.Validate("new_password", user.Name, newPassword)); err != nil {
+		return err
+	}
+
+	// 加密新密码
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user.Password = string(hashedPassword)
+
+	// 事务处理
+	return s.transactor.WithinTransaction(ctx, "change_password", func(repos repositories.Repositories) error {
+		if err := repos.Users.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		// 吊销已有Session，其他设备需要使用新密码重新登录
+		if err := repos.Sessions.DeleteByUserID(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetAccountSummary
+func (s *UserService) GetAccountSummary(ctx context.Context, userID int) (_ *AccountSummary, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetAccountSummary", attribute.Int("user.id", userID))
+	defer endSpan(span, &err)
+
+	// 参数基础校验
+	if userID <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	records, err := s.recordRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active borrow records by user ID: %w", err)
+	}
+
+	// 统计逾期数量
+	now := time.Now()
+	overdue := 0
+	for _, record := range records {
+		if record.DueDate.Before(now) {
+			overdue++
+		}
+	}
+
+	return &AccountSummary{
+		ActiveLoans:  records,
+		ActiveCount:  len(records),
+		OverdueCount: overdue,
+		BorrowLimit:  s.loanPolicy.MaxActiveBorrows,
+	}, nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"library-system/models"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func newTestUserService(env *testEnv) *UserService {
+	return NewUserService(env.store, env.repos.Users, env.repos.BorrowRecords, env.loginGuard(), DefaultPasswordPolicy(), DefaultLoanPolicy())
+}
+
+func TestUserService_GetProfile(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t, "lemon", models.RoleUser)
+	service := newTestUserService(env)
+
+	tests := []struct {
+		name    string
+		userID  int
+		wantErr error
+	}{
+		{name: "获取成功", userID: user.ID},
+		{name: "用户不存在", userID: 404, wantErr: ErrUserNotFound},
+		{name: "无效的参数", userID: 0, wantErr: ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.GetProfile(context.Background(), tt.userID)
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr == nil && got.Name != user.Name {
+				t.Errorf("Name = %q, want %q", got.Name, user.Name)
+			}
+		})
+	}
+}
+
+func TestUserService_UpdateProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      string
+		phone      string
+		locale     string
+		wantErr    error
+		wantEmail  string
+		wantLocale string
+	}{
+		{name: "更新成功", email: " lemon@example.com ", phone: "+86-13800000000", wantEmail: "lemon@example.com"},
+		{name: "清空联系方式", email: "", phone: "", wantEmail: ""},
+		{name: "保留自己的邮箱", email: "LEMON@old.example.com", wantEmail: "LEMON@old.example.com"},
+		{name: "邮箱格式错误", email: "not-an-email", wantErr: ErrInvalidInput},
+		{name: "邮箱带显示名", email: "Lemon <lemon@example.com>", wantErr: ErrInvalidInput},
+		{name: "手机号格式错误", phone: "abc", wantErr: ErrInvalidInput},
+		{name: "邮箱已被使用", email: "Lime@example.com", wantErr: ErrEmailExists},
+		{name: "设置语言偏好", email: "lemon@old.example.com", locale: "en", wantEmail: "lemon@old.example.com", wantLocale: "en-US"},
+		{name: "不支持的语言", locale: "fr-FR", wantErr: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := newTestUserService(env)
+			ctx := context.Background()
+
+			user := env.createUser(t, "lemon", models.RoleUser)
+			user.Email = "lemon@old.example.com"
+			checkErr(t, env.repos.Users.Update(ctx, user), nil)
+			other := env.createUser(t, "lime", models.RoleUser)
+			other.Email = "lime@example.com"
+			checkErr(t, env.repos.Users.Update(ctx, other), nil)
+
+			_, err := service.UpdateProfile(ctx, user.ID, tt.email, tt.phone, tt.locale)
+			checkErr(t, err, tt.wantErr)
+
+			got := env.getUser(t, user.ID)
+			want := tt.wantEmail
+			if tt.wantErr != nil {
+				want = "lemon@old.example.com"
+			}
+			if got.Email != want {
+				t.Errorf("Email = %q, want %q", got.Email, want)
+			}
+			if got.Locale != tt.wantLocale {
+				t.Errorf("Locale = %q, want %q", got.Locale, tt.wantLocale)
+			}
+		})
+	}
+
+	t.Run("用户不存在", func(t *testing.T) {
+		env := newTestEnv(t)
+		_, err := newTestUserService(env).UpdateProfile(context.Background(), 404, "", "", "")
+		checkErr(t, err, ErrUserNotFound)
+	})
+}
+
+func TestUserService_SetCardNumber(t *testing.T) {
+	tests := []struct {
+		name       string
+		cardNumber string
+		wantErr    error
+		want       string
+	}{
+		{name: "登记成功", cardNumber: " L2024-0001 ", want: "L2024-0001"},
+		{name: "注销借书证", cardNumber: ""},
+		{name: "保留自己的证号", cardNumber: "L0001", want: "L0001"},
+		{name: "证号格式错误", cardNumber: "L 0001", wantErr: ErrInvalidInput, want: "L0001"},
+		{name: "证号已被使用", cardNumber: "L0002", wantErr: ErrCardNumberExists, want: "L0001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			service := newTestUserService(env)
+			ctx := context.Background()
+
+			user := env.createUser(t, "lemon", models.RoleUser)
+			_, err := service.SetCardNumber(ctx, user.ID, "L0001")
+			checkErr(t, err, nil)
+			other := env.createUser(t, "lime", models.RoleUser)
+			_, err = service.SetCardNumber(ctx, other.ID, "L0002")
+			checkErr(t, err, nil)
+
+			_, err = service.SetCardNumber(ctx, user.ID, tt.cardNumber)
+			checkErr(t, err, tt.wantErr)
+
+			got := ""
+			if card := env.getUser(t, user.ID).CardNumber; card != nil {
+				got = *card
+			}
+			if got != tt.want {
+				t.Errorf("CardNumber = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("用户不存在", func(t *testing.T) {
+		env := newTestEnv(t)
+		_, err := newTestUserService(env).SetCardNumber(context.Background(), 404, "L0001")
+		checkErr(t, err, ErrUserNotFound)
+	})
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	const newPassword = "orange2025juice"
+
+	tests := []struct {
+		name        string
+		current     string
+		newPassword string
+		wantErr     error
+	}{
+		{name: "修改成功", current: testPassword, newPassword: newPassword},
+		{name: "当前密码错误", current: "wrong-password1", newPassword: newPassword, wantErr: ErrInvalidPassword},
+		{name: "新密码太弱", current: testPassword, newPassword: "short", wantErr: ErrInvalidInput},
+		{name: "参数为空", current: "", newPassword: newPassword, wantErr: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			ctx := context.Background()
+			user := env.createUser(t, "lemon", models.RoleUser)
+			session := &models.Session{ID: "other-device", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+			checkErr(t, env.repos.Sessions.Save(ctx, session), nil)
+
+			err := newTestUserService(env).ChangePassword(ctx, user.ID, tt.current, tt.newPassword, "192.0.2.1")
+			checkErr(t, err, tt.wantErr)
+
+			want := testPassword
+			if tt.wantErr == nil {
+				want = tt.newPassword
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(env.getUser(t, user.ID).Password), []byte(want)); err != nil {
+				t.Errorf("stored password does not match %q", want)
+			}
+
+			// 修改成功后吊销全部Session
+			_, err = env.repos.Sessions.GetByID(ctx, session.ID)
+			if revoked := errors.Is(err, gorm.ErrRecordNotFound); revoked != (tt.wantErr == nil) {
+				t.Errorf("session revoked = %v, want %v", revoked, tt.wantErr == nil)
+			}
+		})
+	}
+
+	t.Run("当前密码错误次数过多后锁定", func(t *testing.T) {
+		env := newTestEnv(t)
+		service := newTestUserService(env)
+		ctx := context.Background()
+		user := env.createUser(t, "lemon", models.RoleUser)
+
+		for range DefaultLoginGuardPolicy().AccountThreshold {
+			checkErr(t, service.ChangePassword(ctx, user.ID, "wrong-password1", newPassword, "192.0.2.1"), ErrInvalidPassword)
+		}
+		checkErr(t, service.ChangePassword(ctx, user.ID, testPassword, newPassword, "192.0.2.1"), ErrTooManyAttempts)
+	})
+}
+
+func TestUserService_GetAccountSummary(t *testing.T) {
+	env := newTestEnv(t)
+	service := newTestUserService(env)
+	user := env.createUser(t, "lemon", models.RoleUser)
+	book := env.createBook(t, "Go", 5)
+	env.createRecord(t, user.ID, book.ID, 48*time.Hour)
+	env.createRecord(t, user.ID, book.ID, -time.Hour)
+	returned := env.createRecord(t, user.ID, book.ID, -48*time.Hour)
+	returnedAt := time.Now()
+	returned.ReturnedAt = &returnedAt
+	checkErr(t, env.repos.BorrowRecords.Update(context.Background(), returned), nil)
+
+	summary, err := service.GetAccountSummary(context.Background(), user.ID)
+	checkErr(t, err, nil)
+	if summary.ActiveCount != 2 || summary.OverdueCount != 1 || summary.BorrowLimit != DefaultLoanPolicy().MaxActiveBorrows {
+		t.Errorf("summary = %+v", summary)
+	}
+	// 按应还日期排序，逾期的排在前面
+	if len(summary.ActiveLoans) != 2 || !summary.ActiveLoans[0].DueDate.Before(summary.ActiveLoans[1].DueDate) {
+		t.Errorf("ActiveLoans not sorted by due date: %+v", summary.ActiveLoans)
+	}
+
+	_, err = service.GetAccountSummary(context.Background(), 0)
+	checkErr(t, err, ErrInvalidInput)
+}
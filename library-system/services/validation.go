@@ -0,0 +1,55 @@
+package services
+
+import (
+	"library-system/i18n"
+	"strings"
+)
+
+// FieldError 单个字段未通过的校验规则
+type FieldError struct {
+	Field   string `json:"field" example:"password"`
+	Rule    string `json:"rule" example:"min_length"`
+	Message string `json:"message" example:"密码长度不能少于8位"`
+	// 提示信息的格式化参数，接口层按请求语言重新生成Message
+	Args []any `json:"-"`
+}
+
+// NewFieldError 提示信息取目录中的 validation.<rule>，默认语言
+func NewFieldError(field, rule string, args ...any) FieldError {
+	return FieldError{
+		Field:   field,
+		Rule:    rule,
+		Message: i18n.T(i18n.DefaultLocale, "validation."+rule, args...),
+		Args:    args,
+	}
+}
+
+// Localize 按locale重新生成提示信息
+func (e FieldError) Localize(locale string) FieldError {
+	e.Message = i18n.T(locale, "validation."+e.Rule, e.Args...)
+	return e
+}
+
+// ValidationError 一组字段校验错误，包装ErrInvalidInput
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func newValidationError(fields []FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
@@ -0,0 +1,469 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"library-system/metrics"
+	"library-system/models"
+	"library-system/repositories"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// WebhookPolicy 投递规则
+type WebhookPolicy struct {
+	// 单次请求的超时时间
+	Timeout time.Duration
+	// 最多尝试的次数，之后标记为失败
+	MaxAttempts int
+	// 第一次重试前的等待时间，之后每次翻倍，最多等待MaxRetryBackoff
+	RetryBackoff time.Duration
+}
+
+// DefaultWebhookPolicy 默认请求超时10秒，最多尝试8次，重试间隔从1分钟开始翻倍
+func DefaultWebhookPolicy() WebhookPolicy {
+	return WebhookPolicy{
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		RetryBackoff: time.Minute,
+	}
+}
+
+// MaxRetryBackoff 两次重试之间最长的等待时间
+const MaxRetryBackoff = 6 * time.Hour
+
+// 投递记录默认和最多返回的条数
+const (
+	DefaultWebhookDeliveryLimit = 20
+	MaxWebhookDeliveryLimit     = 100
+)
+
+// webhookBatchSize 每次分发处理的事件数和投递数，剩余的留到下次
+const webhookBatchSize = 100
+
+// 请求头
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+var webhookDeliveries = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "library_webhook_deliveries_total",
+	Help: "Webhook投递尝试次数，result为succeeded、retry或failed",
+}, []string{"event", "result"})
+
+// CreatedWebhook 新建的Webhook，签名密钥只在创建时返回
+type CreatedWebhook struct {
+	models.Webhook
+	Secret string `json:"secret" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// WebhookEvent 投递给Webhook的请求体，重试时内容不变
+type WebhookEvent struct {
+	ID        int             `json:"id" example:"42"`
+	Type      string          `json:"type" example:"loan.created"`
+	CreatedAt time.Time       `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	Data      json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// WebhookDispatchResult 一次分发的结果
+type WebhookDispatchResult struct {
+	// 已分发的事件数
+	Events    int
+	Succeeded int
+	Retrying  int
+	Failed    int
+}
+
+type WebhookService struct {
+	transactor   repositories.Transactor
+	webhookRepo  repositories.WebhookRepository
+	deliveryRepo repositories.WebhookDeliveryRepository
+	outboxRepo   repositories.OutboxRepository
+	policy       WebhookPolicy
+	client       *http.Client
+}
+
+func NewWebhookService(transactor repositories.Transactor, webhookRepo repositories.WebhookRepository, deliveryRepo repositories.WebhookDeliveryRepository, outboxRepo repositories.OutboxRepository, policy WebhookPolicy) *WebhookService {
+	return &WebhookService{
+		transactor:   transactor,
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		outboxRepo:   outboxRepo,
+		policy:       policy,
+		client: &http.Client{
+			Timeout: policy.Timeout,
+			// 重定向按失败处理，接收方应登记最终地址
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// GetWebhooks
+func (s *WebhookService) GetWebhooks(ctx context.Context) (_ []*models.Webhook, err error) {
+	ctx, span := startSpan(ctx, "WebhookService.GetWebhooks")
+	defer endSpan(span, &err)
+
+	webhooks, err := s.webhookRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook 生成签名密钥，只在返回值中出现一次
+func (s *WebhookService) CreateWebhook(ctx context.Context, rawURL string, events []string, active bool) (_ *CreatedWebhook, err error) {
+	ctx, span := startSpan(ctx, "WebhookService.CreateWebhook")
+	defer endSpan(span, &err)
+
+	events, err = validateWebhook(rawURL, events)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		URL:    rawURL,
+		Events: events,
+		Secret: secret,
+		Active: active,
+	}
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return &CreatedWebhook{Webhook: *webhook, Secret: secret}, nil
+}
+
+// UpdateWebhook 停用后尚未投递的记录不再重试
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id int, rawURL string, events []string, active bool) (_ *models.Webhook, err error) {
+	ctx, span := startSpan(ctx, "WebhookService.UpdateWebhook", attribute.Int("webhook.id", id))
+	defer endSpan(span, &err)
+
+	events, err = validateWebhook(rawURL, events)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := s.getWebhook(ctx, s.webhookRepo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL = rawURL
+	webhook.Events = events
+	webhook.Active = active
+	if err := s.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook 同时删除投递记录
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "WebhookService.DeleteWebhook", attribute.Int("webhook.id", id))
+	defer endSpan(span, &err)
+
+	return s.transactor.WithinTransaction(ctx, "delete_webhook", func(repos repositories.Repositories) error {
+		webhook, err := s.getWebhook(ctx, repos.Webhooks, id)
+		if err != nil {
+			return err
+		}
+		if err := repos.WebhookDeliveries.DeleteByWebhookID(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		if err := repos.Webhooks.Delete(ctx, webhook); err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetDeliveries 投递记录，最新的在前，limit不大于0时使用默认值，最多返回MaxWebhookDeliveryLimit条
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID int, limit int) (_ []*models.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "WebhookService.GetDeliveries", attribute.Int("webhook.id", webhookID))
+	defer endSpan(span, &err)
+
+	if _, err := s.getWebhook(ctx, s.webhookRepo, webhookID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveryLimit
+	}
+	limit = min(limit, MaxWebhookDeliveryLimit)
+
+	deliveries, err := s.deliveryRepo.GetByWebhookID(ctx, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// getWebhook
+func (s *WebhookService) getWebhook(ctx context.Context, repo repositories.WebhookRepository, id int) (*models.Webhook, error) {
+	if id <= 0 {
+		return nil, ErrWebhookNotFound
+	}
+	webhook, err := repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook by ID: %w", err)
+	}
+	return webhook, nil
+}
+
+// Dispatch 为新事件创建投递记录，再投递到了重试时间的记录
+// 由定时任务调用，任务锁保证同一时间只有一个实例分发
+func (s *WebhookService) Dispatch(ctx context.Context) (result WebhookDispatchResult, err error) {
+	ctx, span := startSpan(ctx, "WebhookService.Dispatch")
+	defer endSpan(span, &err)
+
+	result.Events, err = s.fanOut(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+	deliveries, err := s.deliveryRepo.GetDue(ctx, now, webhookBatchSize)
+	if err != nil {
+		return result, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+	webhooks := make(map[int]*models.Webhook)
+	events := make(map[int]*models.OutboxEvent)
+	for _, delivery := range deliveries {
+		if err := s.deliver(ctx, delivery, webhooks, events); err != nil {
+			return result, err
+		}
+		switch delivery.Status {
+		case models.DeliverySucceeded:
+			result.Succeeded++
+		case models.DeliveryFailed:
+			result.Failed++
+		default:
+			result.Retrying++
+		}
+	}
+	return result, nil
+}
+
+// fanOut 每个事件在一个事务中为订阅的Webhook创建投递记录并标记为已分发
+func (s *WebhookService) fanOut(ctx context.Context) (int, error) {
+	events, err := s.outboxRepo.GetUndispatched(ctx, webhookBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get undispatched events: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+	webhooks, err := s.webhookRepo.GetActive(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get active webhooks: %w", err)
+	}
+
+	for _, event := range events {
+		err := s.transactor.WithinTransaction(ctx, "dispatch_event", func(repos repositories.Repositories) error {
+			now := time.Now()
+			for _, webhook := range webhooks {
+				if !webhook.Subscribes(event.Type) {
+					continue
+				}
+				delivery := &models.WebhookDelivery{
+					WebhookID:     webhook.ID,
+					EventID:       event.ID,
+					EventType:     event.Type,
+					Status:        models.DeliveryPending,
+					NextAttemptAt: now,
+				}
+				if err := repos.WebhookDeliveries.Create(ctx, delivery); err != nil {
+					return fmt.Errorf("failed to create webhook delivery: %w", err)
+				}
+			}
+			if err := repos.Outbox.MarkDispatched(ctx, event.ID, now); err != nil {
+				return fmt.Errorf("failed to mark event dispatched: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// deliver 尝试投递一次并保存结果，webhooks和events缓存本次分发中已读取的数据
+// 只有保存失败或ctx取消时返回错误，请求失败记录在投递记录中
+func (s *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery, webhooks map[int]*models.Webhook, events map[int]*models.OutboxEvent) error {
+	webhook, ok := webhooks[delivery.WebhookID]
+	if !ok {
+		var err error
+		webhook, err = s.webhookRepo.GetByID(ctx, delivery.WebhookID)
+		if err != nil {
+			return fmt.Errorf("failed to get webhook by ID: %w", err)
+		}
+		webhooks[webhook.ID] = webhook
+	}
+	event, ok := events[delivery.EventID]
+	if !ok {
+		var err error
+		event, err = s.outboxRepo.GetByID(ctx, delivery.EventID)
+		if err != nil {
+			return fmt.Errorf("failed to get event by ID: %w", err)
+		}
+		events[event.ID] = event
+	}
+
+	now := time.Now()
+	if !webhook.Active {
+		// 停用后不再重试
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = "webhook is inactive"
+	} else {
+		delivery.Attempts++
+		status, err := s.post(ctx, webhook, delivery, event)
+		if ctx.Err() != nil {
+			// 关闭服务时中断的请求不计入尝试次数
+			return ctx.Err()
+		}
+		delivery.ResponseStatus = status
+		switch {
+		case err == nil:
+			delivery.Status = models.DeliverySucceeded
+			delivery.Error = ""
+			delivery.DeliveredAt = &now
+		case delivery.Attempts >= s.policy.MaxAttempts:
+			delivery.Status = models.DeliveryFailed
+			delivery.Error = err.Error()
+		default:
+			delivery.Error = err.Error()
+			delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+		}
+	}
+
+	result := delivery.Status
+	if result == models.DeliveryPending {
+		result = "retry"
+	}
+	webhookDeliveries.WithLabelValues(delivery.EventType, result).Inc()
+	if delivery.Status == models.DeliveryFailed {
+		slog.WarnContext(ctx, "Webhook投递失败", slog.Int("webhook_id", webhook.ID), slog.Int("delivery_id", delivery.ID), slog.String("error", delivery.Error))
+	}
+
+	if err := s.deliveryRepo.Update(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// post 发送事件，返回响应状态码，非2xx按失败处理
+func (s *WebhookService) post(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, event *models.OutboxEvent) (int, error) {
+	body, err := json.Marshal(WebhookEvent{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event.Type)
+	req.Header.Set(WebhookDeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// 读完响应体以便复用连接
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff 第attempts次失败后的等待时间
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	wait := s.policy.RetryBackoff
+	for i := 1; i < attempts && wait < MaxRetryBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, MaxRetryBackoff)
+}
+
+// SignWebhook 签名请求头的值 t=<Unix时间戳>,v1=<HMAC-SHA256(secret, "<时间戳>.<请求体>")的十六进制>
+// 接收方用同样的方法计算并比较，同时检查时间戳以拒绝重放的请求
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateWebhook 返回去重排序后的事件类型
+func validateWebhook(rawURL string, events []string) ([]string, error) {
+	var fields []FieldError
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields = append(fields, NewFieldError("url", "invalid", "url"))
+	}
+
+	events = slices.Clone(events)
+	slices.Sort(events)
+	events = slices.Compact(events)
+	if len(events) == 0 {
+		fields = append(fields, NewFieldError("events", "required", "events"))
+	}
+	for _, e := range events {
+		if !slices.Contains(models.EventTypes, e) {
+			fields = append(fields, NewFieldError("events", "invalid", "events"))
+			break
+		}
+	}
+
+	if err := newValidationError(fields); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// newWebhookSecret 32字节随机数的十六进制
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
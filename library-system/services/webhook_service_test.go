@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"library-system/mailer"
+	"library-system/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver 记录收到的请求，按status返回响应
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, receivedWebhook{header: req.Header.Clone(), body: body})
+	w.WriteHeader(r.status)
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+func newTestWebhookService(env *testEnv, policy WebhookPolicy) *WebhookService {
+	return NewWebhookService(env.store, env.repos.Webhooks, env.repos.WebhookDeliveries, env.repos.Outbox, policy)
+}
+
+// outboxTypes 全部事件的类型，按写入顺序
+func outboxTypes(t *testing.T, env *testEnv) []string {
+	t.Helper()
+	events, err := env.repos.Outbox.GetUndispatched(context.Background(), 100)
+	checkErr(t, err, nil)
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestOutboxEvents(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	lemon := env.createUser(t, "lemon", models.RoleUser)
+
+	admin := newTestAdminService(env)
+	checkErr(t, admin.AddBook(ctx, "Go", "Lemon", 1), nil)
+	// 失败的操作不写入事件
+	checkErr(t, admin.AddBook(ctx, "go", "Lemon", 1), ErrBookExists)
+
+	book, err := env.repos.Books.GetByTitle(ctx, "Go")
+	checkErr(t, err, nil)
+	borrow := NewBorrowService(env.store, env.repos.BorrowRecords, DefaultLoanPolicy(), nil, nil)
+	checkErr(t, borrow.BorrowBook(ctx, lemon.ID, book.ID), nil)
+	checkErr(t, borrow.BorrowBook(ctx, lemon.ID, book.ID), ErrStockNotEnough)
+	records, err := env.repos.BorrowRecords.GetByUserID(ctx, lemon.ID)
+	checkErr(t, err, nil)
+	checkErr(t, borrow.ReturnBook(ctx, records[0].ID, lemon.ID), nil)
+
+	// 逾期扫描只在第一次标记时写入事件
+	overdue := env.createRecord(t, lemon.ID, book.ID, -time.Hour)
+	notifications := newTestNotificationService(env, mailer.NewMemoryMailer())
+	for range 2 {
+		_, err := notifications.ScanDueDates(ctx)
+		checkErr(t, err, nil)
+	}
+
+	checkErr(t, admin.UpdateBook(ctx, "Go", "Lemon", book.ID, 2), nil)
+	checkErr(t, admin.UpdateBook(ctx, "Go", "Lemon", 404, 2), ErrBookNotFound)
+	// 删除图书时一并归还的借阅也写入事件
+	checkErr(t, admin.DeleteBook(ctx, book.ID), nil)
+
+	want := []string{
+		models.EventBookCreated, models.EventLoanCreated, models.EventLoanReturned, models.EventLoanOverdue,
+		models.EventBookUpdated, models.EventLoanReturned, models.EventBookDeleted,
+	}
+	if got := outboxTypes(t, env); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	events, err := env.repos.Outbox.GetUndispatched(ctx, 100)
+	checkErr(t, err, nil)
+	var record models.BorrowRecord
+	if err := json.Unmarshal([]byte(events[3].Payload), &record); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if record.ID != overdue.ID || record.UserID != lemon.ID {
+		t.Errorf("loan.overdue payload = %+v, want record %d of user %d", record, overdue.ID, lemon.ID)
+	}
+}
+
+func TestWebhookService_Validation(t *testing.T) {
+	env := newTestEnv(t)
+	service := newTestWebhookService(env, DefaultWebhookPolicy())
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		url     string
+		events  []string
+		wantErr error
+	}{
+		{name: "登记成功", url: "https://example.com/hook", events: []string{models.EventLoanCreated}},
+		{name: "不支持的协议", url: "ftp://example.com/hook", events: []string{models.EventLoanCreated}, wantErr: ErrInvalidInput},
+		{name: "缺少主机", url: "https:///hook", events: []string{models.EventLoanCreated}, wantErr: ErrInvalidInput},
+		{name: "没有事件", url: "https://example.com/hook", wantErr: ErrInvalidInput},
+		{name: "未知事件", url: "https://example.com/hook", events: []string{"user.created"}, wantErr: ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateWebhook(ctx, tt.url, tt.events, true)
+			checkErr(t, err, tt.wantErr)
+		})
+	}
+
+	created, err := service.CreateWebhook(ctx, "https://example.com/hook", []string{models.EventLoanReturned, models.EventLoanCreated, models.EventLoanCreated}, true)
+	checkErr(t, err, nil)
+	if len(created.Secret) != 64 {
+		t.Errorf("secret length = %d, want 64", len(created.Secret))
+	}
+	if got := strings.Join(created.Events, ","); got != "loan.created,loan.returned" {
+		t.Errorf("events = %s, want sorted and deduplicated", got)
+	}
+
+	_, err = service.UpdateWebhook(ctx, 404, "https://example.com/hook", []string{models.EventLoanCreated}, true)
+	checkErr(t, err, ErrWebhookNotFound)
+	checkErr(t, service.DeleteWebhook(ctx, 404), ErrWebhookNotFound)
+	checkErr(t, service.DeleteWebhook(ctx, created.ID), nil)
+}
+
+func TestWebhookService_Dispatch(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	service := newTestWebhookService(env, WebhookPolicy{Timeout: 5 * time.Second, MaxAttempts: 2, RetryBackoff: time.Nanosecond})
+
+	loans, err := service.CreateWebhook(ctx, server.URL, []string{models.EventLoanCreated}, true)
+	checkErr(t, err, nil)
+	// 未订阅的事件不投递
+	_, err = service.CreateWebhook(ctx, server.URL, []string{models.EventBookCreated}, true)
+	checkErr(t, err, nil)
+
+	lemon := env.createUser(t, "lemon", models.RoleUser)
+	book := env.createBook(t, "Go", 2)
+	borrow := NewBorrowService(env.store, env.repos.BorrowRecords, DefaultLoanPolicy(), nil, nil)
+	checkErr(t, borrow.BorrowBook(ctx, lemon.ID, book.ID), nil)
+
+	result, err := service.Dispatch(ctx)
+	checkErr(t, err, nil)
+	if result.Events != 1 || result.Succeeded != 1 {
+		t.Fatalf("result = %+v, want 1 event delivered", result)
+	}
+
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("received %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if got := req.header.Get(WebhookEventHeader); got != models.EventLoanCreated {
+		t.Errorf("%s = %q, want %q", WebhookEventHeader, got, models.EventLoanCreated)
+	}
+	signature := req.header.Get(WebhookSignatureHeader)
+	unix, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+	if err != nil {
+		t.Fatalf("signature %q has no timestamp", signature)
+	}
+	if want := SignWebhook(loans.Secret, time.Unix(unix, 0), req.body); signature != want {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(req.body, &event); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if event.Type != models.EventLoanCreated || !strings.Contains(string(event.Data), `"book_id":`) {
+		t.Errorf("body = %s, want loan.created with the borrow record", req.body)
+	}
+
+	// 已投递的事件不再投递
+	result, err = service.Dispatch(ctx)
+	checkErr(t, err, nil)
+	if result != (WebhookDispatchResult{}) {
+		t.Errorf("second dispatch result = %+v, want nothing", result)
+	}
+
+	// 接收方出错时重试，超过最多尝试次数后标记为失败
+	receiver.mu.Lock()
+	receiver.status = http.StatusInternalServerError
+	receiver.mu.Unlock()
+	checkErr(t, borrow.BorrowBook(ctx, lemon.ID, book.ID), nil)
+
+	result, err = service.Dispatch(ctx)
+	checkErr(t, err, nil)
+	if result.Retrying != 1 {
+		t.Fatalf("result = %+v, want 1 retrying", result)
+	}
+	result, err = service.Dispatch(ctx)
+	checkErr(t, err, nil)
+	if result.Failed != 1 {
+		t.Fatalf("result = %+v, want 1 failed", result)
+	}
+
+	deliveries, err := service.GetDeliveries(ctx, loans.ID, 0)
+	checkErr(t, err, nil)
+	if len(deliveries) != 2 {
+		t.Fatalf("got %d deliveries, want 2", len(deliveries))
+	}
+	failed := deliveries[0]
+	if failed.Status != models.DeliveryFailed || failed.Attempts != 2 || failed.ResponseStatus != http.StatusInternalServerError || failed.Error == "" {
+		t.Errorf("failed delivery = %+v", failed)
+	}
+	if deliveries[1].Status != models.DeliverySucceeded || deliveries[1].DeliveredAt == nil {
+		t.Errorf("first delivery = %+v, want succeeded", deliveries[1])
+	}
+	if got := len(receiver.received()); got != 3 {
+		t.Errorf("received %d requests, want 3", got)
+	}
+}
+
+func TestWebhookService_InactiveWebhook(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	service := newTestWebhookService(env, DefaultWebhookPolicy())
+
+	webhook, err := service.CreateWebhook(ctx, server.URL, []string{models.EventBookCreated}, true)
+	checkErr(t, err, nil)
+	checkErr(t, newTestAdminService(env).AddBook(ctx, "Go", "Lemon", 1), nil)
+
+	// 分发前停用，已创建的投递不再发送
+	_, err = service.fanOut(ctx)
+	checkErr(t, err, nil)
+	_, err = service.UpdateWebhook(ctx, webhook.ID, webhook.URL, webhook.Events, false)
+	checkErr(t, err, nil)
+
+	result, err := service.Dispatch(ctx)
+	checkErr(t, err, nil)
+	if result.Failed != 1 {
+		t.Fatalf("result = %+v, want 1 failed", result)
+	}
+	if got := len(receiver.received()); got != 0 {
+		t.Errorf("received %d requests, want 0", got)
+	}
+}